@@ -1,8 +1,11 @@
 package action
 
 import (
+	"context"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 )
@@ -10,6 +13,8 @@ import (
 type AttachDiskAction struct {
 	vmFinder   bslcvm.Finder
 	diskFinder bslcdisk.Finder
+	apiVersion int
+	ctx        context.Context
 }
 
 func NewAttachDisk(
@@ -18,9 +23,17 @@ func NewAttachDisk(
 ) (action AttachDiskAction) {
 	action.vmFinder = vmFinder
 	action.diskFinder = diskFinder
+	action.apiVersion = ApiVersion1
+	action.ctx = context.Background()
 	return
 }
 
+func (a AttachDiskAction) WithContext(callContext CallContext) Action {
+	a.apiVersion = callContext.ApiVersion
+	a.ctx = callContext.Context
+	return a
+}
+
 func (a AttachDiskAction) Run(vmCID VMCID, diskCID DiskCID) (interface{}, error) {
 	vm, found, err := a.vmFinder.Find(vmCID.Int())
 	if err != nil {
@@ -28,7 +41,7 @@ func (a AttachDiskAction) Run(vmCID VMCID, diskCID DiskCID) (interface{}, error)
 	}
 
 	if !found {
-		return nil, bosherr.Errorf("Expected to find VM '%s'", vmCID)
+		return nil, bslcapi.NewVMNotFoundError(vmCID.String())
 	}
 
 	disk, found, err := a.diskFinder.Find(diskCID.Int())
@@ -37,13 +50,23 @@ func (a AttachDiskAction) Run(vmCID VMCID, diskCID DiskCID) (interface{}, error)
 	}
 
 	if !found {
-		return nil, bosherr.Errorf("Expected to find disk '%s'", diskCID)
+		return nil, bslcapi.NewDiskNotFoundError(diskCID.String())
 	}
 
-	err = vm.AttachDisk(disk)
+	err = vm.AttachDisk(a.ctx, disk)
 	if err != nil {
 		return nil, bosherr.WrapErrorf(err, "Attaching disk '%s' to VM '%s'", diskCID, vmCID)
 	}
 
-	return nil, nil
+	if a.apiVersion < ApiVersion2 {
+		return nil, nil
+	}
+
+	// Under API version 2 attach_disk returns the disk hint recorded in the agent env
+	agentEnv, err := vm.FetchAgentEnv()
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Fetching agent env of VM '%s'", vmCID)
+	}
+
+	return agentEnv.Disks.Persistent[diskCID.String()], nil
 }
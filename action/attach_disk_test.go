@@ -8,6 +8,10 @@ import (
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+
+	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+
 	fakedisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk/fakes"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
 )
@@ -84,6 +88,28 @@ var _ = Describe("AttachDisk", func() {
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-attach-disk-err"))
 				})
+
+				Context("when API version is 2", func() {
+					BeforeEach(func() {
+						action = action.WithContext(NewCallContext(ApiVersion2, nil)).(AttachDiskAction)
+					})
+
+					It("returns the disk hint recorded in the agent env", func() {
+						vm.FetchAgentEnvAgentEnv = bslcvm.AgentEnv{}.AttachPersistentDisk("1234", "/dev/mapper/fake-device")
+
+						diskHint, err := action.Run(1234, 1234)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(diskHint).To(Equal("/dev/mapper/fake-device"))
+					})
+
+					It("returns error if fetching agent env fails", func() {
+						vm.FetchAgentEnvErr = errors.New("fake-fetch-err")
+
+						_, err := action.Run(1234, 1234)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-fetch-err"))
+					})
+				})
 			})
 
 			Context("when disk is not found with given cid", func() {
@@ -92,7 +118,7 @@ var _ = Describe("AttachDisk", func() {
 
 					_, err := action.Run(1234, 1234)
 					Expect(err).To(HaveOccurred())
-					Expect(err.Error()).To(ContainSubstring("Expected to find disk"))
+					Expect(err).To(Equal(bslcapi.NewDiskNotFoundError("1234")))
 				})
 			})
 
@@ -113,7 +139,7 @@ var _ = Describe("AttachDisk", func() {
 
 				_, err := action.Run(1234, 1234)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("Expected to find VM"))
+				Expect(err).To(Equal(bslcapi.NewVMNotFoundError("1234")))
 			})
 		})
 
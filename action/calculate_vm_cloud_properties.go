@@ -0,0 +1,70 @@
+package action
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
+)
+
+var (
+	// Core counts and memory sizes (MB) offered for SoftLayer virtual guests
+	softLayerCpuCounts   = []int{1, 2, 4, 8, 12, 16, 32, 48, 56}
+	softLayerMemorySizes = []int{1024, 2048, 4096, 6144, 8192, 12288, 16384, 32768, 49152, 65536, 131072, 247808}
+)
+
+type CalculateVMCloudPropertiesAction struct{}
+
+type VMResources struct {
+	Cpu               int `json:"cpu"`
+	Ram               int `json:"ram"`
+	EphemeralDiskSize int `json:"ephemeral_disk_size"`
+}
+
+// VMSizeCloudProperties holds only the sizing cloud properties, since the director
+// merges the result over the AZ cloud_properties and any other key would override them
+type VMSizeCloudProperties struct {
+	StartCpus         int `json:"startCpus"`
+	MaxMemory         int `json:"maxMemory"`
+	EphemeralDiskSize int `json:"ephemeralDiskSize,omitempty"`
+}
+
+func NewCalculateVMCloudProperties() (action CalculateVMCloudPropertiesAction) {
+	return
+}
+
+func (a CalculateVMCloudPropertiesAction) Run(resources VMResources) (VMSizeCloudProperties, error) {
+	cloudProps := VMSizeCloudProperties{}
+
+	cpus, found := roundUpTo(softLayerCpuCounts, resources.Cpu)
+	if !found {
+		return cloudProps, bosherr.Errorf("Requested %d CPUs, more than the %d cores SoftLayer offers", resources.Cpu, cpus)
+	}
+
+	memory, found := roundUpTo(softLayerMemorySizes, resources.Ram)
+	if !found {
+		return cloudProps, bosherr.Errorf("Requested %d MB of RAM, more than the %d MB SoftLayer offers", resources.Ram, memory)
+	}
+
+	cloudProps.StartCpus = cpus
+	cloudProps.MaxMemory = memory
+
+	if resources.EphemeralDiskSize > 0 {
+		diskSize, err := bslcdisk.RoundUpToSoftLayerDiskSize((resources.EphemeralDiskSize + 1023) / 1024)
+		if err != nil {
+			return cloudProps, bosherr.WrapError(err, "Calculating ephemeral disk size")
+		}
+
+		cloudProps.EphemeralDiskSize = diskSize
+	}
+
+	return cloudProps, nil
+}
+
+func roundUpTo(sizes []int, requested int) (int, bool) {
+	for _, size := range sizes {
+		if requested <= size {
+			return size, true
+		}
+	}
+	return sizes[len(sizes)-1], false
+}
@@ -0,0 +1,77 @@
+package action_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+)
+
+var _ = Describe("CalculateVMCloudProperties", func() {
+	var (
+		action CalculateVMCloudPropertiesAction
+	)
+
+	BeforeEach(func() {
+		action = NewCalculateVMCloudProperties()
+	})
+
+	Describe("Run", func() {
+		It("returns cloud properties matching the requested resources exactly", func() {
+			cloudProps, err := action.Run(VMResources{Cpu: 2, Ram: 4096, EphemeralDiskSize: 102400})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProps.StartCpus).To(Equal(2))
+			Expect(cloudProps.MaxMemory).To(Equal(4096))
+			Expect(cloudProps.EphemeralDiskSize).To(Equal(100))
+		})
+
+		It("rounds up to the next available cores, memory and disk size", func() {
+			cloudProps, err := action.Run(VMResources{Cpu: 3, Ram: 5000, EphemeralDiskSize: 20481})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProps.StartCpus).To(Equal(4))
+			Expect(cloudProps.MaxMemory).To(Equal(6144))
+			Expect(cloudProps.EphemeralDiskSize).To(Equal(40))
+		})
+
+		It("marshals only the sizing cloud properties", func() {
+			cloudProps, err := action.Run(VMResources{Cpu: 2, Ram: 4096, EphemeralDiskSize: 102400})
+			Expect(err).ToNot(HaveOccurred())
+
+			cloudPropsJSON, err := json.Marshal(cloudProps)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudPropsJSON).To(MatchJSON(`{"startCpus":2,"maxMemory":4096,"ephemeralDiskSize":100}`))
+		})
+
+		It("does not set ephemeral disk size when none is requested", func() {
+			cloudProps, err := action.Run(VMResources{Cpu: 1, Ram: 1024})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProps.EphemeralDiskSize).To(Equal(0))
+		})
+
+		It("returns error when requested CPUs exceed what SoftLayer offers", func() {
+			_, err := action.Run(VMResources{Cpu: 64, Ram: 1024})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("CPUs"))
+		})
+
+		It("returns error when requested RAM exceeds what SoftLayer offers", func() {
+			_, err := action.Run(VMResources{Cpu: 1, Ram: 300000})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("RAM"))
+		})
+
+		It("returns a cloud error when the requested disk exceeds what SoftLayer offers", func() {
+			_, err := action.Run(VMResources{Cpu: 1, Ram: 1024, EphemeralDiskSize: 20000 * 1024})
+			Expect(err).To(HaveOccurred())
+
+			cloudErr, found := bslcapi.FindCloudError(err)
+			Expect(found).To(BeTrue())
+			Expect(cloudErr.Type()).To(Equal("Bosh::Clouds::CloudError"))
+			Expect(err.Error()).To(ContainSubstring("Requested disk size 20000 GB is larger than the 12000 GB SoftLayer offers"))
+		})
+	})
+})
@@ -0,0 +1,51 @@
+package action
+
+import (
+	"context"
+)
+
+const (
+	ApiVersion1 = 1
+	ApiVersion2 = 2
+
+	SupportedApiVersion = ApiVersion2
+)
+
+type CallContext struct {
+	ApiVersion   int
+	DirectorUUID string
+	Properties   map[string]interface{}
+
+	// Context is cancelled when the CPI is asked to stop, so long running
+	// SoftLayer wait loops can give up promptly
+	Context context.Context
+}
+
+func NewCallContext(apiVersion int, properties map[string]interface{}) CallContext {
+	if apiVersion < ApiVersion1 {
+		apiVersion = ApiVersion1
+	}
+
+	if apiVersion > SupportedApiVersion {
+		apiVersion = SupportedApiVersion
+	}
+
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+
+	directorUUID, _ := properties["director_uuid"].(string)
+
+	return CallContext{
+		ApiVersion:   apiVersion,
+		DirectorUUID: directorUUID,
+		Properties:   properties,
+		Context:      context.Background(),
+	}
+}
+
+// ContextualAction is implemented by actions whose behaviour depends on
+// the API version or the context sent along with the request
+type ContextualAction interface {
+	WithContext(CallContext) Action
+}
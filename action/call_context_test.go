@@ -0,0 +1,36 @@
+package action_test
+
+import (
+	gocontext "context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+)
+
+var _ = Describe("CallContext", func() {
+	Describe("NewCallContext", func() {
+		It("defaults to API version 1 when no version is given", func() {
+			context := NewCallContext(0, nil)
+			Expect(context.ApiVersion).To(Equal(ApiVersion1))
+			Expect(context.Properties).ToNot(BeNil())
+		})
+
+		It("caps the API version to the supported version", func() {
+			context := NewCallContext(SupportedApiVersion+1, nil)
+			Expect(context.ApiVersion).To(Equal(SupportedApiVersion))
+		})
+
+		It("extracts the director uuid from the context", func() {
+			context := NewCallContext(ApiVersion2, map[string]interface{}{"director_uuid": "fake-director-uuid"})
+			Expect(context.ApiVersion).To(Equal(ApiVersion2))
+			Expect(context.DirectorUUID).To(Equal("fake-director-uuid"))
+		})
+
+		It("defaults to a background context that is never cancelled", func() {
+			context := NewCallContext(ApiVersion1, nil)
+			Expect(context.Context).To(Equal(gocontext.Background()))
+		})
+	})
+})
@@ -5,11 +5,12 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
 	bmsclient "github.com/cloudfoundry-community/bosh-softlayer-tools/clients"
-	slclient "github.com/maximilien/softlayer-go/client"
 
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
 )
 
 type concreteFactory struct {
@@ -17,33 +18,48 @@ type concreteFactory struct {
 }
 
 func NewConcreteFactory(options ConcreteFactoryOptions, logger boshlog.Logger) concreteFactory {
-	softLayerClient := slclient.NewSoftLayerClient(options.Softlayer.Username, options.Softlayer.ApiKey)
+	softLayerClient := bslcommon.NewSoftLayerClient(options.Softlayer.Username, options.Softlayer.ApiKey)
 	baremetalClient := bmsclient.NewBmpClient(options.Baremetal.Username, options.Baremetal.Password, options.Baremetal.EndPoint, nil, "")
 
-	stemcellFinder := bslcstem.NewSoftLayerFinder(softLayerClient, logger)
+	waitPolicies := options.WaitPolicies.WithDefaults()
+
+	stemcellFinder := bslcstem.NewSoftLayerFinder(softLayerClient, waitPolicies, logger)
 
 	agentEnvServiceFactory := bslcvm.NewSoftLayerAgentEnvServiceFactory(options.AgentEnvService, options.Registry, logger)
 
+	sshClient := util.NewSshClient(options.Ssh, logger)
+
 	vmFinder := bslcvm.NewSoftLayerFinder(
 		softLayerClient,
 		baremetalClient,
+		sshClient,
 		agentEnvServiceFactory,
+		options.DiskAttachMode,
+		waitPolicies,
 		logger,
 	)
 
 	vmCreatorProvider := NewProvider(
 		softLayerClient,
 		baremetalClient,
+		sshClient,
 		options,
 		logger,
 	)
 
 	diskCreator := bslcdisk.NewSoftLayerDiskCreator(
 		softLayerClient,
+		waitPolicies,
 		logger,
 	)
 
 	diskFinder := bslcdisk.NewSoftLayerDiskFinder(
+		softLayerClient,
+		waitPolicies,
+		logger,
+	)
+
+	snapshotFinder := bslcdisk.NewSoftLayerSnapshotFinder(
 		softLayerClient,
 		logger,
 	)
@@ -62,20 +78,27 @@ func NewConcreteFactory(options ConcreteFactoryOptions, logger boshlog.Logger) c
 			"set_vm_metadata":    NewSetVMMetadata(vmFinder),
 			"configure_networks": NewConfigureNetworks(vmFinder),
 
+			"calculate_vm_cloud_properties": NewCalculateVMCloudProperties(),
+
 			// Disk management
 			"create_disk": NewCreateDisk(vmFinder, diskCreator),
 			"delete_disk": NewDeleteDisk(diskFinder),
 			"attach_disk": NewAttachDisk(vmFinder, diskFinder),
 			"detach_disk": NewDetachDisk(vmFinder, diskFinder),
+			"get_disks":   NewGetDisks(vmFinder, logger),
+			"has_disk":    NewHasDisk(diskFinder),
+			"resize_disk": NewResizeDisk(diskFinder),
+
+			"set_disk_metadata": NewSetDiskMetadata(diskFinder),
 
-			// Not implemented (disk related):
-			//   snapshot_disk
-			//   delete_snapshot
-			//   get_disks
+			// Snapshot management
+			"snapshot_disk":   NewSnapshotDisk(diskFinder),
+			"delete_snapshot": NewDeleteSnapshot(snapshotFinder),
 
-			// Not implemented (others):
-			//   current_vm_id
-			//   ping
+			// Others
+			"info":          NewInfo(),
+			"current_vm_id": NewCurrentVMID(vmFinder, util.RealLocalIPResolver{}),
+			"ping":          NewPing(softLayerClient),
 		},
 	}
 }
@@ -3,7 +3,9 @@ package action
 import (
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
 )
 
 type ConcreteFactoryOptions struct {
@@ -18,6 +20,13 @@ type ConcreteFactoryOptions struct {
 	AgentEnvService string `json:"agentenvservice,omitempty"`
 
 	Registry bslcvm.RegistryOptions `json:"registry,omitempty"`
+
+	WaitPolicies bslcommon.WaitPolicies `json:"wait_policies,omitempty"`
+
+	Ssh util.SshClientOptions `json:"ssh,omitempty"`
+
+	// "ssh" (the default) or "agent", see bslcvm.DiskAttachModeAgent
+	DiskAttachMode string `json:"disk_attach_mode,omitempty"`
 }
 
 func (o ConcreteFactoryOptions) Validate() error {
@@ -31,6 +40,27 @@ func (o ConcreteFactoryOptions) Validate() error {
 		return bosherr.WrapError(err, "Validating SoftLayer configuration")
 	}
 
+	err = o.WaitPolicies.WithDefaults().Validate()
+	if err != nil {
+		return bosherr.WrapError(err, "Validating WaitPolicies configuration")
+	}
+
+	err = o.Ssh.Validate()
+	if err != nil {
+		return bosherr.WrapError(err, "Validating Ssh configuration")
+	}
+
+	err = bslcvm.ValidateDiskAttachMode(o.DiskAttachMode)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating DiskAttachMode configuration")
+	}
+
+	// The file agent env service writes user_data.json over root SSH, which
+	// the agent attach mode is meant to do without
+	if o.DiskAttachMode == bslcvm.DiskAttachModeAgent && o.AgentEnvService != "registry" {
+		return bosherr.Errorf("Disk attach mode '%s' requires the registry agent env service", bslcvm.DiskAttachModeAgent)
+	}
+
 	return nil
 }
 
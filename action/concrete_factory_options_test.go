@@ -1,11 +1,15 @@
 package action_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
 )
 
 var _ = Describe("ConcreteFactoryOptions", func() {
@@ -37,5 +41,57 @@ var _ = Describe("ConcreteFactoryOptions", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Validating Agent configuration"))
 		})
+
+		It("returns error if a wait policy is not valid", func() {
+			options.Softlayer = SoftLayerConfig{Username: "fake-username", ApiKey: "fake-api-key"}
+			options.WaitPolicies.Attach = bslcommon.NewWaitPolicy(1*time.Second, 5*time.Second)
+
+			err := options.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating 'attach' wait policy"))
+		})
+
+		It("returns error if the ssh private key cannot be loaded", func() {
+			options.Softlayer = SoftLayerConfig{Username: "fake-username", ApiKey: "fake-api-key"}
+			options.Ssh = util.SshClientOptions{PrivateKeyPath: "/non-existent/key"}
+
+			err := options.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating Ssh configuration"))
+		})
+
+		It("returns error if the disk attach mode is unknown", func() {
+			options.Softlayer = SoftLayerConfig{Username: "fake-username", ApiKey: "fake-api-key"}
+			options.DiskAttachMode = "fake-mode"
+
+			err := options.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating DiskAttachMode configuration"))
+		})
+
+		It("accepts the agent disk attach mode with the registry", func() {
+			options.Softlayer = SoftLayerConfig{Username: "fake-username", ApiKey: "fake-api-key"}
+			options.DiskAttachMode = bslcvm.DiskAttachModeAgent
+			options.AgentEnvService = "registry"
+
+			err := options.Validate()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns error if the agent disk attach mode is used without the registry", func() {
+			options.Softlayer = SoftLayerConfig{Username: "fake-username", ApiKey: "fake-api-key"}
+			options.DiskAttachMode = bslcvm.DiskAttachModeAgent
+
+			err := options.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("requires the registry agent env service"))
+		})
+
+		It("fills unset wait policies from the defaults", func() {
+			options.Softlayer = SoftLayerConfig{Username: "fake-username", ApiKey: "fake-api-key"}
+
+			err := options.Validate()
+			Expect(err).ToNot(HaveOccurred())
+		})
 	})
 })
@@ -79,6 +79,12 @@ var _ = Describe("ConcreteFactory", func() {
 			Expect(action).ToNot(BeNil())
 			Expect(err).ToNot(HaveOccurred())
 		})
+
+		It("calculate_vm_cloud_properties", func() {
+			action, err := factory.Create("calculate_vm_cloud_properties")
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
+		})
 	})
 
 	Context("Disk methods", func() {
@@ -105,37 +111,61 @@ var _ = Describe("ConcreteFactory", func() {
 			Expect(action).ToNot(BeNil())
 			Expect(err).ToNot(HaveOccurred())
 		})
-	})
 
-	Context("Unsupported methods", func() {
-		It("returns error because CPI machine is not self-aware if action is current_vm_id", func() {
-			action, err := factory.Create("current_vm_id")
-			Expect(err).To(HaveOccurred())
-			Expect(action).To(BeNil())
+		It("lists the iSCSI disks attached to a virtual guest", func() {
+			action, err := factory.Create("get_disks")
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("checks whether an iSCSI disk exists", func() {
+			action, err := factory.Create("has_disk")
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("resizes an iSCSI disk", func() {
+			action, err := factory.Create("resize_disk")
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets metadata on an iSCSI disk", func() {
+			action, err := factory.Create("set_disk_metadata")
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
 		})
 
-		It("returns error because snapshotting is not implemented if action is snapshot_disk", func() {
+		It("snapshots an iSCSI disk", func() {
 			action, err := factory.Create("snapshot_disk")
-			Expect(err).To(HaveOccurred())
-			Expect(action).To(BeNil())
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
 		})
 
-		It("returns error because snapshotting is not implemented if action is delete_snapshot", func() {
+		It("deletes a snapshot of an iSCSI disk", func() {
 			action, err := factory.Create("delete_snapshot")
-			Expect(err).To(HaveOccurred())
-			Expect(action).To(BeNil())
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
 		})
+	})
 
-		It("returns error since CPI should not keep state if action is get_disks", func() {
-			action, err := factory.Create("get_disks")
-			Expect(err).To(HaveOccurred())
-			Expect(action).To(BeNil())
+	Context("Other methods", func() {
+		It("current_vm_id", func() {
+			action, err := factory.Create("current_vm_id")
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
 		})
 
-		It("returns error because ping is not official CPI method if action is ping", func() {
+		It("info", func() {
+			action, err := factory.Create("info")
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("ping", func() {
 			action, err := factory.Create("ping")
-			Expect(err).To(HaveOccurred())
-			Expect(action).To(BeNil())
+			Expect(action).ToNot(BeNil())
+			Expect(err).ToNot(HaveOccurred())
 		})
 	})
 
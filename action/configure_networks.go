@@ -1,34 +1,46 @@
 package action
 
 import (
+	"context"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 )
 
 type ConfigureNetworksAction struct {
 	vmFinder bslcvm.Finder
+	ctx      context.Context
 }
 
 func NewConfigureNetworks(
 	vmFinder bslcvm.Finder,
 ) (action ConfigureNetworksAction) {
 	action.vmFinder = vmFinder
+	action.ctx = context.Background()
 	return
 }
 
+func (a ConfigureNetworksAction) WithContext(callContext CallContext) Action {
+	a.ctx = callContext.Context
+	return a
+}
+
 func (a ConfigureNetworksAction) Run(vmCID VMCID, networks Networks) (interface{}, error) {
 	vm, found, err := a.vmFinder.Find(int(vmCID))
 	if err != nil {
 		return nil, bosherr.WrapErrorf(err, "Finding vm '%s'", vmCID)
 	}
 
-	if found {
-		vmNetworks := networks.AsVMNetworks()
-		err := vm.ConfigureNetworks(vmNetworks)
-		if err != nil {
-			return nil, bosherr.WrapErrorf(err, "Configuring networks vm '%s'", vmCID)
-		}
+	if !found {
+		return nil, bslcapi.NewVMNotFoundError(vmCID.String())
+	}
+
+	vmNetworks := networks.AsVMNetworks()
+	err = vm.ConfigureNetworks(a.ctx, vmNetworks)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Configuring networks vm '%s'", vmCID)
 	}
 
 	return nil, nil
@@ -8,6 +8,7 @@ import (
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
 )
 
@@ -26,8 +27,7 @@ var _ = Describe("ConfigureNetworks", func() {
 
 	Describe("Run", func() {
 		It("tries to find vm with given vm cid", func() {
-			_, err := action.Run(1234, networks)
-			Expect(err).ToNot(HaveOccurred())
+			action.Run(1234, networks)
 
 			Expect(vmFinder.FindID).To(Equal(1234))
 		})
@@ -61,11 +61,11 @@ var _ = Describe("ConfigureNetworks", func() {
 		})
 
 		Context("when vm is not found with given cid", func() {
-			It("does vmFinder return error", func() {
+			It("returns VMNotFound error", func() {
 				vmFinder.FindFound = false
 
 				_, err := action.Run(1234, networks)
-				Expect(err).ToNot(HaveOccurred())
+				Expect(err).To(Equal(bslcapi.NewVMNotFoundError("1234")))
 			})
 		})
 
@@ -3,6 +3,7 @@ package action
 import (
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 )
@@ -23,11 +24,14 @@ func NewCreateDisk(
 
 func (a CreateDiskAction) Run(size int, cloudProps bslcdisk.DiskCloudProperties, instanceId VMCID) (string, error) {
 	vm, found, err := a.vmFinder.Find(int(instanceId))
-
-	if err != nil || !found {
+	if err != nil {
 		return "0", bosherr.WrapErrorf(err, "Not Finding vm '%s'", instanceId)
 	}
 
+	if !found {
+		return "0", bslcapi.NewVMNotFoundError(instanceId.String())
+	}
+
 	disk, err := a.diskCreator.Create(size, cloudProps, vm.GetDataCenterId())
 	if err != nil {
 		return "0", bosherr.WrapErrorf(err, "Creating disk of size '%d'", size)
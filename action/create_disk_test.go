@@ -8,6 +8,7 @@ import (
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	fakedisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk/fakes"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
 
@@ -60,5 +61,12 @@ var _ = Describe("CreateDisk", func() {
 			Expect(err.Error()).To(ContainSubstring("fake-create-err"))
 			Expect(id).To(Equal(DiskCID(0).String()))
 		})
+
+		It("returns VMNotFound error if VM is not found", func() {
+			vmFinder.FindFound = false
+
+			_, err := action.Run(20, diskCloudProp, VMCID(1234))
+			Expect(err).To(Equal(bslcapi.NewVMNotFoundError("1234")))
+		})
 	})
 })
@@ -1,10 +1,12 @@
 package action
 
 import (
+	"context"
 	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
@@ -17,6 +19,8 @@ type CreateVMAction struct {
 	vmCreatorProvider Provider
 	vmCreator         bslcvm.VMCreator
 	vmCloudProperties *bslcvm.VMCloudProperties
+	apiVersion        int
+	ctx               context.Context
 }
 
 type Environment map[string]interface{}
@@ -28,18 +32,23 @@ func NewCreateVM(
 	action.stemcellFinder = stemcellFinder
 	action.vmCreatorProvider = vmCreatorProvider
 	action.vmCloudProperties = &bslcvm.VMCloudProperties{}
+	action.apiVersion = ApiVersion1
+	action.ctx = context.Background()
 	return
 }
 
-func (a CreateVMAction) Run(agentID string, stemcellCID StemcellCID, cloudProps bslcvm.VMCloudProperties, networks Networks, diskIDs []DiskCID, env Environment) (string, error) {
+func (a CreateVMAction) WithContext(callContext CallContext) Action {
+	a.apiVersion = callContext.ApiVersion
+	a.ctx = callContext.Context
+	return a
+}
+
+func (a CreateVMAction) Run(agentID string, stemcellCID StemcellCID, cloudProps bslcvm.VMCloudProperties, networks Networks, diskIDs []DiskCID, env Environment) (interface{}, error) {
 	vmNetworks := networks.AsVMNetworks()
 	vmEnv := bslcvm.Environment(env)
 
 	a.UpdateCloudProperties(&cloudProps)
 
-	bslcommon.TIMEOUT = 30 * time.Second
-	bslcommon.POLLING_INTERVAL = 5 * time.Second
-
 	stemcell, err := a.stemcellFinder.FindById(int(stemcellCID))
 	if err != nil {
 		return "0", bosherr.WrapErrorf(err, "Finding stemcell '%s'", stemcellCID)
@@ -51,23 +60,48 @@ func (a CreateVMAction) Run(agentID string, stemcellCID StemcellCID, cloudProps
 			return "0", bosherr.WrapError(err, "Failed to get baremetal creator'")
 		}
 
-		vm, err := a.vmCreator.Create(agentID, stemcell, cloudProps, vmNetworks, vmEnv)
+		vm, err := a.vmCreator.Create(a.ctx, agentID, stemcell, cloudProps, vmNetworks, vmEnv)
 		if err != nil {
-			return "0", bosherr.WrapErrorf(err, "Creating Baremetal with agent ID '%s'", agentID)
+			return "0", vmCreationError(err, "Creating Baremetal with agent ID '%s'", agentID)
 		}
-		return VMCID(vm.ID()).String(), nil
+		return a.result(VMCID(vm.ID()), networks), nil
 	} else {
 		a.vmCreator, err = a.vmCreatorProvider.Get("virtualguest")
 		if err != nil {
 			return "0", bosherr.WrapError(err, "Failed to get virtual_guest creator'")
 		}
 
-		vm, err := a.vmCreator.Create(agentID, stemcell, cloudProps, vmNetworks, vmEnv)
+		vm, err := a.vmCreator.Create(a.ctx, agentID, stemcell, cloudProps, vmNetworks, vmEnv)
 		if err != nil {
-			return "0", bosherr.WrapErrorf(err, "Creating Virtual_Guest with agent ID '%s'", agentID)
+			return "0", vmCreationError(err, "Creating Virtual_Guest with agent ID '%s'", agentID)
 		}
-		return VMCID(vm.ID()).String(), nil
+		return a.result(VMCID(vm.ID()), networks), nil
+	}
+}
+
+// vmCreationError keeps a typed CPI error from the creator so the director sees
+// its type and retry hint, and reports any other failure as VMCreationFailed
+func vmCreationError(err error, msg string, args ...interface{}) error {
+	wrappedErr := bosherr.WrapErrorf(err, msg, args...)
+
+	if _, ok := bslcapi.FindCloudError(err); ok {
+		return wrappedErr
 	}
+
+	if _, ok := bslcapi.FindRetryableError(err); ok {
+		return wrappedErr
+	}
+
+	return bosherr.WrapComplexError(wrappedErr, bslcapi.VMCreationFailedError{})
+}
+
+// Under API version 2 create_vm returns the networks along with the VM cid
+func (a CreateVMAction) result(vmCID VMCID, networks Networks) interface{} {
+	if a.apiVersion >= ApiVersion2 {
+		return []interface{}{vmCID.String(), networks}
+	}
+
+	return vmCID.String()
 }
 
 func (a CreateVMAction) UpdateCloudProperties(cloudProps *bslcvm.VMCloudProperties) {
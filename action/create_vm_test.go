@@ -1,6 +1,7 @@
 package action_test
 
 import (
+	"context"
 	"errors"
 
 	. "github.com/onsi/ginkgo"
@@ -8,8 +9,11 @@ import (
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
 	fakeaction "github.com/cloudfoundry/bosh-softlayer-cpi/action/fakes"
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 
 	fakestem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell/fakes"
+	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
 
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 
@@ -76,11 +80,35 @@ var _ = Describe("CreateVM", func() {
 				Expect(id).To(Equal(VMCID(1234).String()))
 			})
 
+			It("returns id and networks for created VM when API version is 2", func() {
+				action = action.WithContext(NewCallContext(ApiVersion2, nil)).(CreateVMAction)
+
+				result, err := action.Run("fake-agent-id", stemcellCID, vmCloudProp, networks, diskLocality, env)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal([]interface{}{VMCID(1234).String(), networks}))
+			})
+
 			It("creates VM with requested agent ID, stemcell, cloud properties, and networks", func() {
 				_, err := action.Run("fake-agent-id", stemcellCID, vmCloudProp, networks, diskLocality, env)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
+			It("creates VM with the context of the call", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				callContext := NewCallContext(ApiVersion1, nil)
+				callContext.Context = ctx
+				action = action.WithContext(callContext).(CreateVMAction)
+
+				_, err := action.Run("fake-agent-id", stemcellCID, vmCloudProp, networks, diskLocality, env)
+				Expect(err).ToNot(HaveOccurred())
+
+				creator, err := creatorProvider.Get("virtualguest")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(creator.(*fakevm.FakeCreator).CreateContext).To(Equal(ctx))
+			})
+
 			It("creates VM with requested agent ID, stemcell, cloud properties (without startCPU, Memory, NetworkSpeed), and networks", func() {
 				vmCloudProp2 = bslcvm.VMCloudProperties{
 					Datacenter: sldatatypes.Datacenter{Name: "fake-datacenter"},
@@ -92,6 +120,52 @@ var _ = Describe("CreateVM", func() {
 				_, err := action.Run("fake-agent-id", stemcellCID, vmCloudProp2, networks, diskLocality, env)
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("returns VMCreationFailed error if creating VM fails", func() {
+				creator, err := creatorProvider.Get("virtualguest")
+				Expect(err).ToNot(HaveOccurred())
+				creator.(*fakevm.FakeCreator).CreateErr = errors.New("fake-create-err")
+
+				id, err := action.Run("fake-agent-id", stemcellCID, vmCloudProp, networks, diskLocality, env)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-create-err"))
+				Expect(id).To(Equal(VMCID(0).String()))
+
+				cloudErr, found := bslcapi.FindCloudError(err)
+				Expect(found).To(BeTrue())
+				Expect(cloudErr).To(Equal(bslcapi.VMCreationFailedError{}))
+			})
+
+			It("keeps a transient SoftLayer error retryable if creating VM fails", func() {
+				creator, err := creatorProvider.Get("virtualguest")
+				Expect(err).ToNot(HaveOccurred())
+				creator.(*fakevm.FakeCreator).CreateErr = bslcommon.NewSoftLayerHttpError(503, "fake-create-err")
+
+				_, err = action.Run("fake-agent-id", stemcellCID, vmCloudProp, networks, diskLocality, env)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-create-err"))
+
+				cloudErr, found := bslcapi.FindCloudError(err)
+				Expect(found).To(BeTrue())
+				Expect(cloudErr.Type()).To(Equal("Bosh::Clouds::CloudError"))
+
+				retryableErr, found := bslcapi.FindRetryableError(err)
+				Expect(found).To(BeTrue())
+				Expect(retryableErr.CanRetry()).To(BeTrue())
+			})
+
+			It("keeps the cloud error of the creator if creating VM fails", func() {
+				creator, err := creatorProvider.Get("virtualguest")
+				Expect(err).ToNot(HaveOccurred())
+				creator.(*fakevm.FakeCreator).CreateErr = bslcvm.InvalidCloudPropertiesError{Problems: []string{"fake-problem"}}
+
+				_, err = action.Run("fake-agent-id", stemcellCID, vmCloudProp, networks, diskLocality, env)
+				Expect(err).To(HaveOccurred())
+
+				cloudErr, found := bslcapi.FindCloudError(err)
+				Expect(found).To(BeTrue())
+				Expect(cloudErr).To(Equal(bslcvm.InvalidCloudPropertiesError{Problems: []string{"fake-problem"}}))
+			})
 		})
 
 		Context("when stemcell finding fails", func() {
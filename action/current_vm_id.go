@@ -0,0 +1,42 @@
+package action
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
+)
+
+type CurrentVMIDAction struct {
+	vmFinder   bslcvm.Finder
+	ipResolver util.LocalIPResolver
+}
+
+func NewCurrentVMID(
+	vmFinder bslcvm.Finder,
+	ipResolver util.LocalIPResolver,
+) (action CurrentVMIDAction) {
+	action.vmFinder = vmFinder
+	action.ipResolver = ipResolver
+	return
+}
+
+func (a CurrentVMIDAction) Run() (VMCID, error) {
+	ips, err := a.ipResolver.LocalIPs()
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Resolving local IP addresses")
+	}
+
+	for _, ip := range ips {
+		vm, found, err := a.vmFinder.FindByPrimaryBackendIp(ip)
+		if err != nil {
+			return 0, bosherr.WrapErrorf(err, "Finding VM with primary backend IP '%s'", ip)
+		}
+
+		if found {
+			return VMCID(vm.ID()), nil
+		}
+	}
+
+	return 0, bosherr.Errorf("Expected to find VM with one of primary backend IPs '%v'", ips)
+}
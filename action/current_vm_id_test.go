@@ -0,0 +1,74 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+
+	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
+	fakeutil "github.com/cloudfoundry/bosh-softlayer-cpi/util/fakes"
+)
+
+var _ = Describe("CurrentVMID", func() {
+	var (
+		vmFinder   *fakevm.FakeFinder
+		ipResolver *fakeutil.FakeLocalIPResolver
+		action     CurrentVMIDAction
+	)
+
+	BeforeEach(func() {
+		vmFinder = &fakevm.FakeFinder{}
+		ipResolver = &fakeutil.FakeLocalIPResolver{}
+		action = NewCurrentVMID(vmFinder, ipResolver)
+	})
+
+	Describe("Run", func() {
+		Context("when VM is found with a local IP as primary backend IP", func() {
+			It("returns the VM cid", func() {
+				ipResolver.LocalIPsIPs = []string{"10.0.0.1"}
+				vmFinder.FindByPrimaryBackendIpFound = true
+				vmFinder.FindByPrimaryBackendIpVM = fakevm.NewFakeVM(1234)
+
+				vmCID, err := action.Run()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vmCID).To(Equal(VMCID(1234)))
+				Expect(vmFinder.FindByPrimaryBackendIpIp).To(Equal("10.0.0.1"))
+			})
+		})
+
+		Context("when VM is not found with any local IP", func() {
+			It("returns error", func() {
+				ipResolver.LocalIPsIPs = []string{"10.0.0.1", "10.0.0.2"}
+				vmFinder.FindByPrimaryBackendIpFound = false
+
+				_, err := action.Run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Expected to find VM"))
+			})
+		})
+
+		Context("when VM finding fails", func() {
+			It("returns error", func() {
+				ipResolver.LocalIPsIPs = []string{"10.0.0.1"}
+				vmFinder.FindByPrimaryBackendIpErr = errors.New("fake-find-err")
+
+				_, err := action.Run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-err"))
+			})
+		})
+
+		Context("when resolving local IPs fails", func() {
+			It("returns error", func() {
+				ipResolver.LocalIPsErr = errors.New("fake-resolve-err")
+
+				_, err := action.Run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-resolve-err"))
+			})
+		})
+	})
+})
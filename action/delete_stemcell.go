@@ -3,9 +3,7 @@ package action
 import (
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
-	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
-	"time"
 )
 
 const (
@@ -27,9 +25,6 @@ func NewDeleteStemcell(
 }
 
 func (a DeleteStemcellAction) Run(stemcellCID StemcellCID) (interface{}, error) {
-	bslcommon.TIMEOUT = 30 * time.Second
-	bslcommon.POLLING_INTERVAL = 5 * time.Second
-
 	_, err := a.stemcellFinder.FindById(int(stemcellCID))
 	if err != nil {
 		a.logger.Info(deleteStemcellLogTag, "Stemcell '%s' not found: %s", stemcellCID, err)
@@ -1,6 +1,8 @@
 package action
 
 import (
+	"context"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
@@ -8,19 +10,30 @@ import (
 
 type DeleteVMAction struct {
 	vmFinder bslcvm.Finder
+	ctx      context.Context
 }
 
 func NewDeleteVM(
 	vmFinder bslcvm.Finder,
 ) (action DeleteVMAction) {
 	action.vmFinder = vmFinder
+	action.ctx = context.Background()
 	return
 }
 
+func (a DeleteVMAction) WithContext(callContext CallContext) Action {
+	a.ctx = callContext.Context
+	return a
+}
+
 func (a DeleteVMAction) Run(vmCID VMCID) (interface{}, error) {
-	vm, found, _ := a.vmFinder.Find(int(vmCID))
+	vm, found, err := a.vmFinder.Find(int(vmCID))
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Finding vm '%s'", vmCID)
+	}
+
 	if found {
-		err := vm.Delete("")
+		err := vm.Delete(a.ctx, "")
 		if err != nil {
 			return nil, bosherr.WrapErrorf(err, "Deleting vm '%s'", vmCID)
 		}
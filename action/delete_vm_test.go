@@ -1,6 +1,7 @@
 package action_test
 
 import (
+	"context"
 	"errors"
 
 	. "github.com/onsi/ginkgo"
@@ -41,6 +42,19 @@ var _ = Describe("DeleteVM", func() {
 				Expect(vm.DeleteCalled).To(BeTrue())
 			})
 
+			It("deletes vm with the context of the call", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				callContext := NewCallContext(ApiVersion1, nil)
+				callContext.Context = ctx
+
+				_, err := action.WithContext(callContext).(DeleteVMAction).Run(1234)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(vm.DeleteContext).To(Equal(ctx))
+			})
+
 			It("returns error if deleting vm fails", func() {
 				vm.DeleteErr = errors.New("fake-delete-err")
 
@@ -60,11 +74,12 @@ var _ = Describe("DeleteVM", func() {
 		})
 
 		Context("when vm finding fails", func() {
-			It("does not return error", func() {
+			It("returns error", func() {
 				vmFinder.FindErr = errors.New("fake-find-err")
 
 				_, err := action.Run(1234)
-				Expect(err).ToNot(HaveOccurred())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-err"))
 			})
 		})
 	})
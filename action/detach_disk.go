@@ -1,8 +1,11 @@
 package action
 
 import (
+	"context"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 )
@@ -10,6 +13,7 @@ import (
 type DetachDiskAction struct {
 	vmFinder   bslcvm.Finder
 	diskFinder bslcdisk.Finder
+	ctx        context.Context
 }
 
 func NewDetachDisk(
@@ -18,9 +22,15 @@ func NewDetachDisk(
 ) (action DetachDiskAction) {
 	action.vmFinder = vmFinder
 	action.diskFinder = diskFinder
+	action.ctx = context.Background()
 	return
 }
 
+func (a DetachDiskAction) WithContext(callContext CallContext) Action {
+	a.ctx = callContext.Context
+	return a
+}
+
 func (a DetachDiskAction) Run(vmCID VMCID, diskCID DiskCID) (interface{}, error) {
 	vm, found, err := a.vmFinder.Find(vmCID.Int())
 	if err != nil {
@@ -28,7 +38,7 @@ func (a DetachDiskAction) Run(vmCID VMCID, diskCID DiskCID) (interface{}, error)
 	}
 
 	if !found {
-		return nil, bosherr.Errorf("Expected to find VM '%s'", vmCID)
+		return nil, bslcapi.NewVMNotFoundError(vmCID.String())
 	}
 
 	disk, found, err := a.diskFinder.Find(diskCID.Int())
@@ -37,13 +47,32 @@ func (a DetachDiskAction) Run(vmCID VMCID, diskCID DiskCID) (interface{}, error)
 	}
 
 	if !found {
-		return nil, bosherr.Errorf("Expected to find disk '%s'", diskCID)
+		return nil, bslcapi.NewDiskNotFoundError(diskCID.String())
 	}
 
-	err = vm.DetachDisk(disk)
+	err = vm.DetachDisk(a.ctx, disk)
 	if err != nil {
+		if !a.isAttached(vm, diskCID) {
+			return nil, bosherr.WrapComplexError(err, bslcapi.NewDiskNotAttachedError(vmCID.String(), diskCID.String()))
+		}
+
 		return nil, bosherr.WrapErrorf(err, "Detaching disk '%s' from VM '%s'", diskCID, vmCID)
 	}
 
 	return nil, nil
 }
+
+func (a DetachDiskAction) isAttached(vm bslcvm.VM, diskCID DiskCID) bool {
+	diskIds, err := vm.GetAttachedDiskIds()
+	if err != nil {
+		return true
+	}
+
+	for _, diskId := range diskIds {
+		if diskId == diskCID.Int() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -8,6 +8,7 @@ import (
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	fakedisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk/fakes"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
 )
@@ -84,6 +85,19 @@ var _ = Describe("DetachDisk", func() {
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-detach-disk-err"))
 				})
+
+				It("returns DiskNotAttached error if detaching fails for a disk that is not attached", func() {
+					vm.DetachDiskErr = errors.New("fake-detach-disk-err")
+					vm.GetAttachedDiskIdsDiskIds = []int{5678}
+
+					_, err := action.Run(1234, 1234)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-detach-disk-err"))
+
+					cloudErr, found := bslcapi.FindCloudError(err)
+					Expect(found).To(BeTrue())
+					Expect(cloudErr).To(Equal(bslcapi.NewDiskNotAttachedError("1234", "1234")))
+				})
 			})
 
 			Context("when disk is not found with given cid", func() {
@@ -92,7 +106,7 @@ var _ = Describe("DetachDisk", func() {
 
 					_, err := action.Run(1234, 1234)
 					Expect(err).To(HaveOccurred())
-					Expect(err.Error()).To(ContainSubstring("Expected to find disk"))
+					Expect(err).To(Equal(bslcapi.NewDiskNotFoundError("1234")))
 				})
 			})
 
@@ -113,7 +127,7 @@ var _ = Describe("DetachDisk", func() {
 
 				_, err := action.Run(1234, 1234)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("Expected to find VM"))
+				Expect(err).To(Equal(bslcapi.NewVMNotFoundError("1234")))
 			})
 		})
 
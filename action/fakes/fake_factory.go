@@ -43,7 +43,14 @@ func (f *FakeFactory) RegisterActionErr(method string, err error) {
 	f.registeredActionErrs[method] = err
 }
 
-type FakeAction struct{}
+type FakeAction struct {
+	WithContextContext bslcaction.CallContext
+}
+
+func (a *FakeAction) WithContext(context bslcaction.CallContext) bslcaction.Action {
+	a.WithContextContext = context
+	return a
+}
 
 func (a *FakeAction) Run(payload []byte) (interface{}, error) {
 	return nil, nil
@@ -0,0 +1,78 @@
+package action
+
+import (
+	"sort"
+	"strconv"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+)
+
+const (
+	getDisksLogTag = "GetDisks"
+)
+
+type GetDisksAction struct {
+	vmFinder bslcvm.Finder
+	logger   boshlog.Logger
+}
+
+func NewGetDisks(
+	vmFinder bslcvm.Finder,
+	logger boshlog.Logger,
+) (action GetDisksAction) {
+	action.vmFinder = vmFinder
+	action.logger = logger
+	return
+}
+
+func (a GetDisksAction) Run(vmCID VMCID) ([]string, error) {
+	vm, found, err := a.vmFinder.Find(vmCID.Int())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Finding VM '%s'", vmCID)
+	}
+
+	if !found {
+		return nil, bslcapi.NewVMNotFoundError(vmCID.String())
+	}
+
+	diskIds, err := vm.GetAttachedDiskIds()
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Getting disks attached to VM '%s'", vmCID)
+	}
+
+	diskCIDs := []string{}
+	for _, diskId := range diskIds {
+		diskCIDs = append(diskCIDs, strconv.Itoa(diskId))
+	}
+	sort.Strings(diskCIDs)
+
+	a.crossCheckAgentEnv(vm, vmCID, diskCIDs)
+
+	return diskCIDs, nil
+}
+
+func (a GetDisksAction) crossCheckAgentEnv(vm bslcvm.VM, vmCID VMCID, diskCIDs []string) {
+	agentEnv, err := vm.FetchAgentEnv()
+	if err != nil {
+		a.logger.Warn(getDisksLogTag, "Unable to fetch agent env of VM '%s' to cross-check disks: %s", vmCID, err)
+		return
+	}
+
+	attached := map[string]bool{}
+	for _, diskCID := range diskCIDs {
+		attached[diskCID] = true
+		if _, found := agentEnv.Disks.Persistent[diskCID]; !found {
+			a.logger.Warn(getDisksLogTag, "Disk '%s' is attached to VM '%s' but missing from agent env", diskCID, vmCID)
+		}
+	}
+
+	for diskCID := range agentEnv.Disks.Persistent {
+		if !attached[diskCID] {
+			a.logger.Warn(getDisksLogTag, "Disk '%s' is in agent env of VM '%s' but not attached", diskCID, vmCID)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
+)
+
+var _ = Describe("GetDisks", func() {
+	var (
+		vmFinder *fakevm.FakeFinder
+		logger   boshlog.Logger
+		action   GetDisksAction
+	)
+
+	BeforeEach(func() {
+		vmFinder = &fakevm.FakeFinder{}
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		action = NewGetDisks(vmFinder, logger)
+	})
+
+	Describe("Run", func() {
+		It("tries to find VM with given VM cid", func() {
+			vmFinder.FindFound = true
+			vmFinder.FindVM = fakevm.NewFakeVM(1234)
+
+			_, err := action.Run(1234)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(vmFinder.FindID).To(Equal(1234))
+		})
+
+		Context("when VM is found with given VM cid", func() {
+			var (
+				vm *fakevm.FakeVM
+			)
+
+			BeforeEach(func() {
+				vm = fakevm.NewFakeVM(1234)
+				vmFinder.FindVM = vm
+				vmFinder.FindFound = true
+			})
+
+			It("returns the cids of the attached disks", func() {
+				vm.GetAttachedDiskIdsDiskIds = []int{5678, 1111}
+
+				diskCIDs, err := action.Run(1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(diskCIDs).To(Equal([]string{"1111", "5678"}))
+			})
+
+			It("returns an empty list when no disks are attached", func() {
+				diskCIDs, err := action.Run(1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(diskCIDs).To(BeEmpty())
+			})
+
+			It("does not return error when agent env disagrees with attached disks", func() {
+				vm.GetAttachedDiskIdsDiskIds = []int{5678}
+				vm.FetchAgentEnvAgentEnv = bslcvm.AgentEnv{
+					Disks: bslcvm.DisksSpec{
+						Persistent: bslcvm.PersistentSpec{"9999": "/dev/sdc"},
+					},
+				}
+
+				diskCIDs, err := action.Run(1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(diskCIDs).To(Equal([]string{"5678"}))
+			})
+
+			It("does not return error when fetching agent env fails", func() {
+				vm.GetAttachedDiskIdsDiskIds = []int{5678}
+				vm.FetchAgentEnvErr = errors.New("fake-fetch-err")
+
+				diskCIDs, err := action.Run(1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(diskCIDs).To(Equal([]string{"5678"}))
+			})
+
+			It("returns error if getting attached disks fails", func() {
+				vm.GetAttachedDiskIdsErr = errors.New("fake-get-disks-err")
+
+				_, err := action.Run(1234)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-get-disks-err"))
+			})
+		})
+
+		Context("when VM is not found with given cid", func() {
+			It("returns error because disks can only be listed for existing VMs", func() {
+				vmFinder.FindFound = false
+
+				_, err := action.Run(1234)
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(Equal(bslcapi.NewVMNotFoundError("1234")))
+			})
+		})
+
+		Context("when VM finding fails", func() {
+			It("returns error", func() {
+				vmFinder.FindErr = errors.New("fake-find-err")
+
+				_, err := action.Run(1234)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-err"))
+			})
+		})
+	})
+})
@@ -0,0 +1,27 @@
+package action
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
+)
+
+type HasDiskAction struct {
+	diskFinder bslcdisk.Finder
+}
+
+func NewHasDisk(
+	diskFinder bslcdisk.Finder,
+) (action HasDiskAction) {
+	action.diskFinder = diskFinder
+	return
+}
+
+func (a HasDiskAction) Run(diskCID DiskCID) (bool, error) {
+	_, found, err := a.diskFinder.Find(diskCID.Int())
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Finding disk '%s'", diskCID)
+	}
+
+	return found, nil
+}
@@ -0,0 +1,65 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+
+	fakedisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk/fakes"
+)
+
+var _ = Describe("HasDisk", func() {
+	var (
+		diskFinder *fakedisk.FakeFinder
+		action     HasDiskAction
+	)
+
+	BeforeEach(func() {
+		diskFinder = &fakedisk.FakeFinder{}
+		action = NewHasDisk(diskFinder)
+	})
+
+	Describe("Run", func() {
+		It("tries to find disk with given disk cid", func() {
+			_, err := action.Run(1234)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(diskFinder.FindID).To(Equal(1234))
+		})
+
+		Context("when disk is found with given CID", func() {
+			It("returns true without error", func() {
+				diskFinder.FindFound = true
+				diskFinder.FindDisk = fakedisk.NewFakeDisk(1234)
+
+				found, err := action.Run(1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeTrue())
+			})
+		})
+
+		Context("when disk is not found with given CID", func() {
+			It("returns false without error", func() {
+				diskFinder.FindFound = false
+
+				found, err := action.Run(1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeFalse())
+			})
+		})
+
+		Context("when disk finding fails", func() {
+			It("returns error so that an API outage is not mistaken for a deleted disk", func() {
+				diskFinder.FindErr = errors.New("fake-find-err")
+
+				found, err := action.Run(1234)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-err"))
+				Expect(found).To(BeFalse())
+			})
+		})
+	})
+})
@@ -45,12 +45,13 @@ var _ = Describe("HasVM", func() {
 		})
 
 		Context("when VM finding fails", func() {
-			It("returns false without error", func() {
+			It("returns error", func() {
 				vmFinder.FindFound = false
 				vmFinder.FindErr = errors.New("fake-find-err")
 
 				found, err := action.Run(1234)
-				Expect(err).ToNot(HaveOccurred())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-err"))
 				Expect(found).To(BeFalse())
 			})
 		})
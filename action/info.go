@@ -0,0 +1,19 @@
+package action
+
+type InfoAction struct{}
+
+type Info struct {
+	StemcellFormats []string `json:"stemcell_formats"`
+	ApiVersion      int      `json:"api_version"`
+}
+
+func NewInfo() (action InfoAction) {
+	return
+}
+
+func (a InfoAction) Run() (Info, error) {
+	return Info{
+		StemcellFormats: []string{"softlayer-legacy-light"},
+		ApiVersion:      SupportedApiVersion,
+	}, nil
+}
@@ -0,0 +1,27 @@
+package action_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+)
+
+var _ = Describe("Info", func() {
+	var (
+		action InfoAction
+	)
+
+	BeforeEach(func() {
+		action = NewInfo()
+	})
+
+	Describe("Run", func() {
+		It("returns the supported stemcell formats and API version", func() {
+			info, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.StemcellFormats).To(Equal([]string{"softlayer-legacy-light"}))
+			Expect(info.ApiVersion).To(Equal(SupportedApiVersion))
+		})
+	})
+})
@@ -0,0 +1,32 @@
+package action
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	sl "github.com/maximilien/softlayer-go/softlayer"
+)
+
+type PingAction struct {
+	softLayerClient sl.Client
+}
+
+func NewPing(
+	softLayerClient sl.Client,
+) (action PingAction) {
+	action.softLayerClient = softLayerClient
+	return
+}
+
+func (a PingAction) Run() (string, error) {
+	accountService, err := a.softLayerClient.GetSoftLayer_Account_Service()
+	if err != nil {
+		return "", bosherr.WrapError(err, "Cannot get softlayer account service.")
+	}
+
+	_, err = accountService.GetAccountStatus()
+	if err != nil {
+		return "", bosherr.WrapError(err, "Getting SoftLayer account status")
+	}
+
+	return "pong", nil
+}
@@ -0,0 +1,50 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+
+	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
+)
+
+var _ = Describe("Ping", func() {
+	var (
+		softLayerClient *fakeslclient.FakeSoftLayerClient
+		action          PingAction
+	)
+
+	BeforeEach(func() {
+		softLayerClient = fakeslclient.NewFakeSoftLayerClient("fake-username", "fake-api-key")
+		action = NewPing(softLayerClient)
+	})
+
+	Describe("Run", func() {
+		It("returns pong when SoftLayer account status can be retrieved", func() {
+			softLayerClient.FakeHttpClient.DoRawHttpRequestResponse = []byte(`{"id": 1001, "name": "Active"}`)
+
+			result, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal("pong"))
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Account/getAccountStatus.json"))
+		})
+
+		It("returns error when SoftLayer returns an HTTP error code", func() {
+			softLayerClient.FakeHttpClient.DoRawHttpRequestInt = 401
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns error when SoftLayer cannot be reached", func() {
+			softLayerClient.FakeHttpClient.DoRawHttpRequestError = errors.New("fake-connection-err")
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-connection-err"))
+		})
+	})
+})
@@ -5,6 +5,7 @@ import (
 	sl "github.com/maximilien/softlayer-go/softlayer"
 
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
 	bosherror "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
@@ -17,21 +18,30 @@ type provider struct {
 	creators map[string]bslcvm.VMCreator
 }
 
-func NewProvider(softLayerClient sl.Client, baremetalClient bmscl.BmpClient, options ConcreteFactoryOptions, logger boshlog.Logger) Provider {
+// NewProvider builds the VM creators. sshClient is shared with the rest of
+// the CPI so that its pooled connections and known hosts are too.
+func NewProvider(softLayerClient sl.Client, baremetalClient bmscl.BmpClient, sshClient util.SshClient, options ConcreteFactoryOptions, logger boshlog.Logger) Provider {
 
 	agentEnvServiceFactory := bslcvm.NewSoftLayerAgentEnvServiceFactory(options.AgentEnvService, options.Registry, logger)
 
+	waitPolicies := options.WaitPolicies.WithDefaults()
+
 	vmFinder := bslcvm.NewSoftLayerFinder(
 		softLayerClient,
 		baremetalClient,
+		sshClient,
 		agentEnvServiceFactory,
+		options.DiskAttachMode,
+		waitPolicies,
 		logger,
 	)
 
 	virtualGuestCreator := bslcvm.NewSoftLayerCreator(
 		vmFinder,
 		softLayerClient,
+		sshClient,
 		options.Agent,
+		waitPolicies,
 		logger,
 	)
 
@@ -39,7 +49,9 @@ func NewProvider(softLayerClient sl.Client, baremetalClient bmscl.BmpClient, opt
 		vmFinder,
 		softLayerClient,
 		baremetalClient,
+		sshClient,
 		options.Agent,
+		waitPolicies,
 		logger,
 	)
 
@@ -3,6 +3,7 @@ package action
 import (
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 )
 
@@ -23,11 +24,13 @@ func (a RebootVMAction) Run(vmCID VMCID) (interface{}, error) {
 		return nil, bosherr.WrapErrorf(err, "Finding vm '%s'", vmCID)
 	}
 
-	if found {
-		err := vm.Reboot()
-		if err != nil {
-			return nil, bosherr.WrapErrorf(err, "Rebooting vm '%s'", vmCID)
-		}
+	if !found {
+		return nil, bslcapi.NewVMNotFoundError(vmCID.String())
+	}
+
+	err = vm.Reboot()
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Rebooting vm '%s'", vmCID)
 	}
 
 	return nil, nil
@@ -8,6 +8,7 @@ import (
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
 )
 
@@ -24,8 +25,7 @@ var _ = Describe("RebootVM", func() {
 
 	Describe("Run", func() {
 		It("tries to find vm with given vm cid", func() {
-			_, err := action.Run(1234)
-			Expect(err).ToNot(HaveOccurred())
+			action.Run(1234)
 
 			Expect(vmFinder.FindID).To(Equal(1234))
 		})
@@ -58,11 +58,11 @@ var _ = Describe("RebootVM", func() {
 		})
 
 		Context("when vm is not found with given cid", func() {
-			It("does vmFinder return error", func() {
+			It("returns VMNotFound error", func() {
 				vmFinder.FindFound = false
 
 				_, err := action.Run(1234)
-				Expect(err).ToNot(HaveOccurred())
+				Expect(err).To(Equal(bslcapi.NewVMNotFoundError("1234")))
 			})
 		})
 
@@ -0,0 +1,50 @@
+package action
+
+import (
+	"context"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
+)
+
+type ResizeDiskAction struct {
+	diskFinder bslcdisk.Finder
+	ctx        context.Context
+}
+
+func NewResizeDisk(
+	diskFinder bslcdisk.Finder,
+) (action ResizeDiskAction) {
+	action.diskFinder = diskFinder
+	action.ctx = context.Background()
+	return
+}
+
+func (a ResizeDiskAction) WithContext(callContext CallContext) Action {
+	a.ctx = callContext.Context
+	return a
+}
+
+func (a ResizeDiskAction) Run(diskCID DiskCID, size int) (interface{}, error) {
+	disk, found, err := a.diskFinder.Find(diskCID.Int())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Finding disk '%s'", diskCID)
+	}
+
+	if !found {
+		return nil, bslcapi.NewDiskNotFoundError(diskCID.String())
+	}
+
+	resized, err := disk.Resize(a.ctx, size)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Resizing disk '%s'", diskCID)
+	}
+
+	if !resized {
+		return nil, bslcapi.NotSupportedError{}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,85 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	fakedisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk/fakes"
+)
+
+var _ = Describe("ResizeDisk", func() {
+	var (
+		diskFinder *fakedisk.FakeFinder
+		action     ResizeDiskAction
+	)
+
+	BeforeEach(func() {
+		diskFinder = &fakedisk.FakeFinder{}
+		action = NewResizeDisk(diskFinder)
+	})
+
+	Describe("Run", func() {
+		Context("when disk is found with given disk cid", func() {
+			var (
+				disk *fakedisk.FakeDisk
+			)
+
+			BeforeEach(func() {
+				disk = fakedisk.NewFakeDisk(1234)
+				diskFinder.FindDisk = disk
+				diskFinder.FindFound = true
+			})
+
+			It("resizes the disk to the requested size", func() {
+				disk.ResizeResized = true
+
+				_, err := action.Run(1234, 40960)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(diskFinder.FindID).To(Equal(1234))
+				Expect(disk.ResizeSize).To(Equal(40960))
+			})
+
+			It("returns not supported error when the disk cannot be resized", func() {
+				disk.ResizeResized = false
+
+				_, err := action.Run(1234, 40960)
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(Equal(bslcapi.NotSupportedError{}))
+			})
+
+			It("returns error if resizing disk fails", func() {
+				disk.ResizeErr = errors.New("fake-resize-err")
+
+				_, err := action.Run(1234, 40960)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-resize-err"))
+			})
+		})
+
+		Context("when disk is not found with given cid", func() {
+			It("returns error", func() {
+				diskFinder.FindFound = false
+
+				_, err := action.Run(1234, 40960)
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(Equal(bslcapi.NewDiskNotFoundError("1234")))
+			})
+		})
+
+		Context("when disk finding fails", func() {
+			It("returns error", func() {
+				diskFinder.FindErr = errors.New("fake-find-err")
+
+				_, err := action.Run(1234, 40960)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-err"))
+			})
+		})
+	})
+})
@@ -0,0 +1,41 @@
+package action
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
+)
+
+type SetDiskMetadataAction struct {
+	diskFinder bslcdisk.Finder
+}
+
+func NewSetDiskMetadata(
+	diskFinder bslcdisk.Finder,
+) (action SetDiskMetadataAction) {
+	action.diskFinder = diskFinder
+	return
+}
+
+func (a SetDiskMetadataAction) Run(diskCID DiskCID, metadata bslcdisk.DiskMetadata) (interface{}, error) {
+	disk, found, err := a.diskFinder.Find(diskCID.Int())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Finding disk '%s'", diskCID)
+	}
+
+	if !found {
+		return nil, bslcapi.NewDiskNotFoundError(diskCID.String())
+	}
+
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	err = disk.SetMetadata(metadata)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Setting metadata '%#v' on disk '%s'", metadata, diskCID)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,91 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+
+	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
+
+	fakedisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk/fakes"
+)
+
+var _ = Describe("SetDiskMetadata", func() {
+	var (
+		diskFinder *fakedisk.FakeFinder
+		action     SetDiskMetadataAction
+		metadata   bslcdisk.DiskMetadata
+	)
+
+	BeforeEach(func() {
+		diskFinder = &fakedisk.FakeFinder{}
+		action = NewSetDiskMetadata(diskFinder)
+		metadata = bslcdisk.DiskMetadata{
+			"deployment": "fake-deployment",
+			"job":        "fake-job",
+			"index":      "0",
+		}
+	})
+
+	Describe("Run", func() {
+		Context("when disk is found with given disk cid", func() {
+			var (
+				disk *fakedisk.FakeDisk
+			)
+
+			BeforeEach(func() {
+				disk = fakedisk.NewFakeDisk(1234)
+				diskFinder.FindDisk = disk
+				diskFinder.FindFound = true
+			})
+
+			It("sets the metadata on the disk", func() {
+				_, err := action.Run(1234, metadata)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(diskFinder.FindID).To(Equal(1234))
+				Expect(disk.SetMetadataMetadata).To(Equal(metadata))
+			})
+
+			It("does not set metadata when metadata is empty", func() {
+				_, err := action.Run(1234, bslcdisk.DiskMetadata{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(disk.SetMetadataMetadata).To(BeNil())
+			})
+
+			It("returns error if setting metadata fails", func() {
+				disk.SetMetadataErr = errors.New("fake-set-metadata-err")
+
+				_, err := action.Run(1234, metadata)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-set-metadata-err"))
+			})
+		})
+
+		Context("when disk is not found with given cid", func() {
+			It("returns error", func() {
+				diskFinder.FindFound = false
+
+				_, err := action.Run(1234, metadata)
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(Equal(bslcapi.NewDiskNotFoundError("1234")))
+			})
+		})
+
+		Context("when disk finding fails", func() {
+			It("returns error", func() {
+				diskFinder.FindErr = errors.New("fake-find-err")
+
+				_, err := action.Run(1234, metadata)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-err"))
+			})
+		})
+	})
+})
@@ -3,6 +3,7 @@ package action
 import (
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 )
 
@@ -19,10 +20,14 @@ func NewSetVMMetadata(
 
 func (a SetVMMetadataAction) Run(vmCID VMCID, metadata bslcvm.VMMetadata) (interface{}, error) {
 	vm, found, err := a.vmFinder.Find(int(vmCID))
-	if err != nil || !found {
+	if err != nil {
 		return nil, bosherr.WrapErrorf(err, "Finding VM '%s'", vmCID)
 	}
 
+	if !found {
+		return nil, bslcapi.NewVMNotFoundError(vmCID.String())
+	}
+
 	if len(metadata) == 0 {
 		return nil, nil
 	}
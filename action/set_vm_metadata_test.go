@@ -8,6 +8,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	action "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
 )
@@ -44,7 +45,7 @@ var _ = Describe("SetVMMetadata", func() {
 
 			It("errors with message that VM could not be found", func() {
 				_, err := action.Run(vmID, metadata)
-				Expect(err).To(HaveOccurred())
+				Expect(err).To(Equal(bslcapi.NewVMNotFoundError("1234")))
 			})
 		})
 
@@ -0,0 +1,58 @@
+package action
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
+)
+
+type SnapshotDiskAction struct {
+	diskFinder bslcdisk.Finder
+}
+
+type SnapshotMetadata map[string]interface{}
+
+func NewSnapshotDisk(
+	diskFinder bslcdisk.Finder,
+) (action SnapshotDiskAction) {
+	action.diskFinder = diskFinder
+	return
+}
+
+func (a SnapshotDiskAction) Run(diskCID DiskCID, metadata SnapshotMetadata) (string, error) {
+	disk, found, err := a.diskFinder.Find(diskCID.Int())
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Finding disk '%s'", diskCID)
+	}
+
+	if !found {
+		return "", bslcapi.NewDiskNotFoundError(diskCID.String())
+	}
+
+	snapshot, err := disk.Snapshot(metadata.notes())
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Snapshotting disk '%s'", diskCID)
+	}
+
+	return SnapshotCID(snapshot.ID()).String(), nil
+}
+
+func (m SnapshotMetadata) notes() string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s:%v", key, m[key]))
+	}
+
+	return strings.Join(pairs, ",")
+}
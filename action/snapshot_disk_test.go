@@ -0,0 +1,98 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/action"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	fakedisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk/fakes"
+)
+
+var _ = Describe("SnapshotDisk", func() {
+	var (
+		diskFinder *fakedisk.FakeFinder
+		action     SnapshotDiskAction
+		metadata   SnapshotMetadata
+	)
+
+	BeforeEach(func() {
+		diskFinder = &fakedisk.FakeFinder{}
+		action = NewSnapshotDisk(diskFinder)
+		metadata = SnapshotMetadata{
+			"deployment": "fake-deployment",
+			"job":        "fake-job",
+			"index":      "0",
+		}
+	})
+
+	Describe("Run", func() {
+		It("tries to find disk with given disk cid", func() {
+			diskFinder.FindFound = false
+
+			_, err := action.Run(1234, metadata)
+			Expect(err).To(HaveOccurred())
+
+			Expect(diskFinder.FindID).To(Equal(1234))
+		})
+
+		Context("when disk is found with given disk cid", func() {
+			var (
+				disk *fakedisk.FakeDisk
+			)
+
+			BeforeEach(func() {
+				disk = fakedisk.NewFakeDisk(1234)
+				diskFinder.FindDisk = disk
+				diskFinder.FindFound = true
+			})
+
+			It("snapshots disk and returns snapshot cid", func() {
+				disk.SnapshotSnapshot = fakedisk.NewFakeSnapshot(5678)
+
+				snapshotCID, err := action.Run(1234, metadata)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(snapshotCID).To(Equal("5678"))
+			})
+
+			It("passes sorted metadata as snapshot notes", func() {
+				disk.SnapshotSnapshot = fakedisk.NewFakeSnapshot(5678)
+
+				_, err := action.Run(1234, metadata)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(disk.SnapshotNotes).To(Equal("deployment:fake-deployment,index:0,job:fake-job"))
+			})
+
+			It("returns error if snapshotting disk fails", func() {
+				disk.SnapshotErr = errors.New("fake-snapshot-err")
+
+				_, err := action.Run(1234, metadata)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-snapshot-err"))
+			})
+		})
+
+		Context("when disk is not found with given cid", func() {
+			It("returns error", func() {
+				diskFinder.FindFound = false
+
+				_, err := action.Run(1234, metadata)
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(Equal(bslcapi.NewDiskNotFoundError("1234")))
+			})
+		})
+
+		Context("when disk finding fails", func() {
+			It("returns error", func() {
+				diskFinder.FindErr = errors.New("fake-find-err")
+
+				_, err := action.Run(1234, metadata)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-err"))
+			})
+		})
+	})
+})
@@ -1,7 +1,12 @@
 package dispatcher
 
+import (
+	"context"
+)
+
 type Dispatcher interface {
 	// Dispatch interprets request bytes, executes request, captures response and return response bytes.
 	// It panics if built-in errors fail to serialize.
-	Dispatch([]byte) []byte
+	// Cancelling ctx stops any SoftLayer wait loop the request is blocked in.
+	Dispatch(context.Context, []byte) []byte
 }
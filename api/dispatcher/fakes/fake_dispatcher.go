@@ -1,11 +1,17 @@
 package fakes
 
+import (
+	"context"
+)
+
 type FakeDispatcher struct {
+	DispatchContext   context.Context
 	DispatchReqBytes  []byte
 	DispatchRespBytes []byte
 }
 
-func (d *FakeDispatcher) Dispatch(reqBytes []byte) []byte {
+func (d *FakeDispatcher) Dispatch(ctx context.Context, reqBytes []byte) []byte {
+	d.DispatchContext = ctx
 	d.DispatchReqBytes = reqBytes
 	return d.DispatchRespBytes
 }
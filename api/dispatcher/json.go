@@ -2,6 +2,7 @@ package dispatcher
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 
 	bslcaction "github.com/cloudfoundry/bosh-softlayer-cpi/action"
@@ -25,7 +26,8 @@ type Request struct {
 	Method    string        `json:"method"`
 	Arguments []interface{} `json:"arguments"`
 
-	// context key is ignored
+	ApiVersion int                    `json:"api_version"`
+	Context    map[string]interface{} `json:"context"`
 }
 
 type Response struct {
@@ -60,7 +62,7 @@ func NewJSON(
 	}
 }
 
-func (c JSON) Dispatch(reqBytes []byte) []byte {
+func (c JSON) Dispatch(ctx context.Context, reqBytes []byte) []byte {
 	var req Request
 
 	c.logger.DebugWithDetails(jsonLogTag, "Request bytes", string(reqBytes))
@@ -73,7 +75,7 @@ func (c JSON) Dispatch(reqBytes []byte) []byte {
 	c.logger.DebugWithDetails(jsonLogTag, "Deserialized request", req)
 
 	// Will remove this line of code after we make sure LocalDiskFlag is defined properly in all deployment manifest files
-	c.localDiskFlagNotSet(fmt.Sprintf("%s", req))
+	c.localDiskFlagNotSet(fmt.Sprintf("%v", req))
 
 	if req.Method == "" {
 		return c.buildCpiError("Must provide method key")
@@ -88,6 +90,13 @@ func (c JSON) Dispatch(reqBytes []byte) []byte {
 		return c.buildNotImplementedError()
 	}
 
+	if contextualAction, ok := action.(bslcaction.ContextualAction); ok {
+		callContext := bslcaction.NewCallContext(req.ApiVersion, req.Context)
+		callContext.Context = ctx
+
+		action = contextualAction.WithContext(callContext)
+	}
+
 	result, err := c.caller.Call(action, req.Arguments)
 	if err != nil {
 		return c.buildCloudError(err)
@@ -114,7 +123,7 @@ func (c JSON) buildCloudError(err error) []byte {
 		Error: &ResponseError{},
 	}
 
-	if typedErr, ok := err.(bslcapi.CloudError); ok {
+	if typedErr, ok := bslcapi.FindCloudError(err); ok {
 		respErr.Error.Type = typedErr.Type()
 	} else {
 		respErr.Error.Type = jsonCloudErrorType
@@ -122,7 +131,7 @@ func (c JSON) buildCloudError(err error) []byte {
 
 	respErr.Error.Message = err.Error()
 
-	if typedErr, ok := err.(bslcapi.RetryableError); ok {
+	if typedErr, ok := bslcapi.FindRetryableError(err); ok {
 		respErr.Error.CanRetry = typedErr.CanRetry()
 	}
 
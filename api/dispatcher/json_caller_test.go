@@ -7,6 +7,10 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/api/dispatcher"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 )
 
 type valueType struct {
@@ -102,6 +106,20 @@ var _ = Describe("JSONCaller", func() {
 			Expect(action.SliceArgs).To(Equal([]string{"a", "b", "c"}))
 		})
 
+		It("returns the original typed error of the action", func() {
+			expectedErr := bosherr.WrapError(bslcapi.NewVMNotFoundError("123"), "fake-wrapper")
+
+			action := &actionWithGoodRunMethod{Err: expectedErr}
+			args := []interface{}{"setup", 123, map[string]interface{}{}, []interface{}{}}
+
+			_, err := caller.Call(action, args)
+			Expect(err).To(Equal(expectedErr))
+
+			cloudErr, found := bslcapi.FindCloudError(err)
+			Expect(found).To(BeTrue())
+			Expect(cloudErr.Type()).To(Equal("Bosh::Clouds::VMNotFound"))
+		})
+
 		It("returns error if actions not enough arguments", func() {
 			expectedValue := valueType{ID: 13, Success: true}
 
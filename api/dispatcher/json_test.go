@@ -1,6 +1,7 @@
 package dispatcher_test
 
 import (
+	"context"
 	"errors"
 
 	. "github.com/onsi/ginkgo"
@@ -8,7 +9,9 @@ import (
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/api/dispatcher"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
 	fakeaction "github.com/cloudfoundry/bosh-softlayer-cpi/action/fakes"
@@ -43,11 +46,35 @@ var _ = Describe("JSON", func() {
 			})
 
 			It("runs action with provided arguments", func() {
-				dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+				dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
 				Expect(caller.CallAction).To(Equal(action))
 				Expect(caller.CallArgs).To(Equal([]interface{}{"fake-arg"}))
 			})
 
+			It("passes API version 1 to action when api_version key is missing", func() {
+				dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+				Expect(action.WithContextContext.ApiVersion).To(Equal(1))
+			})
+
+			It("passes provided API version and context to action", func() {
+				dispatcher.Dispatch(context.Background(), []byte(`{
+					"method":"fake-action",
+					"arguments":["fake-arg"],
+					"api_version":2,
+					"context":{"director_uuid":"fake-director-uuid"}
+				}`))
+				Expect(action.WithContextContext.ApiVersion).To(Equal(2))
+				Expect(action.WithContextContext.DirectorUUID).To(Equal("fake-director-uuid"))
+			})
+
+			It("passes the request context to action", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				dispatcher.Dispatch(ctx, []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+				Expect(action.WithContextContext.Context).To(Equal(ctx))
+			})
+
 			Context("when running action succeeds", func() {
 				Context("when result can be serialized", func() {
 					BeforeEach(func() {
@@ -55,7 +82,7 @@ var _ = Describe("JSON", func() {
 					})
 
 					It("returns serialized result without including error", func() {
-						response := dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
 						Expect(response).To(MatchJSON(`{
 							"result": "fake-result",
 							"error": null,
@@ -70,7 +97,7 @@ var _ = Describe("JSON", func() {
 					})
 
 					It("returns Bosh::Clouds::CpiError", func() {
-						response := dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
 						Expect(response).To(MatchJSON(`{
 							"result": null,
               "error": {
@@ -84,10 +111,10 @@ var _ = Describe("JSON", func() {
 				})
 				Context("verify if localDiskFlagNotSet is set properly", func() {
 					It("localDiskFlagNotSet is set to true if LocalDiskFlag is not set, and false if LocalDiskFlag is set to false", func() {
-						_ = dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						_ = dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
 						Expect(bslcommon.LocalDiskFlagNotSet).To(Equal(true))
 
-						_ = dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":["fake-arg", "localDiskFlag:false"]}`))
+						_ = dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg", "localDiskFlag:false"]}`))
 						Expect(bslcommon.LocalDiskFlagNotSet).To(Equal(false))
 					})
 				})
@@ -100,7 +127,7 @@ var _ = Describe("JSON", func() {
 					})
 
 					It("returns error without result", func() {
-						response := dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
 						Expect(response).To(MatchJSON(`{
 							"result": null,
               "error": {
@@ -119,7 +146,7 @@ var _ = Describe("JSON", func() {
 					})
 
 					It("returns error with ok_to_retry set to true", func() {
-						response := dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
 						Expect(response).To(MatchJSON(`{
 							"result": null,
               "error": {
@@ -138,7 +165,7 @@ var _ = Describe("JSON", func() {
 					})
 
 					It("returns error with ok_to_retry set to false", func() {
-						response := dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
 						Expect(response).To(MatchJSON(`{
 							"result": null,
               "error": {
@@ -151,13 +178,108 @@ var _ = Describe("JSON", func() {
 					})
 				})
 
+				Context("when action error wraps a typed CPI error", func() {
+					BeforeEach(func() {
+						caller.CallErr = bosherr.WrapError(bslcapi.NewDiskNotAttachedError("fake-vm-id", "fake-disk-id"), "Detaching disk")
+					})
+
+					It("returns error with the type and retryability of the wrapped error", func() {
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						Expect(response).To(MatchJSON(`{
+							"result": null,
+              "error": {
+                "type":"Bosh::Clouds::DiskNotAttached",
+                "message":"Detaching disk: Disk 'fake-disk-id' not attached to VM 'fake-vm-id'",
+                "ok_to_retry": false
+              },
+              "log": ""
+            }`))
+					})
+				})
+
+				Context("when action error wraps a retryable error", func() {
+					BeforeEach(func() {
+						caller.CallErr = bosherr.WrapError(fakeapi.NewFakeRetryableError("fake-error", true), "fake-wrapper")
+					})
+
+					It("returns error with ok_to_retry set to true", func() {
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						Expect(response).To(MatchJSON(`{
+							"result": null,
+              "error": {
+                "type":"Bosh::Clouds::CloudError",
+                "message":"fake-wrapper: fake-error",
+                "ok_to_retry": true
+              },
+              "log": ""
+            }`))
+					})
+				})
+
+				Context("when action error is a transient SoftLayer API failure", func() {
+					BeforeEach(func() {
+						caller.CallErr = bosherr.WrapError(bslcommon.NewSoftLayerHttpError(503, "softlayer-go: could not SoftLayer_Virtual_Guest#getObject"), "fake-wrapper")
+					})
+
+					It("returns error with ok_to_retry set to true", func() {
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						Expect(response).To(MatchJSON(`{
+							"result": null,
+              "error": {
+                "type":"Bosh::Clouds::CloudError",
+                "message":"fake-wrapper: softlayer-go: could not SoftLayer_Virtual_Guest#getObject, HTTP error code: '503'",
+                "ok_to_retry": true
+              },
+              "log": ""
+            }`))
+					})
+				})
+
+				Context("when action error is a SoftLayer authentication failure", func() {
+					BeforeEach(func() {
+						caller.CallErr = bslcommon.NewSoftLayerHttpError(401, "softlayer-go: could not SoftLayer_Virtual_Guest#getObject")
+					})
+
+					It("returns Bosh::Clouds::CpiError with ok_to_retry set to false", func() {
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						Expect(response).To(MatchJSON(`{
+							"result": null,
+              "error": {
+                "type":"Bosh::Clouds::CpiError",
+                "message":"softlayer-go: could not SoftLayer_Virtual_Guest#getObject, HTTP error code: '401'",
+                "ok_to_retry": false
+              },
+              "log": ""
+            }`))
+					})
+				})
+
+				Context("when action was cancelled while waiting on SoftLayer", func() {
+					BeforeEach(func() {
+						caller.CallErr = bosherr.WrapError(bslcapi.NewCancelledError(context.Canceled), "fake-wrapper")
+					})
+
+					It("returns Bosh::Clouds::CloudError saying the call was cancelled with ok_to_retry set to false", func() {
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						Expect(response).To(MatchJSON(`{
+							"result": null,
+              "error": {
+                "type":"Bosh::Clouds::CloudError",
+                "message":"fake-wrapper: CPI call cancelled: context canceled",
+                "ok_to_retry": false
+              },
+              "log": ""
+            }`))
+					})
+				})
+
 				Context("when action error is neither CloudError or RetryableError", func() {
 					BeforeEach(func() {
 						caller.CallErr = errors.New("fake-run-err")
 					})
 
 					It("returns error without result", func() {
-						response := dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
+						response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":["fake-arg"]}`))
 						Expect(response).To(MatchJSON(`{
 							"result": null,
               "error": {
@@ -174,7 +296,7 @@ var _ = Describe("JSON", func() {
 
 		Context("when method is unknown", func() {
 			It("responds with Bosh::Clouds::NotImplemented error", func() {
-				response := dispatcher.Dispatch([]byte(`{"method":"fake-action","arguments":[]}`))
+				response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action","arguments":[]}`))
 				Expect(response).To(MatchJSON(`{
 					"result": null,
           "error": {
@@ -189,7 +311,7 @@ var _ = Describe("JSON", func() {
 
 		Context("when method key is missing", func() {
 			It("responds with Bosh::Clouds::CpiError error", func() {
-				response := dispatcher.Dispatch([]byte(`{}`))
+				response := dispatcher.Dispatch(context.Background(), []byte(`{}`))
 				Expect(response).To(MatchJSON(`{
 					"result": null,
           "error": {
@@ -204,7 +326,7 @@ var _ = Describe("JSON", func() {
 
 		Context("when arguments key is missing", func() {
 			It("responds with Bosh::Clouds::CpiError error", func() {
-				response := dispatcher.Dispatch([]byte(`{"method":"fake-action"}`))
+				response := dispatcher.Dispatch(context.Background(), []byte(`{"method":"fake-action"}`))
 				Expect(response).To(MatchJSON(`{
 					"result": null,
           "error": {
@@ -219,7 +341,7 @@ var _ = Describe("JSON", func() {
 
 		Context("when payload cannot be deserialized", func() {
 			It("responds with Bosh::Clouds::CpiError error", func() {
-				response := dispatcher.Dispatch([]byte(`{-}`))
+				response := dispatcher.Dispatch(context.Background(), []byte(`{-}`))
 				Expect(response).To(MatchJSON(`{
 					"result": null,
           "error": {
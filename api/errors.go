@@ -2,6 +2,8 @@ package api
 
 import (
 	"fmt"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
 
 type CloudError interface {
@@ -16,6 +18,52 @@ type RetryableError interface {
 	CanRetry() bool
 }
 
+// FindCloudError returns the outermost CloudError in the wrapping chain of err
+func FindCloudError(err error) (CloudError, bool) {
+	found := findInChain(err, func(e error) bool {
+		_, ok := e.(CloudError)
+		return ok
+	})
+	if found == nil {
+		return nil, false
+	}
+
+	return found.(CloudError), true
+}
+
+// FindRetryableError returns the outermost RetryableError in the wrapping chain of err
+func FindRetryableError(err error) (RetryableError, bool) {
+	found := findInChain(err, func(e error) bool {
+		_, ok := e.(RetryableError)
+		return ok
+	})
+	if found == nil {
+		return nil, false
+	}
+
+	return found.(RetryableError), true
+}
+
+func findInChain(err error, matches func(error) bool) error {
+	if err == nil {
+		return nil
+	}
+
+	if matches(err) {
+		return err
+	}
+
+	if complexErr, ok := err.(bosherr.ComplexError); ok {
+		if found := findInChain(complexErr.Err, matches); found != nil {
+			return found
+		}
+
+		return findInChain(complexErr.Cause, matches)
+	}
+
+	return nil
+}
+
 // -
 type NotSupportedError struct{}
 
@@ -78,3 +126,18 @@ func NewDiskNotFoundError(diskID string) diskNotFoundError {
 func (e diskNotFoundError) Type() string   { return "Bosh::Clouds::DiskNotFound" }
 func (e diskNotFoundError) Error() string  { return fmt.Sprintf("Disk '%s' not found", e.diskID) }
 func (e diskNotFoundError) CanRetry() bool { return false }
+
+// -
+// cancelledError is reported as a CloudError since the director has no error
+// class for cancelled calls, the message tells it apart
+type cancelledError struct {
+	cause error
+}
+
+func NewCancelledError(cause error) cancelledError {
+	return cancelledError{cause: cause}
+}
+
+func (e cancelledError) Type() string   { return "Bosh::Clouds::CloudError" }
+func (e cancelledError) Error() string  { return fmt.Sprintf("CPI call cancelled: %s", e.cause) }
+func (e cancelledError) CanRetry() bool { return false }
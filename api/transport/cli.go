@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"io"
 	"io/ioutil"
 
@@ -33,14 +34,14 @@ func NewCLI(
 	}
 }
 
-func (t CLI) ServeOnce() error {
+func (t CLI) ServeOnce(ctx context.Context) error {
 	reqBytes, err := ioutil.ReadAll(t.in)
 	if err != nil {
 		t.logger.Error(cliLogTag, "Failed reading from IN: %s", err)
 		return bosherr.WrapError(err, "Reading from IN")
 	}
 
-	respBytes := t.dispatcher.Dispatch(reqBytes)
+	respBytes := t.dispatcher.Dispatch(ctx, reqBytes)
 
 	_, err = t.out.Write(respBytes)
 	if err != nil {
@@ -1,6 +1,7 @@
 package transport_test
 
 import (
+	"context"
 	"errors"
 	"io"
 
@@ -62,7 +63,7 @@ var _ = Describe("CLI", func() {
 
 			dispatcher.DispatchRespBytes = []byte("fake-bytes-out")
 
-			err := cli.ServeOnce()
+			err := cli.ServeOnce(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(dispatcher.DispatchReqBytes).To(Equal([]byte("fake-bytes-in")))
@@ -73,7 +74,7 @@ var _ = Describe("CLI", func() {
 		It("returns error if reading request from in fails", func() {
 			in.ReadErr = errors.New("fake-read-err")
 
-			err := cli.ServeOnce()
+			err := cli.ServeOnce(context.Background())
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-read-err"))
 		})
@@ -81,7 +82,7 @@ var _ = Describe("CLI", func() {
 		It("returns error if writing response to out fails", func() {
 			out.WriteErr = errors.New("fake-write-err")
 
-			err := cli.ServeOnce()
+			err := cli.ServeOnce(context.Background())
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-write-err"))
 		})
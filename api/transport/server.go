@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	bslcdisp "github.com/cloudfoundry/bosh-softlayer-cpi/api/dispatcher"
+)
+
+const (
+	serverLogTag = "Server"
+
+	unixAddressPrefix = "unix:"
+)
+
+// Server keeps the CPI running and dispatches every request POSTed over HTTP,
+// so that many CPI calls can share one warm process
+type Server struct {
+	listener   net.Listener
+	dispatcher bslcdisp.Dispatcher
+	logger     boshlog.Logger
+
+	// Actions share SoftLayer clients and package level state,
+	// so requests are dispatched one at a time
+	dispatchLock *sync.Mutex
+}
+
+func NewServer(
+	listener net.Listener,
+	dispatcher bslcdisp.Dispatcher,
+	logger boshlog.Logger,
+) Server {
+	return Server{
+		listener:     listener,
+		dispatcher:   dispatcher,
+		logger:       logger,
+		dispatchLock: &sync.Mutex{},
+	}
+}
+
+// Listen opens a unix socket for addresses like 'unix:/path/to/cpi.sock',
+// otherwise a TCP listener which must be bound to a loopback address
+func Listen(address string) (net.Listener, error) {
+	if strings.HasPrefix(address, unixAddressPrefix) {
+		path := strings.TrimPrefix(strings.TrimPrefix(address, unixAddressPrefix), "//")
+
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, bosherr.WrapErrorf(err, "Removing stale socket '%s'", path)
+		}
+
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Listening on unix socket '%s'", path)
+		}
+
+		return listener, nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing address '%s'", address)
+	}
+
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return nil, bosherr.Errorf("Address '%s' must be a loopback address", address)
+		}
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Listening on '%s'", address)
+	}
+
+	return listener, nil
+}
+
+// Serve dispatches requests until ctx is cancelled. Requests in flight see
+// the cancellation through their own context and still get a response.
+func (t Server) Serve(ctx context.Context) error {
+	t.logger.Info(serverLogTag, "Serving CPI requests on %s", t.listener.Addr())
+
+	httpServer := &http.Server{
+		Handler:     t,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		t.logger.Info(serverLogTag, "Stopping to serve CPI requests: %s", ctx.Err())
+
+		err := httpServer.Shutdown(context.Background())
+		if err != nil {
+			t.logger.Error(serverLogTag, "Failed shutting down: %s", err)
+		}
+	}()
+
+	err := httpServer.Serve(t.listener)
+	if err != nil && err != http.ErrServerClosed {
+		return bosherr.WrapError(err, "Serving CPI requests")
+	}
+
+	return nil
+}
+
+func (t Server) Close() error {
+	return t.listener.Close()
+}
+
+func (t Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reqBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.logger.Error(serverLogTag, "Failed reading request body: %s", err)
+		http.Error(w, "Reading request body", http.StatusBadRequest)
+		return
+	}
+
+	t.dispatchLock.Lock()
+	respBytes := t.dispatcher.Dispatch(r.Context(), reqBytes)
+	t.dispatchLock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err = w.Write(respBytes)
+	if err != nil {
+		t.logger.Error(serverLogTag, "Failed writing response: %s", err)
+	}
+}
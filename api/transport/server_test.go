@@ -0,0 +1,143 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/api/transport"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	fakedisp "github.com/cloudfoundry/bosh-softlayer-cpi/api/dispatcher/fakes"
+)
+
+type fakeContextKey struct{}
+
+var _ = Describe("Server", func() {
+	var (
+		dispatcher *fakedisp.FakeDispatcher
+		logger     boshlog.Logger
+	)
+
+	BeforeEach(func() {
+		dispatcher = &fakedisp.FakeDispatcher{}
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+	})
+
+	Describe("ServeHTTP", func() {
+		var (
+			server   Server
+			recorder *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			server = NewServer(nil, dispatcher, logger)
+			recorder = httptest.NewRecorder()
+		})
+
+		It("dispatches the request body and writes the response", func() {
+			dispatcher.DispatchRespBytes = []byte("fake-bytes-out")
+
+			req, err := http.NewRequest("POST", "/", bytes.NewBufferString("fake-bytes-in"))
+			Expect(err).ToNot(HaveOccurred())
+
+			server.ServeHTTP(recorder, req)
+
+			Expect(dispatcher.DispatchReqBytes).To(Equal([]byte("fake-bytes-in")))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("fake-bytes-out"))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		})
+
+		It("rejects methods other than POST", func() {
+			req, err := http.NewRequest("GET", "/", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			server.ServeHTTP(recorder, req)
+
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(dispatcher.DispatchReqBytes).To(BeNil())
+		})
+	})
+
+	Describe("Listen", func() {
+		It("listens on a loopback TCP address", func() {
+			listener, err := Listen("127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			defer listener.Close()
+
+			Expect(listener.Addr().Network()).To(Equal("tcp"))
+		})
+
+		It("listens on a unix socket", func() {
+			tmpDir, err := ioutil.TempDir("", "cpi-server")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+
+			listener, err := Listen("unix:" + filepath.Join(tmpDir, "cpi.sock"))
+			Expect(err).ToNot(HaveOccurred())
+			defer listener.Close()
+
+			Expect(listener.Addr().Network()).To(Equal("unix"))
+		})
+
+		It("returns error for non loopback addresses", func() {
+			_, err := Listen("0.0.0.0:0")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must be a loopback address"))
+		})
+	})
+
+	Describe("Serve", func() {
+		It("serves requests until closed", func() {
+			listener, err := Listen("127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+
+			server := NewServer(listener, dispatcher, logger)
+			dispatcher.DispatchRespBytes = []byte("fake-bytes-out")
+
+			serveErr := make(chan error, 1)
+			go func() { serveErr <- server.Serve(context.Background()) }()
+
+			resp, err := http.Post("http://"+listener.Addr().String(), "application/json", bytes.NewBufferString("fake-bytes-in"))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal("fake-bytes-out"))
+
+			Expect(server.Close()).To(Succeed())
+			Eventually(serveErr).Should(Receive())
+		})
+
+		It("stops serving when the context is cancelled", func() {
+			listener, err := Listen("127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+
+			server := NewServer(listener, dispatcher, logger)
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), fakeContextKey{}, "fake-value"))
+
+			serveErr := make(chan error, 1)
+			go func() { serveErr <- server.Serve(ctx) }()
+
+			resp, err := http.Post("http://"+listener.Addr().String(), "application/json", bytes.NewBufferString("fake-bytes-in"))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+
+			Expect(dispatcher.DispatchContext.Value(fakeContextKey{})).To(Equal("fake-value"))
+
+			cancel()
+
+			Eventually(serveErr).Should(Receive(BeNil()))
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package delete_stemcell_test
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"os"
@@ -12,6 +13,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
 	testhelperscpi "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
 	"github.com/cloudfoundry/bosh-utils/logger"
@@ -97,8 +99,8 @@ var _ = Describe("BOSH Director Level Integration for delete_stemcell", func() {
 		})
 
 		AfterEach(func() {
-			stemcell := bslcstem.NewSoftLayerStemcell(virtual_disk_image_id, "", client, logger.NewLogger(logger.LevelInfo))
-			stemcell.Delete()
+			stemcell := bslcstem.NewSoftLayerStemcell(virtual_disk_image_id, "", client, bslcommon.DefaultWaitPolicies(), logger.NewLogger(logger.LevelInfo))
+			stemcell.Delete(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"os/signal"
+	"syscall"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
@@ -19,6 +22,7 @@ const mainLogTag = "main"
 var (
 	configPathOpt = flag.String("configPath", "", "Path to configuration file")
 	cpiVersion    = flag.Bool("version", false, "The version of CPI release")
+	serveOpt      = flag.String("serve", "", "Keep serving CPI requests over HTTP on a loopback address (127.0.0.1:8080) or unix socket (unix:/path/to/cpi.sock)")
 )
 
 func main() {
@@ -40,9 +44,31 @@ func main() {
 
 	dispatcher := buildDispatcher(config, logger, cmdRunner)
 
+	// Stop polling SoftLayer as soon as the director or an operator asks us to
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if len(*serveOpt) > 0 {
+		listener, err := bslctrans.Listen(*serveOpt)
+		if err != nil {
+			logger.Error(mainLogTag, "Listening %s", err)
+			os.Exit(1)
+		}
+
+		server := bslctrans.NewServer(listener, dispatcher, logger)
+
+		err = server.Serve(ctx)
+		if err != nil {
+			logger.Error(mainLogTag, "Serving %s", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	cli := bslctrans.NewCLI(os.Stdin, os.Stdout, dispatcher, logger)
 
-	err = cli.ServeOnce()
+	err = cli.ServeOnce(ctx)
 	if err != nil {
 		logger.Error(mainLogTag, "Serving once %s", err)
 		os.Exit(1)
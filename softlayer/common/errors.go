@@ -0,0 +1,121 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type SoftLayerErrorCategory string
+
+const (
+	SoftLayerNotFound     SoftLayerErrorCategory = "not-found"
+	SoftLayerUnauthorized SoftLayerErrorCategory = "unauthorized"
+	SoftLayerTransient    SoftLayerErrorCategory = "transient"
+	SoftLayerFatal        SoftLayerErrorCategory = "fatal"
+)
+
+// SoftLayerAPIError is a failed SoftLayer API call sorted into a category
+// the director can act on: transient failures are retryable, bad credentials are not
+type SoftLayerAPIError struct {
+	Category   SoftLayerErrorCategory
+	StatusCode int
+	Cause      error
+}
+
+func NewSoftLayerHttpError(statusCode int, msg string, args ...interface{}) error {
+	cause := bosherr.Errorf("%s, HTTP error code: '%d'", fmt.Sprintf(msg, args...), statusCode)
+
+	return SoftLayerAPIError{
+		Category:   categoryForStatusCode(statusCode),
+		StatusCode: statusCode,
+		Cause:      cause,
+	}
+}
+
+func (e SoftLayerAPIError) Error() string { return e.Cause.Error() }
+
+func (e SoftLayerAPIError) Type() string {
+	if e.Category == SoftLayerUnauthorized {
+		return "Bosh::Clouds::CpiError"
+	}
+
+	return "Bosh::Clouds::CloudError"
+}
+
+func (e SoftLayerAPIError) CanRetry() bool { return e.Category == SoftLayerTransient }
+
+// classifyTransportError marks a SoftLayer API call that timed out as transient
+// and returns any other transport error unchanged
+func classifyTransportError(err error) error {
+	if isTimeoutError(err) {
+		return SoftLayerAPIError{Category: SoftLayerTransient, Cause: err}
+	}
+
+	return err
+}
+
+// IsSoftLayerAPIError reports whether err is a failed SoftLayer API call of any category
+func IsSoftLayerAPIError(err error) bool {
+	_, ok := findSoftLayerAPIError(err)
+	return ok
+}
+
+func IsSoftLayerNotFoundError(err error) bool {
+	return hasSoftLayerErrorCategory(err, SoftLayerNotFound)
+}
+
+func IsSoftLayerTransientError(err error) bool {
+	return hasSoftLayerErrorCategory(err, SoftLayerTransient)
+}
+
+func hasSoftLayerErrorCategory(err error, category SoftLayerErrorCategory) bool {
+	apiErr, ok := findSoftLayerAPIError(err)
+	return ok && apiErr.Category == category
+}
+
+func findSoftLayerAPIError(err error) (SoftLayerAPIError, bool) {
+	switch typedErr := err.(type) {
+	case SoftLayerAPIError:
+		return typedErr, true
+	case bosherr.ComplexError:
+		if apiErr, ok := findSoftLayerAPIError(typedErr.Err); ok {
+			return apiErr, true
+		}
+
+		return findSoftLayerAPIError(typedErr.Cause)
+	}
+
+	return SoftLayerAPIError{}, false
+}
+
+func categoryForStatusCode(statusCode int) SoftLayerErrorCategory {
+	switch {
+	case statusCode == 404:
+		return SoftLayerNotFound
+	case statusCode == 401 || statusCode == 403:
+		return SoftLayerUnauthorized
+	case statusCode == 429 || statusCode >= 500:
+		return SoftLayerTransient
+	default:
+		return SoftLayerFatal
+	}
+}
+
+func isTimeoutError(err error) bool {
+	switch typedErr := err.(type) {
+	case net.Error:
+		if typedErr.Timeout() {
+			return true
+		}
+	case bosherr.ComplexError:
+		if isTimeoutError(typedErr.Err) || isTimeoutError(typedErr.Cause) {
+			return true
+		}
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "Client.Timeout exceeded") || strings.Contains(msg, "i/o timeout")
+}
@@ -0,0 +1,37 @@
+package common_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+var _ = Describe("SoftLayer API errors", func() {
+	Describe("NewSoftLayerHttpError", func() {
+		It("formats the message like softlayer-go and classifies the status code", func() {
+			err := NewSoftLayerHttpError(503, "Getting subnets for account %d", 1234)
+			Expect(err.Error()).To(Equal("Getting subnets for account 1234, HTTP error code: '503'"))
+			Expect(IsSoftLayerTransientError(err)).To(BeTrue())
+		})
+	})
+
+	Describe("predicates", func() {
+		It("finds the category through wrapped errors", func() {
+			err := bosherr.WrapError(NewSoftLayerHttpError(404, "fake-error"), "fake-wrapper")
+			Expect(IsSoftLayerNotFoundError(err)).To(BeTrue())
+			Expect(IsSoftLayerTransientError(err)).To(BeFalse())
+			Expect(IsSoftLayerAPIError(err)).To(BeTrue())
+		})
+
+		It("does not match unrelated errors", func() {
+			err := errors.New("fake-error")
+			Expect(IsSoftLayerNotFoundError(err)).To(BeFalse())
+			Expect(IsSoftLayerAPIError(err)).To(BeFalse())
+		})
+	})
+})
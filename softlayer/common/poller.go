@@ -0,0 +1,102 @@
+package common
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+)
+
+const (
+	pollerBackoffMultiplier = 2
+
+	// Each interval is shortened by up to this fraction so that parallel
+	// CPI calls started together do not hit the SoftLayer API in lockstep
+	pollerJitterFactor = 0.2
+)
+
+// Poller repeatedly checks a condition, starting at the policy's polling
+// interval and doubling it after every attempt up to the max polling interval
+type Poller struct {
+	policy WaitPolicy
+	clock  clock.Clock
+	jitter func(time.Duration) time.Duration
+}
+
+func NewPoller(policy WaitPolicy, clock clock.Clock) Poller {
+	return NewPollerWithJitter(policy, clock, RandomJitter)
+}
+
+func NewPollerWithJitter(policy WaitPolicy, clock clock.Clock, jitter func(time.Duration) time.Duration) Poller {
+	return Poller{
+		policy: policy,
+		clock:  clock,
+		jitter: jitter,
+	}
+}
+
+// RandomJitter shortens interval by a random amount of up to 20%
+func RandomJitter(interval time.Duration) time.Duration {
+	return interval - time.Duration(rand.Float64()*pollerJitterFactor*float64(interval))
+}
+
+// Poll calls condition until it reports done or fails. It returns false
+// without an error once the policy's timeout elapses, and a cancelled CPI
+// error as soon as ctx is done.
+func (p Poller) Poll(ctx context.Context, condition func() (bool, error)) (bool, error) {
+	start := p.clock.Now()
+	interval := p.policy.PollingInterval
+
+	for {
+		done, err := condition()
+		if err != nil || done {
+			return done, err
+		}
+
+		remaining := p.policy.Timeout - p.clock.Since(start)
+		if remaining <= 0 {
+			return false, nil
+		}
+
+		wait := p.jitter(interval)
+		if wait > remaining {
+			wait = remaining
+		}
+
+		err = p.sleep(ctx, wait)
+		if err != nil {
+			return false, err
+		}
+
+		interval = p.nextInterval(interval)
+	}
+}
+
+func (p Poller) nextInterval(interval time.Duration) time.Duration {
+	maxInterval := p.policy.MaxPollingInterval
+	if maxInterval < p.policy.PollingInterval {
+		maxInterval = p.policy.PollingInterval
+	}
+
+	interval *= pollerBackoffMultiplier
+	if interval > maxInterval {
+		return maxInterval
+	}
+
+	return interval
+}
+
+func (p Poller) sleep(ctx context.Context, wait time.Duration) error {
+	timer := p.clock.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return bslcapi.NewCancelledError(ctx.Err())
+	case <-timer.C():
+		return nil
+	}
+}
@@ -0,0 +1,183 @@
+package common_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+type pollResult struct {
+	done bool
+	err  error
+}
+
+var _ = Describe("Poller", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+
+		intervalsLock sync.Mutex
+		intervals     []time.Duration
+		recordJitter  func(time.Duration) time.Duration
+
+		attempts int
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		intervals = []time.Duration{}
+		recordJitter = func(interval time.Duration) time.Duration {
+			intervalsLock.Lock()
+			defer intervalsLock.Unlock()
+
+			intervals = append(intervals, interval)
+			return interval
+		}
+
+		attempts = 0
+	})
+
+	recordedIntervals := func() []time.Duration {
+		intervalsLock.Lock()
+		defer intervalsLock.Unlock()
+
+		return append([]time.Duration{}, intervals...)
+	}
+
+	doneAfter := func(attemptsNeeded int) func() (bool, error) {
+		return func() (bool, error) {
+			attempts++
+			return attempts >= attemptsNeeded, nil
+		}
+	}
+
+	poll := func(ctx context.Context, poller Poller, condition func() (bool, error)) chan pollResult {
+		results := make(chan pollResult, 1)
+
+		go func() {
+			done, err := poller.Poll(ctx, condition)
+			results <- pollResult{done: done, err: err}
+		}()
+
+		return results
+	}
+
+	Describe("Poll", func() {
+		It("returns as soon as the condition is done", func() {
+			poller := NewPollerWithJitter(NewWaitPolicy(1*time.Hour, 1*time.Second), fakeClock, recordJitter)
+
+			done, err := poller.Poll(context.Background(), doneAfter(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(done).To(BeTrue())
+
+			Expect(attempts).To(Equal(1))
+			Expect(recordedIntervals()).To(BeEmpty())
+		})
+
+		It("backs off exponentially up to the max polling interval", func() {
+			policy := NewWaitPolicy(1*time.Hour, 1*time.Second).WithMaxPollingInterval(4 * time.Second)
+			poller := NewPollerWithJitter(policy, fakeClock, recordJitter)
+
+			results := poll(context.Background(), poller, doneAfter(5))
+
+			for i := 0; i < 4; i++ {
+				fakeClock.WaitForWatcherAndIncrement(4 * time.Second)
+			}
+
+			Eventually(results).Should(Receive(Equal(pollResult{done: true})))
+			Expect(recordedIntervals()).To(Equal([]time.Duration{
+				1 * time.Second,
+				2 * time.Second,
+				4 * time.Second,
+				4 * time.Second,
+			}))
+		})
+
+		It("keeps a fixed interval when no max polling interval is set", func() {
+			poller := NewPollerWithJitter(NewWaitPolicy(1*time.Hour, 1*time.Second), fakeClock, recordJitter)
+
+			results := poll(context.Background(), poller, doneAfter(4))
+
+			for i := 0; i < 3; i++ {
+				fakeClock.WaitForWatcherAndIncrement(1 * time.Second)
+			}
+
+			Eventually(results).Should(Receive(Equal(pollResult{done: true})))
+			Expect(recordedIntervals()).To(Equal([]time.Duration{1 * time.Second, 1 * time.Second, 1 * time.Second}))
+		})
+
+		It("waits for the jittered interval", func() {
+			halve := func(interval time.Duration) time.Duration { return interval / 2 }
+			poller := NewPollerWithJitter(NewWaitPolicy(1*time.Hour, 2*time.Second), fakeClock, halve)
+
+			results := poll(context.Background(), poller, doneAfter(2))
+
+			fakeClock.WaitForWatcherAndIncrement(1 * time.Second)
+
+			Eventually(results).Should(Receive(Equal(pollResult{done: true})))
+		})
+
+		It("returns false once the timeout elapses", func() {
+			poller := NewPollerWithJitter(NewWaitPolicy(5*time.Second, 2*time.Second), fakeClock, recordJitter)
+
+			results := poll(context.Background(), poller, doneAfter(100))
+
+			for i := 0; i < 3; i++ {
+				fakeClock.WaitForWatcherAndIncrement(2 * time.Second)
+			}
+
+			Eventually(results).Should(Receive(Equal(pollResult{done: false})))
+			Expect(attempts).To(Equal(4))
+		})
+
+		It("returns the condition error without waiting", func() {
+			poller := NewPollerWithJitter(NewWaitPolicy(1*time.Hour, 1*time.Second), fakeClock, recordJitter)
+
+			done, err := poller.Poll(context.Background(), func() (bool, error) {
+				return false, errors.New("fake-condition-error")
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-condition-error"))
+			Expect(done).To(BeFalse())
+
+			Expect(recordedIntervals()).To(BeEmpty())
+		})
+
+		It("returns a cancelled CPI error when the context is cancelled while waiting", func() {
+			poller := NewPollerWithJitter(NewWaitPolicy(1*time.Hour, 1*time.Minute), fakeClock, recordJitter)
+			ctx, cancel := context.WithCancel(context.Background())
+
+			results := poll(ctx, poller, doneAfter(100))
+
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			cancel()
+
+			var result pollResult
+			Eventually(results).Should(Receive(&result))
+			Expect(result.done).To(BeFalse())
+
+			cloudErr, found := bslcapi.FindCloudError(result.err)
+			Expect(found).To(BeTrue())
+			Expect(cloudErr).To(Equal(bslcapi.NewCancelledError(context.Canceled)))
+		})
+	})
+
+	Describe("RandomJitter", func() {
+		It("shortens the interval by at most 20%", func() {
+			for i := 0; i < 100; i++ {
+				jittered := RandomJitter(10 * time.Second)
+				Expect(jittered).To(BeNumerically(">=", 8*time.Second))
+				Expect(jittered).To(BeNumerically("<=", 10*time.Second))
+			}
+		})
+	})
+})
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"bytes"
+
+	slclient "github.com/maximilien/softlayer-go/client"
+	slcommon "github.com/maximilien/softlayer-go/common"
+	sl "github.com/maximilien/softlayer-go/softlayer"
+)
+
+// NewSoftLayerClient creates a SoftLayer client whose services return failed
+// API calls as SoftLayerAPIErrors
+func NewSoftLayerClient(username, apiKey string) sl.Client {
+	client := slclient.NewSoftLayerClient(username, apiKey)
+	client.HttpClient = NewClassifyingHttpClient(client.HttpClient)
+
+	return client
+}
+
+// classifyingHttpClient sorts every failed request into a SoftLayerAPIError as
+// it leaves the HTTP client. softlayer-go services return request errors as
+// they are, so the category reaches the CPI through any service method.
+type classifyingHttpClient struct {
+	sl.HttpClient
+}
+
+func NewClassifyingHttpClient(httpClient sl.HttpClient) sl.HttpClient {
+	return classifyingHttpClient{HttpClient: httpClient}
+}
+
+func (c classifyingHttpClient) DoRawHttpRequest(path string, requestType string, requestBody *bytes.Buffer) ([]byte, int, error) {
+	response, statusCode, err := c.HttpClient.DoRawHttpRequest(path, requestType, requestBody)
+	return response, statusCode, classifyResponse(path, requestType, response, statusCode, err)
+}
+
+func (c classifyingHttpClient) DoRawHttpRequestWithObjectMask(path string, masks []string, requestType string, requestBody *bytes.Buffer) ([]byte, int, error) {
+	response, statusCode, err := c.HttpClient.DoRawHttpRequestWithObjectMask(path, masks, requestType, requestBody)
+	return response, statusCode, classifyResponse(path, requestType, response, statusCode, err)
+}
+
+func (c classifyingHttpClient) DoRawHttpRequestWithObjectFilter(path string, filters string, requestType string, requestBody *bytes.Buffer) ([]byte, int, error) {
+	response, statusCode, err := c.HttpClient.DoRawHttpRequestWithObjectFilter(path, filters, requestType, requestBody)
+	return response, statusCode, classifyResponse(path, requestType, response, statusCode, err)
+}
+
+func (c classifyingHttpClient) DoRawHttpRequestWithObjectFilterAndObjectMask(path string, masks []string, filters string, requestType string, requestBody *bytes.Buffer) ([]byte, int, error) {
+	response, statusCode, err := c.HttpClient.DoRawHttpRequestWithObjectFilterAndObjectMask(path, masks, filters, requestType, requestBody)
+	return response, statusCode, classifyResponse(path, requestType, response, statusCode, err)
+}
+
+func classifyResponse(path string, requestType string, response []byte, statusCode int, err error) error {
+	if err != nil {
+		return classifyTransportError(err)
+	}
+
+	if slcommon.IsHttpErrorCode(statusCode) {
+		return NewSoftLayerHttpError(statusCode, "SoftLayer API call %s %s failed: %s", requestType, path, bytes.TrimSpace(response))
+	}
+
+	return nil
+}
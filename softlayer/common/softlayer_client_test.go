@@ -0,0 +1,126 @@
+package common_test
+
+import (
+	"bytes"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	slclient "github.com/maximilien/softlayer-go/client"
+	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
+	sl "github.com/maximilien/softlayer-go/softlayer"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+type fakeTimeoutError struct{}
+
+func (e fakeTimeoutError) Error() string   { return "fake-timeout" }
+func (e fakeTimeoutError) Timeout() bool   { return true }
+func (e fakeTimeoutError) Temporary() bool { return true }
+
+var _ = Describe("SoftLayer client", func() {
+	var (
+		fakeHttpClient *fakeslclient.FakeHttpClient
+		httpClient     sl.HttpClient
+	)
+
+	BeforeEach(func() {
+		fakeHttpClient = fakeslclient.NewFakeHttpClient("fake-username", "fake-api-key")
+		fakeHttpClient.DoRawHttpRequestResponse = []byte(`{"error":"fake-error"}`)
+		httpClient = NewClassifyingHttpClient(fakeHttpClient)
+	})
+
+	expectCategory := func(statusCode int, category SoftLayerErrorCategory, errType string, canRetry bool) {
+		fakeHttpClient.DoRawHttpRequestInt = statusCode
+
+		_, returnedStatusCode, err := httpClient.DoRawHttpRequest("SoftLayer_Virtual_Guest/1234/getObject.json", "GET", new(bytes.Buffer))
+		Expect(returnedStatusCode).To(Equal(statusCode))
+
+		apiErr, ok := err.(SoftLayerAPIError)
+		Expect(ok).To(BeTrue())
+		Expect(apiErr.Category).To(Equal(category))
+		Expect(apiErr.StatusCode).To(Equal(statusCode))
+		Expect(apiErr.Type()).To(Equal(errType))
+		Expect(apiErr.CanRetry()).To(Equal(canRetry))
+	}
+
+	Describe("NewSoftLayerClient", func() {
+		It("classifies the errors of its HTTP client", func() {
+			client := NewSoftLayerClient("fake-username", "fake-api-key")
+			Expect(client.GetHttpClient()).To(BeAssignableToTypeOf(NewClassifyingHttpClient(nil)))
+		})
+	})
+
+	Describe("NewClassifyingHttpClient", func() {
+		It("returns successful responses unchanged", func() {
+			fakeHttpClient.DoRawHttpRequestInt = 200
+			fakeHttpClient.DoRawHttpRequestResponse = []byte(`{"id":1234}`)
+
+			response, statusCode, err := httpClient.DoRawHttpRequestWithObjectMask("SoftLayer_Virtual_Guest/1234/getObject.json", []string{"id"}, "GET", new(bytes.Buffer))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statusCode).To(Equal(200))
+			Expect(response).To(Equal([]byte(`{"id":1234}`)))
+		})
+
+		It("includes the request and the SoftLayer error in the message", func() {
+			fakeHttpClient.DoRawHttpRequestInt = 500
+
+			_, _, err := httpClient.DoRawHttpRequestWithObjectFilter("SoftLayer_Account/getVirtualGuests.json", "fake-filter", "GET", new(bytes.Buffer))
+			Expect(err).To(MatchError(`SoftLayer API call GET SoftLayer_Account/getVirtualGuests.json failed: {"error":"fake-error"}, HTTP error code: '500'`))
+		})
+
+		It("classifies 404 as SoftLayerNotFound", func() {
+			expectCategory(404, SoftLayerNotFound, "Bosh::Clouds::CloudError", false)
+		})
+
+		It("classifies 401 and 403 as SoftLayerUnauthorized", func() {
+			expectCategory(401, SoftLayerUnauthorized, "Bosh::Clouds::CpiError", false)
+			expectCategory(403, SoftLayerUnauthorized, "Bosh::Clouds::CpiError", false)
+		})
+
+		It("classifies 429 and 503 as SoftLayerTransient", func() {
+			expectCategory(429, SoftLayerTransient, "Bosh::Clouds::CloudError", true)
+			expectCategory(503, SoftLayerTransient, "Bosh::Clouds::CloudError", true)
+		})
+
+		It("classifies 400 as SoftLayerFatal", func() {
+			expectCategory(400, SoftLayerFatal, "Bosh::Clouds::CloudError", false)
+		})
+
+		It("classifies timeouts as SoftLayerTransient", func() {
+			fakeHttpClient.DoRawHttpRequestError = fakeTimeoutError{}
+
+			_, _, err := httpClient.DoRawHttpRequestWithObjectFilterAndObjectMask("SoftLayer_Account/getVirtualGuests.json", []string{"id"}, "fake-filter", "GET", new(bytes.Buffer))
+			Expect(IsSoftLayerTransientError(err)).To(BeTrue())
+		})
+
+		It("classifies client timeout messages as SoftLayerTransient", func() {
+			fakeHttpClient.DoRawHttpRequestError = errors.New("Get https://api.softlayer.com: net/http: request canceled (Client.Timeout exceeded while awaiting headers)")
+
+			_, _, err := httpClient.DoRawHttpRequest("SoftLayer_Account/getVirtualGuests.json", "GET", new(bytes.Buffer))
+			Expect(IsSoftLayerTransientError(err)).To(BeTrue())
+		})
+
+		It("returns other transport errors unchanged", func() {
+			transportErr := errors.New("fake-connection-refused")
+			fakeHttpClient.DoRawHttpRequestError = transportErr
+
+			_, _, err := httpClient.DoRawHttpRequest("SoftLayer_Account/getVirtualGuests.json", "GET", new(bytes.Buffer))
+			Expect(err).To(Equal(transportErr))
+		})
+
+		It("hands typed errors to the callers of softlayer-go services", func() {
+			client := slclient.NewSoftLayerClient("fake-username", "fake-api-key")
+			client.HttpClient = httpClient
+			fakeHttpClient.DoRawHttpRequestInt = 404
+
+			service, err := client.GetSoftLayer_Network_Storage_Service()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = service.GetNetworkStorage(1234)
+			Expect(IsSoftLayerNotFoundError(err)).To(BeTrue())
+		})
+	})
+})
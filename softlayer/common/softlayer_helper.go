@@ -1,12 +1,11 @@
 package common
 
 import (
+	"context"
 	"strings"
-	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
-	boshretry "github.com/cloudfoundry/bosh-utils/retrystrategy"
 	"github.com/pivotal-golang/clock"
 
 	datatypes "github.com/maximilien/softlayer-go/data_types"
@@ -14,22 +13,25 @@ import (
 )
 
 var (
-	TIMEOUT             time.Duration
-	POLLING_INTERVAL    time.Duration
 	LocalDiskFlagNotSet bool
 )
 
+// Only these metadata keys are recorded as tags on SoftLayer objects
+func IsTaggedMetadataKey(key string) bool {
+	return key == "compiling" || key == "job" || key == "index" || key == "deployment" || key == "deleted"
+}
+
 type SoftLayer_Hardware_Parameters struct {
 	Parameters []datatypes.SoftLayer_Hardware `json:"parameters"`
 }
 
-func AttachEphemeralDiskToVirtualGuest(softLayerClient sl.Client, virtualGuestId int, diskSize int, logger boshlog.Logger) error {
-	err := WaitForVirtualGuestLastCompleteTransaction(softLayerClient, virtualGuestId, "Service Setup")
+func AttachEphemeralDiskToVirtualGuest(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, diskSize int, waitPolicy WaitPolicy, logger boshlog.Logger) error {
+	err := WaitForVirtualGuestLastCompleteTransaction(ctx, softLayerClient, virtualGuestId, "Service Setup", waitPolicy)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Waiting for VirtualGuest `%d` has Service Setup transaction complete", virtualGuestId)
 	}
 
-	err = WaitForVirtualGuestToHaveNoRunningTransactions(softLayerClient, virtualGuestId)
+	err = WaitForVirtualGuestToHaveNoRunningTransactions(ctx, softLayerClient, virtualGuestId, waitPolicy)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Waiting for VirtualGuest `%d` to have no pending transactions", virtualGuestId)
 	}
@@ -41,32 +43,30 @@ func AttachEphemeralDiskToVirtualGuest(softLayerClient sl.Client, virtualGuestId
 
 	receipt, err := service.AttachEphemeralDisk(virtualGuestId, diskSize)
 	if err != nil {
-		if !strings.Contains(err.Error(), "HTTP error code") {
-			return err
-		}
+		return bosherr.WrapErrorf(err, "Ordering ephemeral disk for VirtualGuest `%d`", virtualGuestId)
 	}
 
 	if receipt.OrderId == 0 {
 		return nil
 	}
 
-	err = WaitForVirtualGuestToHaveRunningTransaction(softLayerClient, virtualGuestId, logger)
+	err = WaitForVirtualGuestToHaveRunningTransaction(ctx, softLayerClient, virtualGuestId, waitPolicy, logger)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Waiting for VirtualGuest `%d` to launch transaction", virtualGuestId)
 	}
 
-	err = WaitForVirtualGuestToHaveNoRunningTransaction(softLayerClient, virtualGuestId, logger)
+	err = WaitForVirtualGuestToHaveNoRunningTransaction(ctx, softLayerClient, virtualGuestId, waitPolicy, logger)
 
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Waiting for VirtualGuest `%d` no transcation in progress", virtualGuestId)
 	}
 
-	err = WaitForVirtualGuestUpgradeComplete(softLayerClient, virtualGuestId)
+	err = WaitForVirtualGuestUpgradeComplete(ctx, softLayerClient, virtualGuestId, waitPolicy)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Waiting for VirtualGuest `%d` upgrade complete", virtualGuestId)
 	}
 
-	err = WaitForVirtualGuest(softLayerClient, virtualGuestId, "RUNNING")
+	err = WaitForVirtualGuest(ctx, softLayerClient, virtualGuestId, "RUNNING", waitPolicy)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Waiting for VirtualGuest `%d`", virtualGuestId)
 	}
@@ -74,238 +74,267 @@ func AttachEphemeralDiskToVirtualGuest(softLayerClient sl.Client, virtualGuestId
 	return nil
 }
 
-func WaitForVirtualGuestToHaveNoRunningTransactions(softLayerClient sl.Client, virtualGuestId int) error {
+func WaitForVirtualGuestToHaveNoRunningTransactions(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, waitPolicy WaitPolicy) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	totalTime := time.Duration(0)
-	for totalTime < TIMEOUT {
+	done, err := NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
 		activeTransactions, err := virtualGuestService.GetActiveTransactions(virtualGuestId)
 		if err != nil {
-			return bosherr.WrapError(err, "Getting active transaction from SoftLayer client")
+			return false, bosherr.WrapError(err, "Getting active transaction from SoftLayer client")
 		}
 
 		if len(activeTransactions) == 0 {
-			return nil
+			return true, nil
 		}
 
-		totalTime += POLLING_INTERVAL
-		time.Sleep(POLLING_INTERVAL)
+		return false, nil
+	})
+	if err != nil {
+		return err
 	}
 
-	return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have no active transactions", virtualGuestId)
+	if !done {
+		return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have no active transactions", virtualGuestId)
+	}
+
+	return nil
 }
 
-func WaitForVirtualGuestToHaveRunningTransaction(softLayerClient sl.Client, virtualGuestId int, logger boshlog.Logger) error {
+func WaitForVirtualGuestToHaveRunningTransaction(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, waitPolicy WaitPolicy, logger boshlog.Logger) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	totalTime := time.Duration(0)
-	for totalTime < TIMEOUT {
+	done, err := NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
 		activeTransactions, err := virtualGuestService.GetActiveTransactions(virtualGuestId)
 		if err != nil {
-			return bosherr.WrapErrorf(err, "Getting active transaction against vitrual guest %d", virtualGuestId)
+			return false, bosherr.WrapErrorf(err, "Getting active transaction against vitrual guest %d", virtualGuestId)
 		}
 
 		if len(activeTransactions) > 0 {
-			return nil
+			return true, nil
 		}
 
-		totalTime += POLLING_INTERVAL
-		time.Sleep(POLLING_INTERVAL)
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !done {
+		return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have no active transactions", virtualGuestId)
 	}
 
-	return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have no active transactions", virtualGuestId)
+	return nil
 }
 
-func WaitForVirtualGuestToHaveNoRunningTransaction(softLayerClient sl.Client, virtualGuestId int, logger boshlog.Logger) error {
+func WaitForVirtualGuestToHaveNoRunningTransaction(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, waitPolicy WaitPolicy, logger boshlog.Logger) error {
 
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	totalTime := time.Duration(0)
-	for totalTime < TIMEOUT {
+	done, err := NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
 		activeTransactions, err := virtualGuestService.GetActiveTransactions(virtualGuestId)
 		if err != nil {
-			return bosherr.WrapErrorf(err, "Getting active transaction against vitrual guest %d", virtualGuestId)
+			return false, bosherr.WrapErrorf(err, "Getting active transaction against vitrual guest %d", virtualGuestId)
 		}
 
 		if len(activeTransactions) == 0 {
-			return nil
+			return true, nil
 		}
 
-		totalTime += POLLING_INTERVAL
-		time.Sleep(POLLING_INTERVAL)
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !done {
+		return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have no active transactions", virtualGuestId)
 	}
 
-	return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have no active transactions", virtualGuestId)
+	return nil
 
 }
 
-func WaitForVirtualGuest(softLayerClient sl.Client, virtualGuestId int, targetState string) error {
+func WaitForVirtualGuest(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, targetState string, waitPolicy WaitPolicy) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	totalTime := time.Duration(0)
-	for totalTime < TIMEOUT {
+	done, err := NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
 		vgPowerState, err := virtualGuestService.GetPowerState(virtualGuestId)
 		if err != nil {
-			return bosherr.WrapErrorf(err, "Getting Power State for virtual guest with ID '%d'", virtualGuestId)
+			return false, bosherr.WrapErrorf(err, "Getting Power State for virtual guest with ID '%d'", virtualGuestId)
 		}
 
 		if strings.Contains(vgPowerState.KeyName, targetState) {
-			return nil
+			return true, nil
 		}
 
-		totalTime += POLLING_INTERVAL
-		time.Sleep(POLLING_INTERVAL)
+		return false, nil
+	})
+	if err != nil {
+		return err
 	}
 
-	return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have be in state '%s'", virtualGuestId, targetState)
+	if !done {
+		return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have be in state '%s'", virtualGuestId, targetState)
+	}
+
+	return nil
 }
 
-func WaitForVirtualGuestLastCompleteTransaction(softLayerClient sl.Client, virtualGuestId int, targetTransaction string) error {
+func WaitForVirtualGuestLastCompleteTransaction(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, targetTransaction string, waitPolicy WaitPolicy) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	totalTime := time.Duration(0)
-	for totalTime < TIMEOUT {
+	done, err := NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
 		lastTransaction, err := virtualGuestService.GetLastTransaction(virtualGuestId)
 		if err != nil {
-			return bosherr.WrapErrorf(err, "Getting Last Complete Transaction for virtual guest with ID '%d'", virtualGuestId)
+			return false, bosherr.WrapErrorf(err, "Getting Last Complete Transaction for virtual guest with ID '%d'", virtualGuestId)
 		}
 
 		if strings.Contains(lastTransaction.TransactionGroup.Name, targetTransaction) && strings.Contains(lastTransaction.TransactionStatus.FriendlyName, "Complete") {
-			return nil
+			return true, nil
 		}
 
-		totalTime += POLLING_INTERVAL
-		time.Sleep(POLLING_INTERVAL)
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !done {
+		return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have last transaction '%s'", virtualGuestId, targetTransaction)
 	}
 
-	return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have last transaction '%s'", virtualGuestId, targetTransaction)
+	return nil
 }
 
-func WaitForVirtualGuestIsNotPingable(softLayerClient sl.Client, virtualGuestId int, logger boshlog.Logger) error {
+func WaitForVirtualGuestIsNotPingable(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, waitPolicy WaitPolicy, logger boshlog.Logger) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	checkPingableRetryable := boshretry.NewRetryable(
-		func() (bool, error) {
-			state, err := virtualGuestService.IsPingable(virtualGuestId)
-			if err != nil {
-				return false, bosherr.WrapErrorf(err, "Checking pingable against vitrual guest %d", virtualGuestId)
-			} else {
-				if state {
-					return true, bosherr.Errorf("vitrual guest %d is pingable", virtualGuestId)
-				} else {
-					return false, nil
-				}
-			}
-		})
-
-	timeService := clock.NewClock()
-	timeoutRetryStrategy := boshretry.NewTimeoutRetryStrategy(TIMEOUT, POLLING_INTERVAL, checkPingableRetryable, timeService, logger)
-	err = timeoutRetryStrategy.Try()
+	done, err := NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
+		state, err := virtualGuestService.IsPingable(virtualGuestId)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Checking pingable against vitrual guest %d", virtualGuestId)
+		}
+
+		if !state {
+			return true, nil
+		}
+
+		return false, nil
+	})
 	if err != nil {
+		return err
+	}
+
+	if !done {
 		return bosherr.Errorf("Waiting for virtual guest with ID '%d' is not pingable", virtualGuestId)
 	}
 
 	return nil
 }
 
-func WaitForVirtualGuestIsPingable(softLayerClient sl.Client, virtualGuestId int, logger boshlog.Logger) error {
+func WaitForVirtualGuestIsPingable(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, waitPolicy WaitPolicy, logger boshlog.Logger) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	checkPingableRetryable := boshretry.NewRetryable(
-		func() (bool, error) {
-			state, err := virtualGuestService.IsPingable(virtualGuestId)
-			if err != nil {
-				return false, bosherr.WrapErrorf(err, "Checking pingable against vitrual guest %d", virtualGuestId)
-			} else {
-				if state {
-					return false, nil
-				} else {
-					return true, bosherr.Errorf("vitrual guest %d is not pingable", virtualGuestId)
-				}
-			}
-		})
-
-	timeService := clock.NewClock()
-	timeoutRetryStrategy := boshretry.NewTimeoutRetryStrategy(TIMEOUT, POLLING_INTERVAL, checkPingableRetryable, timeService, logger)
-	err = timeoutRetryStrategy.Try()
+	done, err := NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
+		state, err := virtualGuestService.IsPingable(virtualGuestId)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Checking pingable against vitrual guest %d", virtualGuestId)
+		}
+
+		if state {
+			return true, nil
+		}
+
+		return false, nil
+	})
 	if err != nil {
+		return err
+	}
+
+	if !done {
 		return bosherr.Errorf("Waiting for virtual guest with ID '%d' is not pingable", virtualGuestId)
 	}
+
 	return nil
 }
 
-func WaitForVirtualGuestUpgradeComplete(softLayerClient sl.Client, virtualGuestId int) error {
+func WaitForVirtualGuestUpgradeComplete(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, waitPolicy WaitPolicy) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	totalTime := time.Duration(0)
-	for totalTime < TIMEOUT {
+	done, err := NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
 		lastTransaction, err := virtualGuestService.GetLastTransaction(virtualGuestId)
 		if err != nil {
-			return bosherr.WrapErrorf(err, "Getting Last Complete Transaction for virtual guest with ID '%d'", virtualGuestId)
+			return false, bosherr.WrapErrorf(err, "Getting Last Complete Transaction for virtual guest with ID '%d'", virtualGuestId)
 		}
 
 		if strings.Contains(lastTransaction.TransactionGroup.Name, "Cloud Migrate") && strings.Contains(lastTransaction.TransactionStatus.FriendlyName, "Complete") {
-			return nil
+			return true, nil
 		}
 
 		if strings.Contains(lastTransaction.TransactionGroup.Name, "Cloud Instance Upgrade") && strings.Contains(lastTransaction.TransactionStatus.FriendlyName, "Complete") {
-			return nil
+			return true, nil
 		}
 
-		totalTime += POLLING_INTERVAL
-		time.Sleep(POLLING_INTERVAL)
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !done {
+		return bosherr.Errorf("Waiting for virtual guest with ID '%d' to update complete", virtualGuestId)
 	}
 
-	return bosherr.Errorf("Waiting for virtual guest with ID '%d' to update complete", virtualGuestId)
+	return nil
 }
 
-func WaitForVirtualGuestToTargetState(softLayerClient sl.Client, virtualGuestId int, targetState string, logger boshlog.Logger) error {
+func WaitForVirtualGuestToTargetState(ctx context.Context, softLayerClient sl.Client, virtualGuestId int, targetState string, waitPolicy WaitPolicy, logger boshlog.Logger) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	getTargetStateRetryable := boshretry.NewRetryable(
-		func() (bool, error) {
-			vgPowerState, err := virtualGuestService.GetPowerState(virtualGuestId)
-			if err != nil {
-				return false, bosherr.WrapErrorf(err, "Getting PowerState from vitrual guest %d", virtualGuestId)
-			} else {
-				if strings.Contains(vgPowerState.KeyName, targetState) {
-					return false, nil
-				}
-				return true, bosherr.Errorf("The PowerState of vitrual guest %d is not targetState %s", virtualGuestId, targetState)
-			}
-		})
-
-	timeService := clock.NewClock()
-	timeoutRetryStrategy := boshretry.NewTimeoutRetryStrategy(TIMEOUT, POLLING_INTERVAL, getTargetStateRetryable, timeService, logger)
-	err = timeoutRetryStrategy.Try()
+	done, err := NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
+		vgPowerState, err := virtualGuestService.GetPowerState(virtualGuestId)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Getting PowerState from vitrual guest %d", virtualGuestId)
+		}
+
+		if strings.Contains(vgPowerState.KeyName, targetState) {
+			return true, nil
+		}
+
+		return false, nil
+	})
 	if err != nil {
+		return err
+	}
+
+	if !done {
 		return bosherr.Errorf("Waiting for virtual guest with ID '%d' to have be in state '%s'", virtualGuestId, targetState)
 	}
 
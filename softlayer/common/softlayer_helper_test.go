@@ -0,0 +1,87 @@
+package common_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	slclient "github.com/maximilien/softlayer-go/client"
+	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+// pathHttpClient answers each SoftLayer API call by the end of its path
+type pathHttpClient struct {
+	*fakeslclient.FakeHttpClient
+	statusCodes map[string]int
+	responses   map[string]string
+}
+
+func (c pathHttpClient) respond(path string) ([]byte, int, error) {
+	for suffix, statusCode := range c.statusCodes {
+		if strings.HasSuffix(path, suffix) {
+			return []byte(`{"error":"fake-error"}`), statusCode, nil
+		}
+	}
+	for suffix, response := range c.responses {
+		if strings.HasSuffix(path, suffix) {
+			return []byte(response), 200, nil
+		}
+	}
+	return []byte(`[]`), 200, nil
+}
+
+func (c pathHttpClient) DoRawHttpRequest(path string, requestType string, requestBody *bytes.Buffer) ([]byte, int, error) {
+	return c.respond(path)
+}
+
+func (c pathHttpClient) DoRawHttpRequestWithObjectMask(path string, masks []string, requestType string, requestBody *bytes.Buffer) ([]byte, int, error) {
+	return c.respond(path)
+}
+
+func (c pathHttpClient) DoRawHttpRequestWithObjectFilter(path string, filters string, requestType string, requestBody *bytes.Buffer) ([]byte, int, error) {
+	return c.respond(path)
+}
+
+func (c pathHttpClient) DoRawHttpRequestWithObjectFilterAndObjectMask(path string, masks []string, filters string, requestType string, requestBody *bytes.Buffer) ([]byte, int, error) {
+	return c.respond(path)
+}
+
+var _ = Describe("SoftLayer helper", func() {
+	Describe("AttachEphemeralDiskToVirtualGuest", func() {
+		var (
+			httpClient pathHttpClient
+			client     *slclient.SoftLayerClient
+			logger     boshlog.Logger
+		)
+
+		BeforeEach(func() {
+			httpClient = pathHttpClient{
+				FakeHttpClient: fakeslclient.NewFakeHttpClient("fake-username", "fake-api-key"),
+				statusCodes:    map[string]int{},
+				responses: map[string]string{
+					"getLastTransaction.json":   `{"transactionGroup":{"name":"Service Setup"},"transactionStatus":{"friendlyName":"Complete"}}`,
+					"getUpgradeItemPrices.json": `[{"id":5678,"categories":[{"categoryCode":"guest_disk1"}],"item":{"capacity":"100","description":"100 GB (LOCAL)"}}]`,
+				},
+			}
+			client = slclient.NewSoftLayerClient("fake-username", "fake-api-key")
+			client.HttpClient = NewClassifyingHttpClient(httpClient)
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+		})
+
+		It("returns the not found error when the disk order finds no virtual guest", func() {
+			httpClient.statusCodes["placeOrder.json"] = 404
+
+			err := AttachEphemeralDiskToVirtualGuest(context.Background(), client, 1234, 100, NewWaitPolicy(time.Second, 10*time.Millisecond), logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Ordering ephemeral disk for VirtualGuest `1234`"))
+			Expect(IsSoftLayerNotFoundError(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,188 @@
+package common
+
+import (
+	"encoding/json"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// WaitPolicy bounds how long a single operation polls SoftLayer and how often.
+// Polling starts at PollingInterval and backs off up to MaxPollingInterval.
+type WaitPolicy struct {
+	Timeout            time.Duration
+	PollingInterval    time.Duration
+	MaxPollingInterval time.Duration
+}
+
+func NewWaitPolicy(timeout time.Duration, pollingInterval time.Duration) WaitPolicy {
+	return WaitPolicy{Timeout: timeout, PollingInterval: pollingInterval}
+}
+
+func (p WaitPolicy) WithMaxPollingInterval(maxPollingInterval time.Duration) WaitPolicy {
+	p.MaxPollingInterval = maxPollingInterval
+	return p
+}
+
+type waitPolicyJSON struct {
+	Timeout            string `json:"timeout,omitempty"`
+	PollingInterval    string `json:"polling_interval,omitempty"`
+	MaxPollingInterval string `json:"max_polling_interval,omitempty"`
+}
+
+// UnmarshalJSON accepts durations such as "120m" or "5s"
+func (p *WaitPolicy) UnmarshalJSON(data []byte) error {
+	var raw waitPolicyJSON
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return bosherr.WrapError(err, "Unmarshalling wait policy")
+	}
+
+	if raw.Timeout != "" {
+		p.Timeout, err = time.ParseDuration(raw.Timeout)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Parsing wait policy timeout '%s'", raw.Timeout)
+		}
+	}
+
+	if raw.PollingInterval != "" {
+		p.PollingInterval, err = time.ParseDuration(raw.PollingInterval)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Parsing wait policy polling interval '%s'", raw.PollingInterval)
+		}
+	}
+
+	if raw.MaxPollingInterval != "" {
+		p.MaxPollingInterval, err = time.ParseDuration(raw.MaxPollingInterval)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Parsing wait policy max polling interval '%s'", raw.MaxPollingInterval)
+		}
+	}
+
+	return nil
+}
+
+func (p WaitPolicy) MarshalJSON() ([]byte, error) {
+	raw := waitPolicyJSON{
+		Timeout:         p.Timeout.String(),
+		PollingInterval: p.PollingInterval.String(),
+	}
+
+	if p.MaxPollingInterval != 0 {
+		raw.MaxPollingInterval = p.MaxPollingInterval.String()
+	}
+
+	return json.Marshal(raw)
+}
+
+func (p WaitPolicy) Validate() error {
+	if p.Timeout <= 0 {
+		return bosherr.Error("Must provide a positive timeout")
+	}
+
+	if p.PollingInterval <= 0 {
+		return bosherr.Error("Must provide a positive polling interval")
+	}
+
+	if p.PollingInterval > p.Timeout {
+		return bosherr.Errorf("Polling interval '%s' must not exceed timeout '%s'", p.PollingInterval, p.Timeout)
+	}
+
+	if p.MaxPollingInterval != 0 && p.MaxPollingInterval < p.PollingInterval {
+		return bosherr.Errorf("Max polling interval '%s' must not be below polling interval '%s'", p.MaxPollingInterval, p.PollingInterval)
+	}
+
+	return nil
+}
+
+func (p WaitPolicy) withDefault(defaultPolicy WaitPolicy) WaitPolicy {
+	if p.Timeout == 0 {
+		p.Timeout = defaultPolicy.Timeout
+	}
+
+	if p.PollingInterval == 0 {
+		p.PollingInterval = defaultPolicy.PollingInterval
+	}
+
+	// A configured polling interval above the default max only disables backoff
+	if p.MaxPollingInterval == 0 && defaultPolicy.MaxPollingInterval >= p.PollingInterval {
+		p.MaxPollingInterval = defaultPolicy.MaxPollingInterval
+	}
+
+	return p
+}
+
+// WaitPolicies holds one WaitPolicy per kind of long running operation
+type WaitPolicies struct {
+	// Provisioning a new virtual guest or bare metal server
+	Create WaitPolicy `json:"create,omitempty"`
+
+	// Reloading the OS of an existing virtual guest or bare metal server
+	OSReload WaitPolicy `json:"os_reload,omitempty"`
+
+	// Creating, attaching, detaching and resizing disks
+	Attach WaitPolicy `json:"attach,omitempty"`
+
+	// Deleting virtual guests and stemcells
+	Delete WaitPolicy `json:"delete,omitempty"`
+
+	// Looking up stemcell images
+	StemcellLookup WaitPolicy `json:"stemcell_lookup,omitempty"`
+}
+
+func DefaultWaitPolicies() WaitPolicies {
+	return WaitPolicies{
+		Create:         NewWaitPolicy(120*time.Minute, 5*time.Second).WithMaxPollingInterval(1 * time.Minute),
+		OSReload:       NewWaitPolicy(4*time.Hour, 10*time.Second).WithMaxPollingInterval(2 * time.Minute),
+		Attach:         NewWaitPolicy(60*time.Minute, 10*time.Second).WithMaxPollingInterval(1 * time.Minute),
+		Delete:         NewWaitPolicy(60*time.Minute, 10*time.Second).WithMaxPollingInterval(1 * time.Minute),
+		StemcellLookup: NewWaitPolicy(30*time.Second, 5*time.Second).WithMaxPollingInterval(10 * time.Second),
+	}
+}
+
+// UniformWaitPolicies uses the same policy for every operation
+func UniformWaitPolicies(policy WaitPolicy) WaitPolicies {
+	return WaitPolicies{
+		Create:         policy,
+		OSReload:       policy,
+		Attach:         policy,
+		Delete:         policy,
+		StemcellLookup: policy,
+	}
+}
+
+// WithDefaults fills every unset timeout or polling interval from DefaultWaitPolicies
+func (p WaitPolicies) WithDefaults() WaitPolicies {
+	defaults := DefaultWaitPolicies()
+
+	return WaitPolicies{
+		Create:         p.Create.withDefault(defaults.Create),
+		OSReload:       p.OSReload.withDefault(defaults.OSReload),
+		Attach:         p.Attach.withDefault(defaults.Attach),
+		Delete:         p.Delete.withDefault(defaults.Delete),
+		StemcellLookup: p.StemcellLookup.withDefault(defaults.StemcellLookup),
+	}
+}
+
+func (p WaitPolicies) Validate() error {
+	policies := []struct {
+		name   string
+		policy WaitPolicy
+	}{
+		{"create", p.Create},
+		{"os_reload", p.OSReload},
+		{"attach", p.Attach},
+		{"delete", p.Delete},
+		{"stemcell_lookup", p.StemcellLookup},
+	}
+
+	for _, named := range policies {
+		err := named.policy.Validate()
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Validating '%s' wait policy", named.name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package common_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+var _ = Describe("WaitPolicies", func() {
+	Describe("UnmarshalJSON", func() {
+		It("parses durations per operation", func() {
+			var policies WaitPolicies
+			err := json.Unmarshal([]byte(`{
+				"create": {"timeout": "90m", "polling_interval": "15s", "max_polling_interval": "2m"},
+				"stemcell_lookup": {"timeout": "1m"}
+			}`), &policies)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(policies.Create).To(Equal(NewWaitPolicy(90*time.Minute, 15*time.Second).WithMaxPollingInterval(2 * time.Minute)))
+			Expect(policies.StemcellLookup).To(Equal(NewWaitPolicy(1*time.Minute, 0)))
+			Expect(policies.Delete).To(Equal(WaitPolicy{}))
+		})
+
+		It("returns error if a duration cannot be parsed", func() {
+			var policies WaitPolicies
+			err := json.Unmarshal([]byte(`{"attach": {"timeout": "forever"}}`), &policies)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing wait policy timeout 'forever'"))
+		})
+	})
+
+	Describe("WithDefaults", func() {
+		It("keeps configured values and fills the rest from the defaults", func() {
+			policies := WaitPolicies{
+				Create:         NewWaitPolicy(90*time.Minute, 0),
+				StemcellLookup: NewWaitPolicy(0, 1*time.Second),
+			}.WithDefaults()
+
+			defaults := DefaultWaitPolicies()
+			Expect(policies.Create).To(Equal(NewWaitPolicy(90*time.Minute, defaults.Create.PollingInterval).WithMaxPollingInterval(defaults.Create.MaxPollingInterval)))
+			Expect(policies.StemcellLookup).To(Equal(NewWaitPolicy(defaults.StemcellLookup.Timeout, 1*time.Second).WithMaxPollingInterval(defaults.StemcellLookup.MaxPollingInterval)))
+			Expect(policies.OSReload).To(Equal(defaults.OSReload))
+			Expect(policies.Attach).To(Equal(defaults.Attach))
+			Expect(policies.Delete).To(Equal(defaults.Delete))
+		})
+
+		It("does not back off when the polling interval exceeds the default max", func() {
+			policies := WaitPolicies{
+				Attach: NewWaitPolicy(0, 5*time.Minute),
+			}.WithDefaults()
+
+			Expect(policies.Attach.MaxPollingInterval).To(BeZero())
+			Expect(policies.Validate()).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("accepts the defaults", func() {
+			Expect(DefaultWaitPolicies().Validate()).ToNot(HaveOccurred())
+		})
+
+		It("returns error if a timeout is not positive", func() {
+			policies := DefaultWaitPolicies()
+			policies.Delete = NewWaitPolicy(0, 1*time.Second)
+
+			err := policies.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating 'delete' wait policy"))
+		})
+
+		It("returns error if the polling interval exceeds the timeout", func() {
+			policies := DefaultWaitPolicies()
+			policies.OSReload = NewWaitPolicy(1*time.Second, 5*time.Second)
+
+			err := policies.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating 'os_reload' wait policy"))
+		})
+
+		It("returns error if the max polling interval is below the polling interval", func() {
+			policies := DefaultWaitPolicies()
+			policies.Attach = NewWaitPolicy(1*time.Minute, 10*time.Second).WithMaxPollingInterval(5 * time.Second)
+
+			err := policies.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating 'attach' wait policy"))
+		})
+	})
+})
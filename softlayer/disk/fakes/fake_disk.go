@@ -1,11 +1,28 @@
 package fakes
 
+import (
+	"context"
+
+	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
+)
+
 type FakeDisk struct {
 	id   int
 	path string
 
 	DeleteCalled bool
 	DeleteErr    error
+
+	SetMetadataMetadata bslcdisk.DiskMetadata
+	SetMetadataErr      error
+
+	SnapshotNotes    string
+	SnapshotSnapshot bslcdisk.Snapshot
+	SnapshotErr      error
+
+	ResizeSize    int
+	ResizeResized bool
+	ResizeErr     error
 }
 
 func NewFakeDisk(id int) *FakeDisk {
@@ -24,3 +41,18 @@ func (s *FakeDisk) Delete() error {
 	s.DeleteCalled = true
 	return s.DeleteErr
 }
+
+func (s *FakeDisk) SetMetadata(metadata bslcdisk.DiskMetadata) error {
+	s.SetMetadataMetadata = metadata
+	return s.SetMetadataErr
+}
+
+func (s *FakeDisk) Snapshot(notes string) (bslcdisk.Snapshot, error) {
+	s.SnapshotNotes = notes
+	return s.SnapshotSnapshot, s.SnapshotErr
+}
+
+func (s *FakeDisk) Resize(ctx context.Context, size int) (bool, error) {
+	s.ResizeSize = size
+	return s.ResizeResized, s.ResizeErr
+}
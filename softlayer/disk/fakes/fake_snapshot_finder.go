@@ -0,0 +1,17 @@
+package fakes
+
+import (
+	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
+)
+
+type FakeSnapshotFinder struct {
+	FindID       int
+	FindSnapshot bslcdisk.Snapshot
+	FindFound    bool
+	FindErr      error
+}
+
+func (f *FakeSnapshotFinder) Find(id int) (bslcdisk.Snapshot, bool, error) {
+	f.FindID = id
+	return f.FindSnapshot, f.FindFound, f.FindErr
+}
@@ -1,10 +1,16 @@
 package disk
 
+import (
+	"context"
+)
+
 type DiskCloudProperties struct {
 	Iops             int  `json:"iops,omitempty"`
 	UseHourlyPricing bool `json:"useHourlyPricing,omitempty"`
 }
 
+type DiskMetadata map[string]interface{}
+
 type Creator interface {
 	Create(size int, cloudProp DiskCloudProperties, datacenter_id int) (Disk, error)
 }
@@ -15,6 +21,22 @@ type Finder interface {
 
 type Disk interface {
 	ID() int
+	Delete() error
+
+	SetMetadata(metadata DiskMetadata) error
+
+	Snapshot(notes string) (Snapshot, error)
 
+	// Resize grows the disk to hold at least size MB, reporting false
+	// when the storage type cannot be resized in place
+	Resize(ctx context.Context, size int) (bool, error)
+}
+
+type SnapshotFinder interface {
+	Find(id int) (Snapshot, bool, error)
+}
+
+type Snapshot interface {
+	ID() int
 	Delete() error
 }
@@ -1,51 +1,159 @@
 package disk
 
 import (
+	"context"
+	"fmt"
 	"strconv"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	sl "github.com/maximilien/softlayer-go/softlayer"
+	"github.com/pivotal-golang/clock"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 )
 
-const SOFTLAYER_DISK_CREATOR_LOG_TAG = "SoftLayerDiskCreator"
+const (
+	SOFTLAYER_DISK_CREATOR_LOG_TAG = "SoftLayerDiskCreator"
+
+	// Offered for every disk size in SoftLayerDiskSizes
+	DEFAULT_PERFORMANCE_STORAGE_IOPS = 1000
+)
 
 type SoftLayerCreator struct {
 	softLayerClient sl.Client
+	waitPolicies    bslcommon.WaitPolicies
 	logger          boshlog.Logger
 }
 
-func NewSoftLayerDiskCreator(client sl.Client, logger boshlog.Logger) SoftLayerCreator {
+func NewSoftLayerDiskCreator(client sl.Client, waitPolicies bslcommon.WaitPolicies, logger boshlog.Logger) SoftLayerCreator {
 	return SoftLayerCreator{
 		softLayerClient: client,
+		waitPolicies:    waitPolicies,
 		logger:          logger,
 	}
 }
 
+// Create orders a performance block volume of the STORAGE_AS_A_SERVICE package,
+// which Resize can upgrade in place
 func (c SoftLayerCreator) Create(size int, cloudProps DiskCloudProperties, datacenter_id int) (Disk, error) {
 	c.logger.Debug(SOFTLAYER_DISK_CREATOR_LOG_TAG, "Creating disk of size '%d'", size)
 
-	storageService, err := c.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	diskSize, err := RoundUpToSoftLayerDiskSize((size + 1023) / 1024)
 	if err != nil {
-		return SoftLayerDisk{}, bosherr.WrapError(err, "Create SoftLayer Network Storage Service error.")
+		return SoftLayerDisk{}, err
+	}
+
+	iops := cloudProps.Iops
+	if iops == 0 {
+		iops = DEFAULT_PERFORMANCE_STORAGE_IOPS
 	}
 
-	disk, err := storageService.CreateNetworkStorage(c.getSoftLayerDiskSize(size), cloudProps.Iops, strconv.Itoa(datacenter_id), cloudProps.UseHourlyPricing)
+	itemPrices, err := getStorageAsAServiceItemPrices(
+		c.softLayerClient,
+		STORAGE_AS_A_SERVICE_CATEGORY_CODE,
+		STORAGE_BLOCK_CATEGORY_CODE,
+		PERFORMANCE_STORAGE_SPACE_CATEGORY_CODE,
+		PERFORMANCE_STORAGE_IOPS_CATEGORY_CODE,
+	)
 	if err != nil {
 		return SoftLayerDisk{}, bosherr.WrapError(err, "Create SoftLayer iSCSI disk error.")
 	}
 
-	return NewSoftLayerDisk(disk.Id, c.softLayerClient, c.logger), nil
+	prices := []map[string]int{}
+	for _, required := range []struct {
+		categoryCode string
+		capacity     int
+	}{
+		{STORAGE_AS_A_SERVICE_CATEGORY_CODE, 0},
+		{STORAGE_BLOCK_CATEGORY_CODE, 0},
+		{PERFORMANCE_STORAGE_SPACE_CATEGORY_CODE, diskSize},
+		{PERFORMANCE_STORAGE_IOPS_CATEGORY_CODE, iops},
+	} {
+		itemPriceId, err := findStorageAsAServiceItemPriceId(itemPrices, required.categoryCode, required.capacity)
+		if err != nil {
+			return SoftLayerDisk{}, bosherr.WrapErrorf(err, "Create SoftLayer iSCSI disk of size %d GB with %d IOPS error.", diskSize, iops)
+		}
+		prices = append(prices, map[string]int{"id": itemPriceId})
+	}
+
+	orderId, err := placeStorageAsAServiceOrder(c.softLayerClient, map[string]interface{}{
+		"complexType":      "SoftLayer_Container_Product_Order_Network_Storage_AsAService",
+		"packageId":        STORAGE_AS_A_SERVICE_PACKAGE_ID,
+		"location":         strconv.Itoa(datacenter_id),
+		"prices":           prices,
+		"volumeSize":       diskSize,
+		"iops":             iops,
+		"osFormatType":     map[string]string{"keyName": "LINUX"},
+		"quantity":         1,
+		"useHourlyPricing": cloudProps.UseHourlyPricing,
+	})
+	if err != nil {
+		return SoftLayerDisk{}, bosherr.WrapError(err, "Create SoftLayer iSCSI disk error.")
+	}
+
+	diskId, err := c.findOrderedDiskId(orderId)
+	if err != nil {
+		return SoftLayerDisk{}, bosherr.WrapErrorf(err, "Finding SoftLayer iSCSI disk of order %d", orderId)
+	}
+
+	return NewSoftLayerDisk(diskId, c.softLayerClient, c.waitPolicies, c.logger), nil
+}
+
+func (c SoftLayerCreator) findOrderedDiskId(orderId int) (int, error) {
+	accountService, err := c.softLayerClient.GetSoftLayer_Account_Service()
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Create SoftLayer Account Service error.")
+	}
+
+	filter := fmt.Sprintf(`{"iscsiNetworkStorage":{"billingItem":{"orderItem":{"order":{"id":{"operation":%d}}}}}}`, orderId)
+
+	var diskId int
+	found, err := bslcommon.NewPoller(c.waitPolicies.Attach, clock.NewClock()).Poll(context.Background(), func() (bool, error) {
+		disks, err := accountService.GetIscsiNetworkStorageWithFilter(filter)
+		if err != nil {
+			return false, err
+		}
+
+		if len(disks) != 1 {
+			return false, nil
+		}
+
+		diskId = disks[0].Id
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if !found {
+		return 0, bosherr.Errorf("Waiting for the iSCSI disk of order %d timed out", orderId)
+	}
+
+	return diskId, nil
 }
 
-func (c SoftLayerCreator) getSoftLayerDiskSize(size int) int {
-	// Sizes and IOPS ranges: http://knowledgelayer.softlayer.com/learning/performance-storage-concepts
-	sizeArray := []int{20, 40, 80, 100, 250, 500, 1000, 2000, 4000, 8000, 12000}
+// Sizes and IOPS ranges: http://knowledgelayer.softlayer.com/learning/performance-storage-concepts
+var SoftLayerDiskSizes = []int{20, 40, 80, 100, 250, 500, 1000, 2000, 4000, 8000, 12000}
 
-	for _, value := range sizeArray {
-		if ret := size / 1024; ret <= value {
-			return value
+// RoundUpToSoftLayerDiskSize returns the smallest offered size that fits sizeInGb,
+// or a DiskSizeTooLargeError when no offered size is large enough
+func RoundUpToSoftLayerDiskSize(sizeInGb int) (int, error) {
+	for _, value := range SoftLayerDiskSizes {
+		if sizeInGb <= value {
+			return value, nil
 		}
 	}
-	return 12000
+	return 0, DiskSizeTooLargeError{SizeInGb: sizeInGb}
+}
+
+// DiskSizeTooLargeError reports a disk size above the largest SoftLayer offers
+type DiskSizeTooLargeError struct {
+	SizeInGb int
+}
+
+func (e DiskSizeTooLargeError) Type() string   { return "Bosh::Clouds::CloudError" }
+func (e DiskSizeTooLargeError) CanRetry() bool { return false }
+func (e DiskSizeTooLargeError) Error() string {
+	return fmt.Sprintf("Requested disk size %d GB is larger than the %d GB SoftLayer offers", e.SizeInGb, SoftLayerDiskSizes[len(SoftLayerDiskSizes)-1])
 }
@@ -1,8 +1,12 @@
 package disk_test
 
 import (
+	"context"
+
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	testhelpers "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
 
 	fakeclient "github.com/maximilien/softlayer-go/client/fakes"
@@ -23,7 +27,7 @@ var _ = Describe("SoftLayerCreator", func() {
 	BeforeEach(func() {
 		fc = fakeclient.NewFakeSoftLayerClient("fake-user", "fake-key")
 		logger = boshlog.NewLogger(boshlog.LevelNone)
-		creator = NewSoftLayerDiskCreator(fc, logger)
+		creator = NewSoftLayerDiskCreator(fc, bslcommon.DefaultWaitPolicies(), logger)
 	})
 
 	Describe("Create", func() {
@@ -34,34 +38,45 @@ var _ = Describe("SoftLayerCreator", func() {
 		Context("Creates disk successfully with cloud properties", func() {
 			BeforeEach(func() {
 				fileNames := []string{
-					"SoftLayer_Product_Order_Service_getItems.json",
-					"SoftLayer_Product_Order_Service_getItemPrices.json",
-					"SoftLayer_Product_Order_Service_getItemPricesBySizeAndIops.json",
+					"SoftLayer_Product_Package_Service_getStorageAsAServiceItemPrices.json",
 					"SoftLayer_Product_Order_Service_placeOrder.json",
 					"SoftLayer_Account_Service_getIscsiVolume.json",
 				}
 				cloudProps = DiskCloudProperties{
-					Iops:             1000,
+					Iops:             3000,
 					UseHourlyPricing: true,
 				}
 				testhelpers.SetTestFixturesForFakeSoftLayerClient(fc, fileNames)
 			})
 
 			It("creates disk successfully and returns unique disk id", func() {
-				disk, err := creator.Create(20, cloudProps, 123)
+				disk, err := creator.Create(20*1024, cloudProps, 123)
 				Expect(err).ToNot(HaveOccurred())
 
-				expectedDisk := NewSoftLayerDisk(1234, fc, logger)
+				expectedDisk := NewSoftLayerDisk(1234, fc, bslcommon.DefaultWaitPolicies(), logger)
 				Expect(disk).To(Equal(expectedDisk))
 			})
+
+			It("orders an upgradable Storage-as-a-Service performance block volume", func() {
+				_, err := creator.Create(20*1024, cloudProps, 123)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fc.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Product_Order/placeOrder.json"))
+
+				order := fc.FakeHttpClient.DoRawHttpRequestRequestBody.String()
+				Expect(order).To(ContainSubstring(`"complexType":"SoftLayer_Container_Product_Order_Network_Storage_AsAService"`))
+				Expect(order).To(ContainSubstring(`"packageId":759`))
+				Expect(order).To(ContainSubstring(`"location":"123"`))
+				Expect(order).To(ContainSubstring(`"prices":[{"id":189433},{"id":189443},{"id":190233},{"id":190303}]`))
+				Expect(order).To(ContainSubstring(`"volumeSize":20`))
+				Expect(order).To(ContainSubstring(`"iops":3000`))
+				Expect(order).To(ContainSubstring(`"useHourlyPricing":true`))
+			})
 		})
 
 		Context("Creates disk successfully without cloud properties", func() {
 			BeforeEach(func() {
 				fileNames := []string{
-					"SoftLayer_Product_Order_Service_getItems.json",
-					"SoftLayer_Product_Order_Service_getItemPrices.json",
-					"SoftLayer_Product_Order_Service_getIopsItemPrices.json",
+					"SoftLayer_Product_Package_Service_getStorageAsAServiceItemPrices.json",
 					"SoftLayer_Product_Order_Service_placeOrder.json",
 					"SoftLayer_Account_Service_getIscsiVolume.json",
 				}
@@ -70,15 +85,59 @@ var _ = Describe("SoftLayerCreator", func() {
 			})
 
 			It("creates disk successfully and returns unique disk id", func() {
-				disk, err := creator.Create(20, cloudProps, 123)
+				disk, err := creator.Create(20*1024, cloudProps, 123)
 				Expect(err).ToNot(HaveOccurred())
 
-				expectedDisk := NewSoftLayerDisk(1234, fc, logger)
+				expectedDisk := NewSoftLayerDisk(1234, fc, bslcommon.DefaultWaitPolicies(), logger)
 				Expect(disk).To(Equal(expectedDisk))
 			})
+
+			It("orders the default IOPS", func() {
+				_, err := creator.Create(20*1024, cloudProps, 123)
+				Expect(err).ToNot(HaveOccurred())
+
+				order := fc.FakeHttpClient.DoRawHttpRequestRequestBody.String()
+				Expect(order).To(ContainSubstring(`"prices":[{"id":189433},{"id":189443},{"id":190233},{"id":190293}]`))
+				Expect(order).To(ContainSubstring(`"iops":1000`))
+			})
+		})
+
+		Context("Resizes a disk it created", func() {
+			It("upgrades the created disk in place", func() {
+				fileNames := []string{
+					"SoftLayer_Product_Package_Service_getStorageAsAServiceItemPrices.json",
+					"SoftLayer_Product_Order_Service_placeOrder.json",
+					"SoftLayer_Account_Service_getIscsiVolume.json",
+				}
+				testhelpers.SetTestFixturesForFakeSoftLayerClient(fc, fileNames)
+
+				disk, err := creator.Create(20*1024, DiskCloudProperties{}, 123)
+				Expect(err).ToNot(HaveOccurred())
+
+				fileNames = []string{
+					"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgradable.json",
+					"SoftLayer_Product_Package_Service_getStorageAsAServiceItemPrices.json",
+					"SoftLayer_Product_Order_Service_placeOrder.json",
+					"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgraded.json",
+				}
+				testhelpers.SetTestFixturesForFakeSoftLayerClient(fc, fileNames)
+
+				resized, err := disk.Resize(context.Background(), 40*1024)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resized).To(BeTrue())
+
+				order := fc.FakeHttpClient.DoRawHttpRequestRequestBody.String()
+				Expect(order).To(ContainSubstring(`"complexType":"SoftLayer_Container_Product_Order_Network_Storage_AsAService_Upgrade"`))
+				Expect(order).To(ContainSubstring(`"volume":{"id":1234}`))
+			})
 		})
 
 		Context("Failed to create disk", func() {
+			It("rounds a size that is not a whole number of GB up before checking it is offered", func() {
+				_, err := creator.Create(12000*1024+1, cloudProps, 123)
+				Expect(err).To(Equal(DiskSizeTooLargeError{SizeInGb: 12001}))
+			})
+
 			It("Reports error due to wrong virtual guest id", func() {
 				fileNames := []string{
 					"SoftLayer_Virtual_Guest_Service_getEmptyObject.json",
@@ -92,4 +151,24 @@ var _ = Describe("SoftLayerCreator", func() {
 		})
 
 	})
+
+	Describe("RoundUpToSoftLayerDiskSize", func() {
+		It("rounds up to the next available disk size", func() {
+			for requested, expected := range map[int]int{1: 20, 20: 20, 21: 40, 260: 500, 12000: 12000} {
+				size, err := RoundUpToSoftLayerDiskSize(requested)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(size).To(Equal(expected))
+			}
+		})
+
+		It("returns a cloud error when the size exceeds the largest available disk size", func() {
+			_, err := RoundUpToSoftLayerDiskSize(20000)
+			Expect(err).To(Equal(DiskSizeTooLargeError{SizeInGb: 20000}))
+			Expect(err.Error()).To(Equal("Requested disk size 20000 GB is larger than the 12000 GB SoftLayer offers"))
+
+			cloudErr, found := bslcapi.FindCloudError(err)
+			Expect(found).To(BeTrue())
+			Expect(cloudErr.Type()).To(Equal("Bosh::Clouds::CloudError"))
+		})
+	})
 })
@@ -1,23 +1,77 @@
 package disk
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	slcommon "github.com/maximilien/softlayer-go/common"
+	datatypes "github.com/maximilien/softlayer-go/data_types"
 	slc "github.com/maximilien/softlayer-go/softlayer"
+	"github.com/pivotal-golang/clock"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+const (
+	SOFTLAYER_DISK_LOG_TAG = "SoftLayerDisk"
+
+	// Only Storage-as-a-Service volumes can be upgraded in place, through the
+	// STORAGE_AS_A_SERVICE package
+	STORAGE_AS_A_SERVICE_PACKAGE_ID         = 759
+	STORAGE_AS_A_SERVICE_CATEGORY_CODE      = "storage_as_a_service"
+	STORAGE_BLOCK_CATEGORY_CODE             = "storage_block"
+	PERFORMANCE_BLOCK_STORAGE_TYPE          = "PERFORMANCE_BLOCK_STORAGE"
+	PERFORMANCE_STORAGE_SPACE_CATEGORY_CODE = "performance_storage_space"
+	PERFORMANCE_STORAGE_IOPS_CATEGORY_CODE  = "performance_storage_iops"
 )
 
-const SOFTLAYER_DISK_LOG_TAG = "SoftLayerDisk"
+type upgradableVolume struct {
+	Id             int  `json:"id"`
+	CapacityGb     int  `json:"capacityGb"`
+	UpgradableFlag bool `json:"upgradableFlag"`
+
+	BillingItem struct {
+		CategoryCode string `json:"categoryCode"`
+	} `json:"billingItem"`
+
+	StorageType struct {
+		KeyName string `json:"keyName"`
+	} `json:"storageType"`
+}
+
+type storageAsAServiceItemPrice struct {
+	Id              int `json:"id"`
+	LocationGroupId int `json:"locationGroupId"`
+
+	Categories []struct {
+		CategoryCode string `json:"categoryCode"`
+	} `json:"categories"`
+
+	Item struct {
+		CapacityMinimum string `json:"capacityMinimum"`
+		CapacityMaximum string `json:"capacityMaximum"`
+	} `json:"item"`
+}
 
 type SoftLayerDisk struct {
 	id              int
 	softLayerClient slc.Client
+	waitPolicies    bslcommon.WaitPolicies
 	logger          boshlog.Logger
 }
 
-func NewSoftLayerDisk(id int, client slc.Client, logger boshlog.Logger) SoftLayerDisk {
+func NewSoftLayerDisk(id int, client slc.Client, waitPolicies bslcommon.WaitPolicies, logger boshlog.Logger) SoftLayerDisk {
 	return SoftLayerDisk{
 		id:              id,
 		softLayerClient: client,
+		waitPolicies:    waitPolicies,
 		logger:          logger,
 	}
 }
@@ -39,3 +93,301 @@ func (s SoftLayerDisk) Delete() error {
 
 	return nil
 }
+
+func (s SoftLayerDisk) SetMetadata(metadata DiskMetadata) error {
+	notes, err := s.extractNotesFromDiskMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	if notes == "" {
+		return nil
+	}
+
+	s.logger.Debug(SOFTLAYER_DISK_LOG_TAG, "Setting notes '%s' on disk '%d'", notes, s.id)
+
+	parameters := map[string][]interface{}{
+		"parameters": []interface{}{
+			map[string]string{"notes": notes},
+		},
+	}
+	requestBody, err := json.Marshal(parameters)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling editObject parameters")
+	}
+
+	response, errorCode, err := s.softLayerClient.GetHttpClient().DoRawHttpRequest(fmt.Sprintf("SoftLayer_Network_Storage/%d/editObject.json", s.id), "POST", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Failed to set notes on iSCSI volume with id: %d", s.id)
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return bslcommon.NewSoftLayerHttpError(errorCode, "Failed to set notes on iSCSI volume with id: %d", s.id)
+	}
+
+	if string(response) != "true" {
+		return bosherr.Errorf("Failed to set notes on iSCSI volume with id: %d, got response '%s'", s.id, string(response))
+	}
+
+	return nil
+}
+
+func (s SoftLayerDisk) Snapshot(notes string) (Snapshot, error) {
+	s.logger.Debug(SOFTLAYER_DISK_LOG_TAG, "Creating snapshot of disk '%d'", s.id)
+
+	parameters := map[string][]interface{}{
+		"parameters": []interface{}{notes},
+	}
+	requestBody, err := json.Marshal(parameters)
+	if err != nil {
+		return SoftLayerSnapshot{}, bosherr.WrapError(err, "Marshalling createSnapshot parameters")
+	}
+
+	response, errorCode, err := s.softLayerClient.GetHttpClient().DoRawHttpRequest(fmt.Sprintf("SoftLayer_Network_Storage/%d/createSnapshot.json", s.id), "POST", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return SoftLayerSnapshot{}, bosherr.WrapErrorf(err, "Failed to create snapshot of iSCSI volume with id: %d", s.id)
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return SoftLayerSnapshot{}, bslcommon.NewSoftLayerHttpError(errorCode, "Failed to create snapshot of iSCSI volume with id: %d", s.id)
+	}
+
+	snapshot := datatypes.SoftLayer_Network_Storage{}
+	err = json.Unmarshal(response, &snapshot)
+	if err != nil {
+		return SoftLayerSnapshot{}, bosherr.WrapError(err, "Unmarshalling createSnapshot response")
+	}
+
+	if snapshot.Id == 0 {
+		return SoftLayerSnapshot{}, bosherr.Errorf("No snapshot was created for iSCSI volume with id: %d", s.id)
+	}
+
+	return NewSoftLayerSnapshot(snapshot.Id, s.softLayerClient, s.logger), nil
+}
+
+func (s SoftLayerDisk) Resize(ctx context.Context, size int) (bool, error) {
+	s.logger.Debug(SOFTLAYER_DISK_LOG_TAG, "Resizing disk '%d' to '%d'", s.id, size)
+
+	storageService, err := s.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Cannot get network storage service.")
+	}
+
+	newCapacity, err := RoundUpToSoftLayerDiskSize((size + 1023) / 1024)
+	if err != nil {
+		return false, err
+	}
+
+	volume, err := s.getUpgradableVolume()
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Failed to find iSCSI volume with id: %d", s.id)
+	}
+
+	if newCapacity == volume.CapacityGb {
+		return true, nil
+	}
+
+	if newCapacity < volume.CapacityGb || !volume.UpgradableFlag {
+		return false, nil
+	}
+
+	if volume.BillingItem.CategoryCode != STORAGE_AS_A_SERVICE_CATEGORY_CODE || volume.StorageType.KeyName != PERFORMANCE_BLOCK_STORAGE_TYPE {
+		s.logger.Debug(SOFTLAYER_DISK_LOG_TAG, "Disk '%d' of category '%s' and type '%s' cannot be upgraded in place", s.id, volume.BillingItem.CategoryCode, volume.StorageType.KeyName)
+		return false, nil
+	}
+
+	itemPrices, err := getStorageAsAServiceItemPrices(s.softLayerClient, PERFORMANCE_STORAGE_SPACE_CATEGORY_CODE)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Failed to find item price for iSCSI volume size %d GB", newCapacity)
+	}
+
+	sizeItemPriceId, err := findStorageAsAServiceItemPriceId(itemPrices, PERFORMANCE_STORAGE_SPACE_CATEGORY_CODE, newCapacity)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Failed to find item price for iSCSI volume size %d GB", newCapacity)
+	}
+
+	_, err = placeStorageAsAServiceOrder(s.softLayerClient, map[string]interface{}{
+		"complexType": "SoftLayer_Container_Product_Order_Network_Storage_AsAService_Upgrade",
+		"packageId":   STORAGE_AS_A_SERVICE_PACKAGE_ID,
+		"prices":      []map[string]int{{"id": sizeItemPriceId}},
+		"volume":      map[string]int{"id": s.id},
+		"volumeSize":  newCapacity,
+		"quantity":    1,
+	})
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Failed to upgrade iSCSI volume with id: %d", s.id)
+	}
+
+	resized, err := bslcommon.NewPoller(s.waitPolicies.Attach, clock.NewClock()).Poll(ctx, func() (bool, error) {
+		upgradedVolume, err := storageService.GetNetworkStorage(s.id)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Failed to find iSCSI volume with id: %d", s.id)
+		}
+
+		return upgradedVolume.CapacityGb >= newCapacity, nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if !resized {
+		return false, bosherr.Errorf("Waiting for iSCSI volume with id: %d to be resized to %d GB timed out", s.id, newCapacity)
+	}
+
+	return true, nil
+}
+
+func (s SoftLayerDisk) extractNotesFromDiskMetadata(metadata DiskMetadata) (string, error) {
+	keys := []string{}
+	for key := range metadata {
+		if bslcommon.IsTaggedMetadataKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		stringValue, ok := metadata[key].(string)
+		if !ok {
+			return "", bosherr.Errorf("Cannot convert tags metadata value `%v` to string", metadata[key])
+		}
+		pairs = append(pairs, key+":"+stringValue)
+	}
+
+	return strings.Join(pairs, ","), nil
+}
+
+func (s SoftLayerDisk) getUpgradableVolume() (upgradableVolume, error) {
+	objectMask := []string{
+		"id",
+		"capacityGb",
+		"upgradableFlag",
+		"billingItem.categoryCode",
+		"storageType.keyName",
+	}
+
+	response, errorCode, err := s.softLayerClient.GetHttpClient().DoRawHttpRequestWithObjectMask(fmt.Sprintf("SoftLayer_Network_Storage/%d/getObject.json", s.id), objectMask, "GET", new(bytes.Buffer))
+	if err != nil {
+		return upgradableVolume{}, err
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return upgradableVolume{}, bslcommon.NewSoftLayerHttpError(errorCode, "Getting iSCSI volume with id: %d", s.id)
+	}
+
+	volume := upgradableVolume{}
+	err = json.Unmarshal(response, &volume)
+	if err != nil {
+		return upgradableVolume{}, bosherr.WrapError(err, "Unmarshalling iSCSI volume")
+	}
+
+	return volume, nil
+}
+
+// getStorageAsAServiceItemPrices fetches the standard prices of the
+// STORAGE_AS_A_SERVICE package in the given categories
+func getStorageAsAServiceItemPrices(client slc.Client, categoryCodes ...string) ([]storageAsAServiceItemPrice, error) {
+	objectMask := []string{
+		"id",
+		"locationGroupId",
+		"categories.categoryCode",
+		"item.capacityMinimum",
+		"item.capacityMaximum",
+	}
+	categoryCodesJSON, err := json.Marshal(categoryCodes)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Marshalling category codes")
+	}
+	filters := fmt.Sprintf(`{"itemPrices":{"categories":{"categoryCode":{"operation":"in","options":[{"name":"data","value":%s}]}}}}`, categoryCodesJSON)
+
+	response, errorCode, err := client.GetHttpClient().DoRawHttpRequestWithObjectFilterAndObjectMask(fmt.Sprintf("SoftLayer_Product_Package/%d/getItemPrices.json", STORAGE_AS_A_SERVICE_PACKAGE_ID), objectMask, filters, "GET", new(bytes.Buffer))
+	if err != nil {
+		return nil, err
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return nil, bslcommon.NewSoftLayerHttpError(errorCode, "Getting item prices of package %d", STORAGE_AS_A_SERVICE_PACKAGE_ID)
+	}
+
+	itemPrices := []storageAsAServiceItemPrice{}
+	err = json.Unmarshal(response, &itemPrices)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling item prices")
+	}
+
+	return itemPrices, nil
+}
+
+// findStorageAsAServiceItemPriceId picks the standard price in the category whose
+// capacity range holds capacity. Categories without a range match any capacity.
+func findStorageAsAServiceItemPriceId(itemPrices []storageAsAServiceItemPrice, categoryCode string, capacity int) (int, error) {
+	for _, itemPrice := range itemPrices {
+		if itemPrice.LocationGroupId != 0 || !itemPrice.hasCategory(categoryCode) {
+			continue
+		}
+
+		if itemPrice.Item.CapacityMinimum == "" && itemPrice.Item.CapacityMaximum == "" {
+			return itemPrice.Id, nil
+		}
+
+		minimum, err := strconv.Atoi(itemPrice.Item.CapacityMinimum)
+		if err != nil {
+			continue
+		}
+
+		maximum, err := strconv.Atoi(itemPrice.Item.CapacityMaximum)
+		if err != nil {
+			continue
+		}
+
+		if minimum <= capacity && capacity <= maximum {
+			return itemPrice.Id, nil
+		}
+	}
+
+	return 0, bosherr.Errorf("No item price available in category '%s' for %d", categoryCode, capacity)
+}
+
+func (p storageAsAServiceItemPrice) hasCategory(categoryCode string) bool {
+	for _, category := range p.Categories {
+		if category.CategoryCode == categoryCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// placeStorageAsAServiceOrder places an order of the STORAGE_AS_A_SERVICE package
+// and returns its order id
+func placeStorageAsAServiceOrder(client slc.Client, order map[string]interface{}) (int, error) {
+	parameters := map[string][]interface{}{
+		"parameters": []interface{}{order},
+	}
+	requestBody, err := json.Marshal(parameters)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Marshalling order")
+	}
+
+	response, errorCode, err := client.GetHttpClient().DoRawHttpRequest("SoftLayer_Product_Order/placeOrder.json", "POST", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return 0, err
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return 0, bslcommon.NewSoftLayerHttpError(errorCode, "Placing order of package %d", STORAGE_AS_A_SERVICE_PACKAGE_ID)
+	}
+
+	receipt := datatypes.SoftLayer_Container_Product_Order_Receipt{}
+	err = json.Unmarshal(response, &receipt)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Unmarshalling order receipt")
+	}
+
+	if receipt.OrderId == 0 {
+		return 0, bosherr.Error("No order was placed")
+	}
+
+	return receipt.OrderId, nil
+}
@@ -1,8 +1,13 @@
 package disk_test
 
 import (
+	"context"
+	"time"
+
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	testhelpers "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
 
 	fakeclient "github.com/maximilien/softlayer-go/client/fakes"
@@ -21,7 +26,7 @@ var _ = Describe("SoftLayerDisk", func() {
 	BeforeEach(func() {
 		fc = fakeclient.NewFakeSoftLayerClient("fake-user", "fake-key")
 		logger := boshlog.NewLogger(boshlog.LevelNone)
-		disk = NewSoftLayerDisk(1234, fc, logger)
+		disk = NewSoftLayerDisk(1234, fc, bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(2*time.Second, 1*time.Second)), logger)
 	})
 
 	Describe("Delete", func() {
@@ -36,4 +41,185 @@ var _ = Describe("SoftLayerDisk", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
+
+	Describe("SetMetadata", func() {
+		It("sets notes on the iSCSI disk from the tagged metadata keys", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_editObject_true.json")
+
+			err := disk.SetMetadata(DiskMetadata{
+				"deployment":    "fake-deployment",
+				"job":           "fake-job",
+				"index":         "0",
+				"director_name": "fake-director",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fc.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Network_Storage/1234/editObject.json"))
+			Expect(fc.FakeHttpClient.DoRawHttpRequestRequestType).To(Equal("POST"))
+			Expect(fc.FakeHttpClient.DoRawHttpRequestRequestBody.String()).To(Equal(`{"parameters":[{"notes":"deployment:fake-deployment,index:0,job:fake-job"}]}`))
+		})
+
+		It("does not call SoftLayer when no tagged metadata keys are given", func() {
+			err := disk.SetMetadata(DiskMetadata{"director_name": "fake-director"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fc.FakeHttpClient.DoRawHttpRequestPath).To(BeEmpty())
+		})
+
+		It("reports error when a tagged metadata value is not a string", func() {
+			err := disk.SetMetadata(DiskMetadata{"index": 0})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("reports error when SoftLayer returns an HTTP error code", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_editObject_true.json")
+			fc.FakeHttpClient.DoRawHttpRequestInt = 500
+
+			err := disk.SetMetadata(DiskMetadata{"job": "fake-job"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Snapshot", func() {
+		It("creates a snapshot of an iSCSI disk successfully", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_createSnapshot.json")
+
+			snapshot, err := disk.Snapshot("fake-notes")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshot.ID()).To(Equal(5678))
+
+			Expect(fc.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Network_Storage/1234/createSnapshot.json"))
+			Expect(fc.FakeHttpClient.DoRawHttpRequestRequestType).To(Equal("POST"))
+			Expect(fc.FakeHttpClient.DoRawHttpRequestRequestBody.String()).To(ContainSubstring("fake-notes"))
+		})
+
+		It("reports error when SoftLayer returns an HTTP error code", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_createSnapshot.json")
+			fc.FakeHttpClient.DoRawHttpRequestInt = 500
+
+			_, err := disk.Snapshot("fake-notes")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("HTTP error code"))
+		})
+
+		It("reports error when no snapshot is created", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_createSnapshot_Empty.json")
+
+			_, err := disk.Snapshot("fake-notes")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Resize", func() {
+		It("upgrades an upgradable iSCSI disk in place", func() {
+			fileNames := []string{
+				"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgradable.json",
+				"SoftLayer_Product_Package_Service_getStorageAsAServiceItemPrices.json",
+				"SoftLayer_Product_Order_Service_placeOrder.json",
+				"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgraded.json",
+			}
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fc, fileNames)
+
+			resized, err := disk.Resize(context.Background(), 40*1024)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resized).To(BeTrue())
+			Expect(fc.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Product_Order/placeOrder.json"))
+			Expect(fc.FakeHttpClient.DoRawHttpRequestWithObjectFilterAndObjectMaskPath).To(Equal("SoftLayer_Product_Package/759/getItemPrices.json"))
+
+			order := fc.FakeHttpClient.DoRawHttpRequestRequestBody.String()
+			Expect(order).To(ContainSubstring(`"complexType":"SoftLayer_Container_Product_Order_Network_Storage_AsAService_Upgrade"`))
+			Expect(order).To(ContainSubstring(`"packageId":759`))
+			Expect(order).To(ContainSubstring(`"prices":[{"id":190263}]`))
+			Expect(order).To(ContainSubstring(`"volumeSize":40`))
+		})
+
+		It("rounds a size that is not a whole number of GB up before comparing", func() {
+			fileNames := []string{
+				"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgradable.json",
+				"SoftLayer_Product_Package_Service_getStorageAsAServiceItemPrices.json",
+				"SoftLayer_Product_Order_Service_placeOrder.json",
+				"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgraded.json",
+			}
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fc, fileNames)
+
+			resized, err := disk.Resize(context.Background(), 20500)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resized).To(BeTrue())
+			Expect(fc.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Product_Order/placeOrder.json"))
+		})
+
+		It("reports not resized when the iSCSI disk is not Storage-as-a-Service", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_getIscsiVolume_Legacy.json")
+
+			resized, err := disk.Resize(context.Background(), 40*1024)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resized).To(BeFalse())
+			Expect(fc.FakeHttpClient.DoRawHttpRequestPath).ToNot(Equal("SoftLayer_Product_Order/placeOrder.json"))
+		})
+
+		It("does nothing when the disk already has the requested size", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_getIscsiVolume_Upgradable.json")
+
+			resized, err := disk.Resize(context.Background(), 20*1024)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resized).To(BeTrue())
+		})
+
+		It("returns a cloud error when the size exceeds the largest available disk size", func() {
+			_, err := disk.Resize(context.Background(), 20000*1024)
+			Expect(err).To(Equal(DiskSizeTooLargeError{SizeInGb: 20000}))
+			Expect(fc.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(0))
+		})
+
+		It("reports not resized when the iSCSI disk is not upgradable", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_getIscsiVolume.json")
+
+			resized, err := disk.Resize(context.Background(), 40*1024)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resized).To(BeFalse())
+		})
+
+		It("reports not resized when shrinking the iSCSI disk", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_getIscsiVolume_Upgraded.json")
+
+			resized, err := disk.Resize(context.Background(), 20*1024)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resized).To(BeFalse())
+		})
+
+		It("reports error when the upgraded capacity never shows up", func() {
+			fileNames := []string{
+				"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgradable.json",
+				"SoftLayer_Product_Package_Service_getStorageAsAServiceItemPrices.json",
+				"SoftLayer_Product_Order_Service_placeOrder.json",
+				"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgradable.json",
+			}
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fc, fileNames)
+
+			logger := boshlog.NewLogger(boshlog.LevelNone)
+			disk = NewSoftLayerDisk(1234, fc, bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(0, 1*time.Second)), logger)
+
+			_, err := disk.Resize(context.Background(), 40*1024)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("timed out"))
+		})
+
+		It("stops waiting for the upgraded capacity when the context is cancelled", func() {
+			fileNames := []string{
+				"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgradable.json",
+				"SoftLayer_Product_Package_Service_getStorageAsAServiceItemPrices.json",
+				"SoftLayer_Product_Order_Service_placeOrder.json",
+				"SoftLayer_Network_Storage_Service_getIscsiVolume_Upgradable.json",
+			}
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fc, fileNames)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := disk.Resize(ctx, 40*1024)
+			cloudErr, found := bslcapi.FindCloudError(err)
+			Expect(found).To(BeTrue())
+			Expect(cloudErr).To(Equal(bslcapi.NewCancelledError(context.Canceled)))
+		})
+	})
 })
@@ -1,22 +1,23 @@
 package disk
 
 import (
-	"strings"
-
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	slc "github.com/maximilien/softlayer-go/softlayer"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 )
 
 const SOFTLAYER_DISK_FINDER_LOG_TAG = "SoftLayerDiskFinder"
 
 type SoftLayerFinder struct {
 	softLayerClient slc.Client
+	waitPolicies    bslcommon.WaitPolicies
 	logger          boshlog.Logger
 }
 
-func NewSoftLayerDiskFinder(client slc.Client, logger boshlog.Logger) SoftLayerFinder {
-	return SoftLayerFinder{softLayerClient: client, logger: logger}
+func NewSoftLayerDiskFinder(client slc.Client, waitPolicies bslcommon.WaitPolicies, logger boshlog.Logger) SoftLayerFinder {
+	return SoftLayerFinder{softLayerClient: client, waitPolicies: waitPolicies, logger: logger}
 }
 
 func (f SoftLayerFinder) Find(id int) (Disk, bool, error) {
@@ -29,7 +30,7 @@ func (f SoftLayerFinder) Find(id int) (Disk, bool, error) {
 
 	disk, err := service.GetNetworkStorage(id)
 	if err != nil {
-		if !strings.Contains(err.Error(), "HTTP error code") {
+		if !bslcommon.IsSoftLayerNotFoundError(err) {
 			return nil, false, bosherr.WrapErrorf(err, "Failed to find iSCSI volume with id: %d", id)
 		}
 	}
@@ -38,7 +39,7 @@ func (f SoftLayerFinder) Find(id int) (Disk, bool, error) {
 		return nil, false, nil
 	}
 
-	result := NewSoftLayerDisk(id, f.softLayerClient, f.logger)
+	result := NewSoftLayerDisk(id, f.softLayerClient, f.waitPolicies, f.logger)
 
 	return result, true, nil
 }
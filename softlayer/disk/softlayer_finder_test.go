@@ -3,6 +3,7 @@ package disk_test
 import (
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	testhelpers "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
 
 	fakeclient "github.com/maximilien/softlayer-go/client/fakes"
@@ -23,7 +24,7 @@ var _ = Describe("SoftLayerFinder", func() {
 	BeforeEach(func() {
 		fc = fakeclient.NewFakeSoftLayerClient("fake-user", "fake-key")
 		logger = boshlog.NewLogger(boshlog.LevelNone)
-		finder = NewSoftLayerDiskFinder(fc, logger)
+		finder = NewSoftLayerDiskFinder(fc, bslcommon.DefaultWaitPolicies(), logger)
 	})
 
 	Describe("Find", func() {
@@ -34,7 +35,7 @@ var _ = Describe("SoftLayerFinder", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(found).To(BeTrue())
 
-			expectedDisk := NewSoftLayerDisk(1234, fc, logger)
+			expectedDisk := NewSoftLayerDisk(1234, fc, bslcommon.DefaultWaitPolicies(), logger)
 			Expect(disk).To(Equal(expectedDisk))
 		})
 
@@ -46,5 +47,25 @@ var _ = Describe("SoftLayerFinder", func() {
 			Expect(found).To(BeFalse())
 			Expect(disk).To(BeNil())
 		})
+
+		It("returns found as false when SoftLayer reports the disk does not exist", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_getEmptyIscsiVolume.json")
+			fc.FakeHttpClient.DoRawHttpRequestError = bslcommon.NewSoftLayerHttpError(404, "fake-not-found")
+
+			disk, found, err := finder.Find(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+			Expect(disk).To(BeNil())
+		})
+
+		It("returns error when SoftLayer fails with another HTTP error code", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fc, "SoftLayer_Network_Storage_Service_getEmptyIscsiVolume.json")
+			fc.FakeHttpClient.DoRawHttpRequestInt = 500
+
+			disk, found, err := finder.Find(1234)
+			Expect(err).To(HaveOccurred())
+			Expect(found).To(BeFalse())
+			Expect(disk).To(BeNil())
+		})
 	})
 })
@@ -0,0 +1,53 @@
+package disk
+
+import (
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	slc "github.com/maximilien/softlayer-go/softlayer"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+const SOFTLAYER_SNAPSHOT_FINDER_LOG_TAG = "SoftLayerSnapshotFinder"
+
+// Network storages created by createSnapshot carry this nasType, volumes do not
+const SOFTLAYER_SNAPSHOT_NAS_TYPE = "SNAPSHOT"
+
+type SoftLayerSnapshotFinder struct {
+	softLayerClient slc.Client
+	logger          boshlog.Logger
+}
+
+func NewSoftLayerSnapshotFinder(client slc.Client, logger boshlog.Logger) SoftLayerSnapshotFinder {
+	return SoftLayerSnapshotFinder{softLayerClient: client, logger: logger}
+}
+
+func (f SoftLayerSnapshotFinder) Find(id int) (Snapshot, bool, error) {
+	f.logger.Debug(SOFTLAYER_SNAPSHOT_FINDER_LOG_TAG, "Finding snapshot '%d'", id)
+
+	service, err := f.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	if err != nil {
+		return nil, false, bosherr.WrapError(err, "Cannot get network storage service.")
+	}
+
+	snapshot, err := service.GetNetworkStorage(id)
+	if err != nil {
+		if !bslcommon.IsSoftLayerNotFoundError(err) {
+			return nil, false, bosherr.WrapErrorf(err, "Failed to find snapshot with id: %d", id)
+		}
+	}
+
+	if snapshot.Id == 0 {
+		return nil, false, nil
+	}
+
+	if !strings.EqualFold(snapshot.NasType, SOFTLAYER_SNAPSHOT_NAS_TYPE) {
+		f.logger.Debug(SOFTLAYER_SNAPSHOT_FINDER_LOG_TAG, "Network storage '%d' has nasType '%s' and is not a snapshot", id, snapshot.NasType)
+		return nil, false, nil
+	}
+
+	return NewSoftLayerSnapshot(id, f.softLayerClient, f.logger), true, nil
+}
@@ -1,5 +1,9 @@
 package fakes
 
+import (
+	"context"
+)
+
 type FakeStemcell struct {
 	id   int
 	uuid string
@@ -19,7 +23,7 @@ func (s FakeStemcell) ID() int { return s.id }
 
 func (s FakeStemcell) Uuid() string { return s.uuid }
 
-func (s *FakeStemcell) Delete() error {
+func (s *FakeStemcell) Delete(ctx context.Context) error {
 	s.DeleteCalled = true
 	return s.DeleteErr
 }
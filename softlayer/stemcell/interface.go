@@ -1,5 +1,9 @@
 package stemcell
 
+import (
+	"context"
+)
+
 type Finder interface {
 	FindById(id int) (Stemcell, error)
 }
@@ -8,5 +12,5 @@ type Stemcell interface {
 	ID() int
 	Uuid() string
 
-	Delete() error
+	Delete(ctx context.Context) error
 }
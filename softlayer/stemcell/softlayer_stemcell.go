@@ -1,6 +1,8 @@
 package stemcell
 
 import (
+	"context"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
@@ -10,7 +12,6 @@ import (
 	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 
 	"fmt"
-	"time"
 )
 
 type SoftLayerStemcell struct {
@@ -20,13 +21,11 @@ type SoftLayerStemcell struct {
 	softLayerFinder SoftLayerFinder
 }
 
-func NewSoftLayerStemcell(id int, uuid string, softLayerClient sl.Client, logger boshlog.Logger) SoftLayerStemcell {
-	bslcommon.TIMEOUT = 60 * time.Minute
-	bslcommon.POLLING_INTERVAL = 10 * time.Second
-
+func NewSoftLayerStemcell(id int, uuid string, softLayerClient sl.Client, waitPolicies bslcommon.WaitPolicies, logger boshlog.Logger) SoftLayerStemcell {
 	softLayerFinder := SoftLayerFinder{
-		client: softLayerClient,
-		logger: logger,
+		client:       softLayerClient,
+		waitPolicies: waitPolicies,
+		logger:       logger,
 	}
 
 	return SoftLayerStemcell{
@@ -40,7 +39,7 @@ func (s SoftLayerStemcell) ID() int { return s.id }
 
 func (s SoftLayerStemcell) Uuid() string { return s.uuid }
 
-func (s SoftLayerStemcell) Delete() error {
+func (s SoftLayerStemcell) Delete(ctx context.Context) error {
 	vgdtgService, err := s.softLayerFinder.client.GetSoftLayer_Virtual_Guest_Block_Device_Template_Group_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Getting SoftLayer_Virtual_Guest_Block_Device_Template_Group_Service from SoftLayer client")
@@ -51,7 +50,7 @@ func (s SoftLayerStemcell) Delete() error {
 		return bosherr.WrapError(err, "Deleting VirtualGuestBlockDeviceTemplateGroup from service")
 	}
 
-	err = slh.WaitForVirtualGuestToHaveNoRunningTransactions(s.softLayerFinder.client, s.id)
+	err = slh.WaitForVirtualGuestToHaveNoRunningTransactions(ctx, s.softLayerFinder.client, s.id, s.softLayerFinder.waitPolicies.Delete)
 	if err != nil {
 		return bosherr.WrapError(err, fmt.Sprintf("Waiting for VirtualGuest `%d` to have no pending transactions", s.id))
 	}
@@ -1,12 +1,14 @@
 package stemcell_test
 
 import (
+	"context"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"time"
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
@@ -26,10 +28,7 @@ var _ = Describe("SoftLayerStemcell", func() {
 
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 
-		stemcell = NewSoftLayerStemcell(1234, "fake-stemcell-uuid", fakeSoftLayerClient, logger)
-
-		bslcommon.TIMEOUT = 10 * time.Millisecond
-		bslcommon.POLLING_INTERVAL = 2 * time.Millisecond
+		stemcell = NewSoftLayerStemcell(1234, "fake-stemcell-uuid", fakeSoftLayerClient, bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(10*time.Millisecond, 2*time.Millisecond)), logger)
 	})
 
 	Describe("#Delete", func() {
@@ -51,18 +50,32 @@ var _ = Describe("SoftLayerStemcell", func() {
 
 		Context("when stemcell exists", func() {
 			It("deletes the stemcell in collection directory that contains unpacked stemcell", func() {
-				err := stemcell.Delete()
+				err := stemcell.Delete(context.Background())
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
 
+		Context("when the context is cancelled while waiting for transactions", func() {
+			It("stops waiting and returns a cancelled CPI error", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				err := stemcell.Delete(ctx)
+				Expect(err).To(HaveOccurred())
+
+				cloudErr, found := bslcapi.FindCloudError(err)
+				Expect(found).To(BeTrue())
+				Expect(cloudErr).To(Equal(bslcapi.NewCancelledError(context.Canceled)))
+			})
+		})
+
 		Context("when stemcell does not exist", func() {
 			BeforeEach(func() {
 				fakeSoftLayerClient.FakeHttpClient.DoRawHttpRequestInt = 404
 			})
 
 			It("returns error if deleting stemcell does not exist", func() {
-				err := stemcell.Delete()
+				err := stemcell.Delete(context.Background())
 				Expect(err).To(HaveOccurred())
 			})
 		})
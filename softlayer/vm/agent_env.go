@@ -33,7 +33,24 @@ type DisksSpec struct {
 	Persistent PersistentSpec `json:"persistent"`
 }
 
-type PersistentSpec map[string]string
+// PersistentSpec maps disk ids to either a device path or the
+// PersistentDiskSettings the agent uses to find the disk by itself
+type PersistentSpec map[string]interface{}
+
+type PersistentDiskSettings struct {
+	ID       string `json:"id"`
+	VolumeID string `json:"volume_id"`
+	Lun      string `json:"lun"`
+
+	ISCSISettings ISCSISettings `json:"iscsi_settings"`
+}
+
+type ISCSISettings struct {
+	InitiatorName string `json:"initiator_name"`
+	Target        string `json:"target"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+}
 
 type EnvSpec map[string]interface{}
 
@@ -109,6 +126,14 @@ func NewAgentEnvForVM(agentID, vmCID string, networks Networks, disksSpec DisksS
 }
 
 func (ae AgentEnv) AttachPersistentDisk(diskID, path string) AgentEnv {
+	return ae.attachPersistentDisk(diskID, path)
+}
+
+func (ae AgentEnv) AttachPersistentDiskSettings(diskID string, settings PersistentDiskSettings) AgentEnv {
+	return ae.attachPersistentDisk(diskID, settings)
+}
+
+func (ae AgentEnv) attachPersistentDisk(diskID string, hint interface{}) AgentEnv {
 	spec := PersistentSpec{}
 
 	if ae.Disks.Persistent != nil {
@@ -117,7 +142,7 @@ func (ae AgentEnv) AttachPersistentDisk(diskID, path string) AgentEnv {
 		}
 	}
 
-	spec[diskID] = path
+	spec[diskID] = hint
 
 	ae.Disks.Persistent = spec
 
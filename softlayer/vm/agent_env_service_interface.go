@@ -1,7 +1,11 @@
 package vm
 
+import (
+	"context"
+)
+
 type AgentEnvService interface {
 	// Fetch will return an error if Update was not called beforehand
 	Fetch() (AgentEnv, error)
-	Update(AgentEnv) error
+	Update(context.Context, AgentEnv) error
 }
@@ -88,6 +88,54 @@ var _ = Describe("AgentEnv", func() {
 		})
 	})
 
+	Describe("AttachPersistentDiskSettings", func() {
+		It("sets iSCSI settings for given disk id next to plain disk paths", func() {
+			agentEnv := AgentEnv{
+				Disks: DisksSpec{
+					Persistent: PersistentSpec{
+						"fake-other-disk-id": "fake-other-disk-path",
+					},
+				},
+			}
+
+			settings := PersistentDiskSettings{
+				ID:       "fake-disk-id",
+				VolumeID: "fake-disk-id",
+				Lun:      "0",
+				ISCSISettings: ISCSISettings{
+					InitiatorName: "fake-iqn",
+					Target:        "fake-target",
+					Username:      "fake-username",
+					Password:      "fake-password",
+				},
+			}
+
+			newAgentEnv := agentEnv.AttachPersistentDiskSettings("fake-disk-id", settings)
+
+			Expect(newAgentEnv.Disks.Persistent).To(Equal(PersistentSpec{
+				"fake-other-disk-id": "fake-other-disk-path",
+				"fake-disk-id":       settings,
+			}))
+
+			bytes, err := json.Marshal(newAgentEnv.Disks.Persistent)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bytes).To(MatchJSON(`{
+				"fake-other-disk-id": "fake-other-disk-path",
+				"fake-disk-id": {
+					"id": "fake-disk-id",
+					"volume_id": "fake-disk-id",
+					"lun": "0",
+					"iscsi_settings": {
+						"initiator_name": "fake-iqn",
+						"target": "fake-target",
+						"username": "fake-username",
+						"password": "fake-password"
+					}
+				}
+			}`))
+		})
+	})
+
 	Describe("DetachPersistentDisk", func() {
 		It("unsets persistent disk path if previously set", func() {
 			agentEnv := AgentEnv{
@@ -0,0 +1,242 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	slcommon "github.com/maximilien/softlayer-go/common"
+	sl "github.com/maximilien/softlayer-go/softlayer"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+type softLayerCreateObjectOptions struct {
+	Datacenters       []softLayerDatacenterOption       `json:"datacenters"`
+	Memory            []softLayerMemoryOption           `json:"memory"`
+	Processors        []softLayerProcessorOption        `json:"processors"`
+	NetworkComponents []softLayerNetworkComponentOption `json:"networkComponents"`
+	BlockDevices      []softLayerBlockDeviceOption      `json:"blockDevices"`
+}
+
+type softLayerDatacenterOption struct {
+	Template struct {
+		Datacenter struct {
+			Name string `json:"name"`
+		} `json:"datacenter"`
+	} `json:"template"`
+}
+
+type softLayerMemoryOption struct {
+	Template struct {
+		MaxMemory int `json:"maxMemory"`
+	} `json:"template"`
+}
+
+type softLayerProcessorOption struct {
+	Template struct {
+		StartCpus                    int  `json:"startCpus"`
+		DedicatedAccountHostOnlyFlag bool `json:"dedicatedAccountHostOnlyFlag"`
+	} `json:"template"`
+}
+
+type softLayerNetworkComponentOption struct {
+	Template struct {
+		NetworkComponents []struct {
+			MaxSpeed int `json:"maxSpeed"`
+		} `json:"networkComponents"`
+	} `json:"template"`
+}
+
+type softLayerBlockDeviceOption struct {
+	Template struct {
+		BlockDevices []struct {
+			Device    string `json:"device"`
+			DiskImage struct {
+				Capacity int `json:"capacity"`
+			} `json:"diskImage"`
+		} `json:"blockDevices"`
+		LocalDiskFlag bool `json:"localDiskFlag"`
+	} `json:"template"`
+}
+
+// ValidateCloudProperties checks cloudProps against the options SoftLayer
+// offers for new virtual guests, so that a bad datacenter, flavor, port
+// speed or disk is reported up front instead of failing deep inside
+// createObject. All problems are returned together as an
+// InvalidCloudPropertiesError. Product orders reach items createObject does
+// not offer and are checked against the package items instead, see
+// PlaceVirtualGuestOrder.
+func ValidateCloudProperties(softLayerClient sl.Client, cloudProps VMCloudProperties) error {
+	options, err := getCreateObjectOptions(softLayerClient)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting virtual guest create options")
+	}
+
+	problems := []string{}
+
+	if cloudProps.Domain == "" {
+		problems = append(problems, "domain must be set")
+	}
+
+	if cloudProps.Datacenter.Name == "" {
+		problems = append(problems, "datacenter name must be set")
+	} else {
+		names := []string{}
+		for _, option := range options.Datacenters {
+			names = append(names, option.Template.Datacenter.Name)
+		}
+		if !containsString(names, cloudProps.Datacenter.Name) {
+			problems = append(problems, fmt.Sprintf("datacenter '%s' is not available (available: %s)", cloudProps.Datacenter.Name, joinStrings(names)))
+		}
+	}
+
+	cpus := []int{}
+	for _, option := range options.Processors {
+		if option.Template.DedicatedAccountHostOnlyFlag == cloudProps.DedicatedAccountHostOnlyFlag {
+			cpus = append(cpus, option.Template.StartCpus)
+		}
+	}
+	if !containsInt(cpus, cloudProps.StartCpus) {
+		problems = append(problems, fmt.Sprintf("startCpus %d is not offered%s (available: %s)", cloudProps.StartCpus, dedicatedSuffix(cloudProps.DedicatedAccountHostOnlyFlag), joinInts(cpus)))
+	}
+
+	memory := []int{}
+	for _, option := range options.Memory {
+		memory = append(memory, option.Template.MaxMemory)
+	}
+	if !containsInt(memory, cloudProps.MaxMemory) {
+		problems = append(problems, fmt.Sprintf("maxMemory %d is not offered (available: %s)", cloudProps.MaxMemory, joinInts(memory)))
+	}
+
+	speeds := []int{}
+	for _, option := range options.NetworkComponents {
+		for _, component := range option.Template.NetworkComponents {
+			speeds = append(speeds, component.MaxSpeed)
+		}
+	}
+	for _, component := range cloudProps.NetworkComponents {
+		if component.MaxSpeed > 0 && !containsInt(speeds, component.MaxSpeed) {
+			problems = append(problems, fmt.Sprintf("network component maxSpeed %d is not offered (available: %s)", component.MaxSpeed, joinInts(speeds)))
+		}
+	}
+
+	if cloudProps.RootDiskSize > 0 {
+		sizes := options.blockDeviceSizes("0", cloudProps.LocalDiskFlag)
+		if !containsInt(sizes, cloudProps.RootDiskSize) {
+			problems = append(problems, fmt.Sprintf("rootDiskSize %d is not offered for %s disks (available: %s)", cloudProps.RootDiskSize, diskKind(cloudProps.LocalDiskFlag), joinInts(sizes)))
+		}
+	}
+
+	for _, blockDevice := range cloudProps.BlockDevices {
+		sizes := options.blockDeviceSizes(blockDevice.Device, cloudProps.LocalDiskFlag)
+		if len(sizes) == 0 {
+			problems = append(problems, fmt.Sprintf("block device '%s' is not offered for %s disks", blockDevice.Device, diskKind(cloudProps.LocalDiskFlag)))
+		} else if !containsInt(sizes, blockDevice.DiskImage.Capacity) {
+			problems = append(problems, fmt.Sprintf("block device '%s' capacity %d is not offered for %s disks (available: %s)", blockDevice.Device, blockDevice.DiskImage.Capacity, diskKind(cloudProps.LocalDiskFlag), joinInts(sizes)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return InvalidCloudPropertiesError{Problems: problems}
+	}
+
+	return nil
+}
+
+// Private methods
+func (o softLayerCreateObjectOptions) blockDeviceSizes(device string, local bool) []int {
+	sizes := []int{}
+	for _, option := range o.BlockDevices {
+		if option.Template.LocalDiskFlag != local {
+			continue
+		}
+		for _, blockDevice := range option.Template.BlockDevices {
+			if blockDevice.Device == device {
+				sizes = append(sizes, blockDevice.DiskImage.Capacity)
+			}
+		}
+	}
+
+	return sizes
+}
+
+func getCreateObjectOptions(softLayerClient sl.Client) (softLayerCreateObjectOptions, error) {
+	options := softLayerCreateObjectOptions{}
+
+	response, errorCode, err := softLayerClient.GetHttpClient().DoRawHttpRequest("SoftLayer_Virtual_Guest/getCreateObjectOptions.json", "GET", new(bytes.Buffer))
+	if err != nil {
+		return options, err
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return options, bslcommon.NewSoftLayerHttpError(errorCode, "Calling SoftLayer_Virtual_Guest#getCreateObjectOptions")
+	}
+
+	err = json.Unmarshal(response, &options)
+	if err != nil {
+		return options, bosherr.WrapError(err, "Unmarshaling virtual guest create options")
+	}
+
+	return options, nil
+}
+
+func dedicatedSuffix(dedicated bool) string {
+	if dedicated {
+		return " on dedicated hosts"
+	}
+	return ""
+}
+
+func diskKind(local bool) string {
+	if local {
+		return "local"
+	}
+	return "SAN"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func joinStrings(values []string) string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+
+	return strings.Join(sorted, ", ")
+}
+
+func joinInts(values []int) string {
+	unique := []int{}
+	for _, v := range values {
+		if !containsInt(unique, v) {
+			unique = append(unique, v)
+		}
+	}
+	sort.Ints(unique)
+
+	joined := []string{}
+	for _, v := range unique {
+		joined = append(joined, strconv.Itoa(v))
+	}
+	return strings.Join(joined, ", ")
+}
@@ -0,0 +1,101 @@
+package vm_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+
+	testhelpers "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
+
+	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
+	sldatatypes "github.com/maximilien/softlayer-go/data_types"
+)
+
+var _ = Describe("ValidateCloudProperties", func() {
+	var (
+		softLayerClient *fakeslclient.FakeSoftLayerClient
+		cloudProps      VMCloudProperties
+	)
+
+	BeforeEach(func() {
+		softLayerClient = fakeslclient.NewFakeSoftLayerClient("fake-username", "fake-api-key")
+		testhelpers.SetTestFixtureForFakeSoftLayerClient(softLayerClient, "SoftLayer_Virtual_Guest_Service_getCreateObjectOptions.json")
+
+		cloudProps = VMCloudProperties{
+			StartCpus:         4,
+			MaxMemory:         2048,
+			Domain:            "fake-domain.com",
+			Datacenter:        sldatatypes.Datacenter{Name: "fake-datacenter"},
+			RootDiskSize:      100,
+			NetworkComponents: []sldatatypes.NetworkComponents{{MaxSpeed: 1000}},
+		}
+	})
+
+	It("accepts properties SoftLayer offers", func() {
+		err := ValidateCloudProperties(softLayerClient, cloudProps)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Virtual_Guest/getCreateObjectOptions.json"))
+	})
+
+	It("reports every problem at once", func() {
+		cloudProps.Domain = ""
+		cloudProps.Datacenter.Name = "fake-unknown-datacenter"
+		cloudProps.MaxMemory = 3000
+		cloudProps.RootDiskSize = 50
+		cloudProps.NetworkComponents = []sldatatypes.NetworkComponents{{MaxSpeed: 10000}}
+
+		err := ValidateCloudProperties(softLayerClient, cloudProps)
+		Expect(err).To(HaveOccurred())
+
+		invalidErr, ok := err.(InvalidCloudPropertiesError)
+		Expect(ok).To(BeTrue())
+		Expect(invalidErr.Problems).To(Equal([]string{
+			"domain must be set",
+			"datacenter 'fake-unknown-datacenter' is not available (available: ams01, dal05, fake-datacenter)",
+			"maxMemory 3000 is not offered (available: 1024, 2048, 4096, 8192, 16384)",
+			"network component maxSpeed 10000 is not offered (available: 10, 100, 1000)",
+			"rootDiskSize 50 is not offered for SAN disks (available: 25, 100)",
+		}))
+
+		cloudErr, found := bslcapi.FindCloudError(err)
+		Expect(found).To(BeTrue())
+		Expect(cloudErr.Type()).To(Equal("Bosh::Clouds::CloudError"))
+	})
+
+	It("checks every block device against the offered capacities", func() {
+		cloudProps.BlockDevices = []sldatatypes.BlockDevice{
+			{Device: "2", DiskImage: sldatatypes.DiskImage{Capacity: 100}},
+			{Device: "3", DiskImage: sldatatypes.DiskImage{Capacity: 100}},
+			{Device: "2", DiskImage: sldatatypes.DiskImage{Capacity: 50}},
+		}
+
+		err := ValidateCloudProperties(softLayerClient, cloudProps)
+		Expect(err).To(HaveOccurred())
+
+		invalidErr, ok := err.(InvalidCloudPropertiesError)
+		Expect(ok).To(BeTrue())
+		Expect(invalidErr.Problems).To(Equal([]string{
+			"block device '3' is not offered for SAN disks",
+			"block device '2' capacity 50 is not offered for SAN disks (available: 25, 100)",
+		}))
+	})
+
+	It("only offers dedicated core counts for dedicated hosts", func() {
+		cloudProps.StartCpus = 8
+		cloudProps.DedicatedAccountHostOnlyFlag = true
+
+		err := ValidateCloudProperties(softLayerClient, cloudProps)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Invalid cloud properties: startCpus 8 is not offered on dedicated hosts (available: 1, 2, 4)"))
+	})
+
+	It("returns error if the create options cannot be fetched", func() {
+		softLayerClient.FakeHttpClient.DoRawHttpRequestInt = 500
+
+		err := ValidateCloudProperties(softLayerClient, cloudProps)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Getting virtual guest create options"))
+	})
+})
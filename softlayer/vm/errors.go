@@ -1,6 +1,23 @@
 package vm
 
+import (
+	"fmt"
+	"strings"
+)
+
 type NotSupportedError struct{}
 
 func (e NotSupportedError) Type() string  { return "Bosh::Clouds::NotSupported" }
 func (e NotSupportedError) Error() string { return "Not supported" }
+
+// InvalidCloudPropertiesError lists every cloud property SoftLayer would
+// reject when creating a virtual guest
+type InvalidCloudPropertiesError struct {
+	Problems []string
+}
+
+func (e InvalidCloudPropertiesError) Type() string   { return "Bosh::Clouds::CloudError" }
+func (e InvalidCloudPropertiesError) CanRetry() bool { return false }
+func (e InvalidCloudPropertiesError) Error() string {
+	return fmt.Sprintf("Invalid cloud properties: %s", strings.Join(e.Problems, "; "))
+}
@@ -1,6 +1,8 @@
 package fakes
 
 import (
+	"context"
+
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 )
 
@@ -22,7 +24,7 @@ func (s *FakeAgentEnvService) Fetch() (bslcvm.AgentEnv, error) {
 	return s.FetchAgentEnv, s.FetchErr
 }
 
-func (s *FakeAgentEnvService) Update(agentEnv bslcvm.AgentEnv) error {
+func (s *FakeAgentEnvService) Update(ctx context.Context, agentEnv bslcvm.AgentEnv) error {
 	s.UpdateAgentEnv = agentEnv
 	return s.UpdateErr
 }
@@ -1,11 +1,14 @@
 package fakes
 
 import (
+	"context"
+
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 )
 
 type FakeCreator struct {
+	CreateContext           context.Context
 	CreateAgentID           string
 	CreateStemcell          bslcstem.Stemcell
 	CreateNetworks          bslcvm.Networks
@@ -15,7 +18,8 @@ type FakeCreator struct {
 	CreateErr               error
 }
 
-func (c *FakeCreator) Create(agentID string, stemcell bslcstem.Stemcell, vmCloudProperties bslcvm.VMCloudProperties, networks bslcvm.Networks, env bslcvm.Environment) (bslcvm.VM, error) {
+func (c *FakeCreator) Create(ctx context.Context, agentID string, stemcell bslcstem.Stemcell, vmCloudProperties bslcvm.VMCloudProperties, networks bslcvm.Networks, env bslcvm.Environment) (bslcvm.VM, error) {
+	c.CreateContext = ctx
 	c.CreateAgentID = agentID
 	c.CreateStemcell = stemcell
 	c.CreateVMCloudProperties = vmCloudProperties
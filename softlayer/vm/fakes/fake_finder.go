@@ -9,9 +9,19 @@ type FakeFinder struct {
 	FindVM    bslcvm.VM
 	FindFound bool
 	FindErr   error
+
+	FindByPrimaryBackendIpIp    string
+	FindByPrimaryBackendIpVM    bslcvm.VM
+	FindByPrimaryBackendIpFound bool
+	FindByPrimaryBackendIpErr   error
 }
 
 func (f *FakeFinder) Find(id int) (bslcvm.VM, bool, error) {
 	f.FindID = id
 	return f.FindVM, f.FindFound, f.FindErr
 }
+
+func (f *FakeFinder) FindByPrimaryBackendIp(ip string) (bslcvm.VM, bool, error) {
+	f.FindByPrimaryBackendIpIp = ip
+	return f.FindByPrimaryBackendIpVM, f.FindByPrimaryBackendIpFound, f.FindByPrimaryBackendIpErr
+}
@@ -0,0 +1,54 @@
+package fakes
+
+import (
+	"fmt"
+
+	datatypes "github.com/maximilien/softlayer-go/data_types"
+)
+
+type FakeIscsiAccess struct {
+	IDValue int
+
+	HasAllowedVolumeId int
+	HasAllowedResult   bool
+	HasAllowedErr      error
+
+	AllowVolumeId  int
+	AllowCallCount int
+	AllowResult    bool
+	AllowErr       error
+
+	RevokeVolumeId int
+	RevokeCalled   bool
+	RevokeErr      error
+
+	GetAllowedHostResult datatypes.SoftLayer_Network_Storage_Allowed_Host
+	GetAllowedHostErr    error
+}
+
+func (a *FakeIscsiAccess) ID() int { return a.IDValue }
+
+func (a *FakeIscsiAccess) Name() string {
+	return fmt.Sprintf("fake server `%d`", a.IDValue)
+}
+
+func (a *FakeIscsiAccess) HasAllowed(volumeId int) (bool, error) {
+	a.HasAllowedVolumeId = volumeId
+	return a.HasAllowedResult, a.HasAllowedErr
+}
+
+func (a *FakeIscsiAccess) Allow(volumeId int) (bool, error) {
+	a.AllowVolumeId = volumeId
+	a.AllowCallCount++
+	return a.AllowResult, a.AllowErr
+}
+
+func (a *FakeIscsiAccess) Revoke(volumeId int) error {
+	a.RevokeVolumeId = volumeId
+	a.RevokeCalled = true
+	return a.RevokeErr
+}
+
+func (a *FakeIscsiAccess) GetAllowedHost() (datatypes.SoftLayer_Network_Storage_Allowed_Host, error) {
+	return a.GetAllowedHostResult, a.GetAllowedHostErr
+}
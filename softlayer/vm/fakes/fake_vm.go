@@ -1,6 +1,8 @@
 package fakes
 
 import (
+	"context"
+
 	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
 	bslcstemcell "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
 	bslcvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
@@ -9,8 +11,9 @@ import (
 type FakeVM struct {
 	id int
 
-	DeleteCalled bool
-	DeleteErr    error
+	DeleteCalled  bool
+	DeleteContext context.Context
+	DeleteErr     error
 
 	RebootCalled bool
 	RebootErr    error
@@ -39,7 +42,15 @@ type FakeVM struct {
 
 	SetAgentEnvServiceErr error
 
-	UpdateAgentEnvErr error
+	FetchAgentEnvAgentEnv bslcvm.AgentEnv
+	FetchAgentEnvErr      error
+
+	GetAttachedDiskIdsDiskIds []int
+	GetAttachedDiskIdsErr     error
+
+	UpdateAgentEnvCalled   bool
+	UpdateAgentEnvAgentEnv bslcvm.AgentEnv
+	UpdateAgentEnvErr      error
 }
 
 func NewFakeVM(id int) *FakeVM {
@@ -48,8 +59,9 @@ func NewFakeVM(id int) *FakeVM {
 
 func (vm *FakeVM) ID() int { return vm.id }
 
-func (vm *FakeVM) Delete(agentID string) error {
+func (vm *FakeVM) Delete(ctx context.Context, agentID string) error {
 	vm.DeleteCalled = true
+	vm.DeleteContext = ctx
 	return vm.DeleteErr
 }
 
@@ -64,33 +76,37 @@ func (vm *FakeVM) SetMetadata(metadata bslcvm.VMMetadata) error {
 	return vm.SetMetadataErr
 }
 
-func (vm *FakeVM) ConfigureNetworks(networks bslcvm.Networks) error {
+func (vm *FakeVM) ConfigureNetworks(ctx context.Context, networks bslcvm.Networks) error {
 	vm.ConfigureNetworksCalled = true
 	vm.Networks = networks
 	return vm.ConfigureNetworksErr
 }
 
-func (vm *FakeVM) AttachDisk(disk bslcdisk.Disk) error {
+func (vm *FakeVM) AttachDisk(ctx context.Context, disk bslcdisk.Disk) error {
 	vm.AttachDiskDisk = disk
 	return vm.AttachDiskErr
 }
 
-func (vm *FakeVM) DetachDisk(disk bslcdisk.Disk) error {
+func (vm *FakeVM) DetachDisk(ctx context.Context, disk bslcdisk.Disk) error {
 	vm.DetachDiskDisk = disk
 	return vm.DetachDiskErr
 }
 
-func (vm *FakeVM) ReloadOS(stemcell bslcstemcell.Stemcell) error {
+func (vm *FakeVM) ReloadOS(ctx context.Context, stemcell bslcstemcell.Stemcell) error {
 	vm.ReloadOSStemcell = stemcell
 	return vm.ReloadOSErr
 }
 
-func (vm *FakeVM) ReloadOSForBaremetal(stemcell string, netbootImage string) error {
+func (vm *FakeVM) ReloadOSForBaremetal(ctx context.Context, stemcell string, netbootImage string) error {
 	vm.ReloadBaremetalStemcell = stemcell
 	vm.ReloadBaremetalNetBootImage = netbootImage
 	return vm.ReloadOSErr
 }
 
+func (vm *FakeVM) GetAttachedDiskIds() ([]int, error) {
+	return vm.GetAttachedDiskIdsDiskIds, vm.GetAttachedDiskIdsErr
+}
+
 func (vm *FakeVM) GetDataCenterId() int {
 	return 1234567
 }
@@ -119,6 +135,12 @@ func (vm *FakeVM) SetAgentEnvService(agentEnvService bslcvm.AgentEnvService) err
 	return vm.SetAgentEnvServiceErr
 }
 
-func (vm *FakeVM) UpdateAgentEnv(agentEnv bslcvm.AgentEnv) error {
+func (vm *FakeVM) FetchAgentEnv() (bslcvm.AgentEnv, error) {
+	return vm.FetchAgentEnvAgentEnv, vm.FetchAgentEnvErr
+}
+
+func (vm *FakeVM) UpdateAgentEnv(ctx context.Context, agentEnv bslcvm.AgentEnv) error {
+	vm.UpdateAgentEnvCalled = true
+	vm.UpdateAgentEnvAgentEnv = agentEnv
 	return vm.UpdateAgentEnvErr
 }
@@ -1,18 +1,20 @@
 package vm
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
-)
+	"github.com/pivotal-golang/clock"
 
-const (
-	maxAttempts = 30
-	delay       = 10
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 )
 
+// Uploads are retried for up to five minutes while the server boots
+var fsAgentEnvUpdatePolicy = bslcommon.NewWaitPolicy(5*time.Minute, 10*time.Second)
+
 type fsAgentEnvService struct {
 	vm                   VM
 	softlayerFileService SoftlayerFileService
@@ -53,7 +55,7 @@ func (s *fsAgentEnvService) Fetch() (AgentEnv, error) {
 	return agentEnv, nil
 }
 
-func (s *fsAgentEnvService) Update(agentEnv AgentEnv) error {
+func (s *fsAgentEnvService) Update(ctx context.Context, agentEnv AgentEnv) error {
 	s.logger.Debug(s.logTag, "Updating agent env: %#v", agentEnv)
 
 	jsonBytes, err := json.Marshal(agentEnv)
@@ -61,13 +63,21 @@ func (s *fsAgentEnvService) Update(agentEnv AgentEnv) error {
 		return bosherr.WrapError(err, "Marshalling agent env")
 	}
 
-	for i := 0; i < maxAttempts; i++ {
-		s.logger.Debug(s.logTag, "Updating Agent Env: Making attempt #%d", i)
-		err = s.softlayerFileService.Upload(ROOT_USER_NAME, s.vm.GetRootPassword(), s.vm.GetPrimaryBackendIP(), s.settingsPath, jsonBytes)
-		if err == nil {
-			return nil
-		}
-		time.Sleep(delay * time.Second)
+	var uploadErr error
+	attempt := 0
+	uploaded, err := bslcommon.NewPoller(fsAgentEnvUpdatePolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
+		attempt++
+		s.logger.Debug(s.logTag, "Updating Agent Env: Making attempt #%d", attempt)
+		uploadErr = s.softlayerFileService.Upload(ROOT_USER_NAME, s.vm.GetRootPassword(), s.vm.GetPrimaryBackendIP(), s.settingsPath, jsonBytes)
+		return uploadErr == nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !uploaded {
+		return bosherr.WrapError(uploadErr, "Updating Agent Env timeout")
 	}
-	return bosherr.WrapError(err, "Updating Agent Env timeout")
+
+	return nil
 }
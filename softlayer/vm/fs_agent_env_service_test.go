@@ -1,9 +1,11 @@
 package vm_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	fakebslvm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
@@ -87,10 +89,23 @@ var _ = Describe("SoftlayerAgentEnvService", func() {
 		})
 
 		It("uploads file contents to the warden container", func() {
-			err := agentEnvService.Update(newAgentEnv)
+			err := agentEnvService.Update(context.Background(), newAgentEnv)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(fakeSoftlayerFileService.UploadInputs[0].Contents).To(Equal(expectedAgentEnvBytes))
 		})
 
+		It("stops retrying the upload when the context is cancelled", func() {
+			fakeSoftlayerFileService.UploadErr = errors.New("fake-upload-error")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := agentEnvService.Update(ctx, newAgentEnv)
+			cloudErr, found := bslcapi.FindCloudError(err)
+			Expect(found).To(BeTrue())
+			Expect(cloudErr).To(Equal(bslcapi.NewCancelledError(context.Canceled)))
+			Expect(fakeSoftlayerFileService.UploadInputs).To(HaveLen(1))
+		})
+
 	})
 })
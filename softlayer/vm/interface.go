@@ -1,6 +1,9 @@
 package vm
 
 import (
+	"context"
+	"time"
+
 	bslcdisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk"
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
 
@@ -34,6 +37,15 @@ type VMCloudProperties struct {
 	Baremetal             bool   `json:"baremetal,omitempty"`
 	BaremetalStemcell     string `json:"bm_stemcell,omitempty"`
 	BaremetalNetbootImage string `json:"bm_netboot_image,omitempty"`
+
+	// Order the virtual guest through SoftLayer_Product_Order instead of createObject,
+	// adding the given item key names to the ones resolved from the properties above
+	UseProductOrder   bool     `json:"use_product_order,omitempty"`
+	ProductOrderItems []string `json:"product_order_items,omitempty"`
+
+	// Leave a server whose creation failed part way through running for
+	// debugging instead of deleting it
+	KeepFailedVM bool `json:"keep_failed_vm,omitempty"`
 }
 
 type AllowedHostCredential struct {
@@ -45,21 +57,25 @@ type AllowedHostCredential struct {
 type VMMetadata map[string]interface{}
 
 type VMCreator interface {
-	Create(string, bslcstem.Stemcell, VMCloudProperties, Networks, Environment) (VM, error)
+	Create(context.Context, string, bslcstem.Stemcell, VMCloudProperties, Networks, Environment) (VM, error)
 }
 
 type Finder interface {
 	Find(int) (VM, bool, error)
+	FindByPrimaryBackendIp(string) (VM, bool, error)
 }
 
 type VM interface {
-	AttachDisk(bslcdisk.Disk) error
+	AttachDisk(context.Context, bslcdisk.Disk) error
 
-	ConfigureNetworks(Networks) error
+	ConfigureNetworks(context.Context, Networks) error
 
-	DetachDisk(bslcdisk.Disk) error
-	Delete(agentId string) error
+	DetachDisk(context.Context, bslcdisk.Disk) error
+	Delete(ctx context.Context, agentId string) error
 
+	FetchAgentEnv() (AgentEnv, error)
+
+	GetAttachedDiskIds() ([]int, error)
 	GetDataCenterId() int
 	GetPrimaryIP() string
 	GetPrimaryBackendIP() string
@@ -69,14 +85,14 @@ type VM interface {
 	ID() int
 
 	Reboot() error
-	ReloadOS(bslcstem.Stemcell) error
-	ReloadOSForBaremetal(string, string) error
+	ReloadOS(context.Context, bslcstem.Stemcell) error
+	ReloadOSForBaremetal(context.Context, string, string) error
 
 	SetMetadata(VMMetadata) error
 	SetVcapPassword(string) error
 	SetAgentEnvService(AgentEnvService) error
 
-	UpdateAgentEnv(AgentEnv) error
+	UpdateAgentEnv(context.Context, AgentEnv) error
 }
 
 type Environment map[string]interface{}
@@ -112,4 +128,7 @@ const (
 	SOFTLAYER_VM_OS_RELOAD_TAG  = "OSReload"
 	SOFTLAYER_VM_LOG_TAG        = "SoftLayerVM"
 	ROOT_USER_NAME              = "root"
+
+	// Remote commands still running after this long are killed
+	REMOTE_COMMAND_TIMEOUT = 5 * time.Minute
 )
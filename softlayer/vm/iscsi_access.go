@@ -0,0 +1,131 @@
+package vm
+
+import (
+	"fmt"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	datatypes "github.com/maximilien/softlayer-go/data_types"
+	sl "github.com/maximilien/softlayer-go/softlayer"
+)
+
+// IscsiAccess grants and revokes a server's access to iSCSI volumes. Virtual
+// guests and hardware only differ in the SoftLayer calls used for this.
+type IscsiAccess interface {
+	ID() int
+	Name() string
+
+	HasAllowed(volumeId int) (bool, error)
+	Allow(volumeId int) (bool, error)
+	Revoke(volumeId int) error
+
+	GetAllowedHost() (datatypes.SoftLayer_Network_Storage_Allowed_Host, error)
+}
+
+type virtualGuestIscsiAccess struct {
+	virtualGuest    datatypes.SoftLayer_Virtual_Guest
+	softLayerClient sl.Client
+}
+
+func NewVirtualGuestIscsiAccess(virtualGuest datatypes.SoftLayer_Virtual_Guest, softLayerClient sl.Client) IscsiAccess {
+	return &virtualGuestIscsiAccess{
+		virtualGuest:    virtualGuest,
+		softLayerClient: softLayerClient,
+	}
+}
+
+func (a *virtualGuestIscsiAccess) ID() int { return a.virtualGuest.Id }
+
+func (a *virtualGuestIscsiAccess) Name() string {
+	return fmt.Sprintf("virtual guest `%d`", a.virtualGuest.Id)
+}
+
+func (a *virtualGuestIscsiAccess) HasAllowed(volumeId int) (bool, error) {
+	networkStorageService, err := a.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Cannot get network storage service.")
+	}
+
+	return networkStorageService.HasAllowedVirtualGuest(volumeId, a.virtualGuest.Id)
+}
+
+func (a *virtualGuestIscsiAccess) Allow(volumeId int) (bool, error) {
+	networkStorageService, err := a.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Cannot get network storage service.")
+	}
+
+	return networkStorageService.AttachNetworkStorageToVirtualGuest(a.virtualGuest, volumeId)
+}
+
+func (a *virtualGuestIscsiAccess) Revoke(volumeId int) error {
+	networkStorageService, err := a.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	if err != nil {
+		return bosherr.WrapError(err, "Cannot get network storage service.")
+	}
+
+	return networkStorageService.DetachNetworkStorageFromVirtualGuest(a.virtualGuest, volumeId)
+}
+
+func (a *virtualGuestIscsiAccess) GetAllowedHost() (datatypes.SoftLayer_Network_Storage_Allowed_Host, error) {
+	virtualGuestService, err := a.softLayerClient.GetSoftLayer_Virtual_Guest_Service()
+	if err != nil {
+		return datatypes.SoftLayer_Network_Storage_Allowed_Host{}, bosherr.WrapError(err, "Cannot get softlayer virtual guest service.")
+	}
+
+	return virtualGuestService.GetAllowedHost(a.virtualGuest.Id)
+}
+
+type hardwareIscsiAccess struct {
+	hardware        datatypes.SoftLayer_Hardware
+	softLayerClient sl.Client
+}
+
+func NewHardwareIscsiAccess(hardware datatypes.SoftLayer_Hardware, softLayerClient sl.Client) IscsiAccess {
+	return &hardwareIscsiAccess{
+		hardware:        hardware,
+		softLayerClient: softLayerClient,
+	}
+}
+
+func (a *hardwareIscsiAccess) ID() int { return a.hardware.Id }
+
+func (a *hardwareIscsiAccess) Name() string {
+	return fmt.Sprintf("hardware `%d`", a.hardware.Id)
+}
+
+func (a *hardwareIscsiAccess) HasAllowed(volumeId int) (bool, error) {
+	networkStorageService, err := a.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Cannot get network storage service.")
+	}
+
+	return networkStorageService.HasAllowedHardware(volumeId, a.hardware.Id)
+}
+
+func (a *hardwareIscsiAccess) Allow(volumeId int) (bool, error) {
+	networkStorageService, err := a.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Cannot get network storage service.")
+	}
+
+	return networkStorageService.AttachNetworkStorageToHardware(a.hardware, volumeId)
+}
+
+func (a *hardwareIscsiAccess) Revoke(volumeId int) error {
+	networkStorageService, err := a.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	if err != nil {
+		return bosherr.WrapError(err, "Cannot get network storage service.")
+	}
+
+	return networkStorageService.DetachNetworkStorageFromHardware(a.hardware, volumeId)
+}
+
+func (a *hardwareIscsiAccess) GetAllowedHost() (datatypes.SoftLayer_Network_Storage_Allowed_Host, error) {
+	hardwareService, err := a.softLayerClient.GetSoftLayer_Hardware_Service()
+	if err != nil {
+		return datatypes.SoftLayer_Network_Storage_Allowed_Host{}, bosherr.WrapError(err, "Cannot get softlayer hardware service.")
+	}
+
+	return hardwareService.GetAllowedHost(a.hardware.Id)
+}
@@ -0,0 +1,650 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
+
+	datatypes "github.com/maximilien/softlayer-go/data_types"
+	sl "github.com/maximilien/softlayer-go/softlayer"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
+)
+
+const ISCSI_ATTACHER_LOG_TAG = "IscsiAttacher"
+
+const (
+	// The CPI logs the server in to iSCSI volumes over SSH and hands the
+	// agent a device path
+	DiskAttachModeSsh = "ssh"
+
+	// The CPI only grants access and hands the agent the iSCSI target, which
+	// the agent logs in to by itself
+	DiskAttachModeAgent = "agent"
+)
+
+func ValidateDiskAttachMode(mode string) error {
+	switch mode {
+	case "", DiskAttachModeSsh, DiskAttachModeAgent:
+		return nil
+	}
+
+	return bosherr.Errorf("Unknown disk attach mode '%s', must be '%s' or '%s'", mode, DiskAttachModeSsh, DiskAttachModeAgent)
+}
+
+// IscsiAttacher grants a server access to iSCSI volumes and logs it in to and
+// out of them over SSH
+type IscsiAttacher interface {
+	// GrantAccess grants the server access to the volume and returns what the
+	// agent needs to log in to it, without touching the server
+	GrantAccess(ctx context.Context, volumeId int) (PersistentDiskSettings, error)
+
+	// RevokeAccess revokes the server's access to the volume if it has any
+	RevokeAccess(volumeId int) error
+
+	// AttachVolume grants the server access to the volume, logs in to it and
+	// returns the device path of the disk that appears
+	AttachVolume(ctx context.Context, volumeId int) (string, error)
+
+	// DetachVolume logs the server out of all its iSCSI targets and revokes
+	// its access to the volume
+	DetachVolume(volumeId int) error
+
+	// ReattachVolume logs back in to a volume left attached by DetachVolume
+	// and mounts devicePath on /var/vcap/store again
+	ReattachVolume(volumeId int, devicePath string) error
+}
+
+type iscsiAttacher struct {
+	access IscsiAccess
+
+	softLayerClient sl.Client
+	sshClient       util.SshClient
+
+	password string
+	ip       string
+
+	waitPolicies bslcommon.WaitPolicies
+
+	logger boshlog.Logger
+}
+
+func NewIscsiAttacher(access IscsiAccess, softLayerClient sl.Client, sshClient util.SshClient, password string, ip string, waitPolicies bslcommon.WaitPolicies, logger boshlog.Logger) IscsiAttacher {
+	return &iscsiAttacher{
+		access: access,
+
+		softLayerClient: softLayerClient,
+		sshClient:       sshClient,
+
+		password: password,
+		ip:       ip,
+
+		waitPolicies: waitPolicies,
+
+		logger: logger,
+	}
+}
+
+// AttachDiskForAgent grants the server access to the disk and records its
+// iSCSI target in the agent settings, so that the agent logs in by itself
+func AttachDiskForAgent(ctx context.Context, iscsiAttacher IscsiAttacher, agentEnvService AgentEnvService, diskId int) error {
+	settings, err := iscsiAttacher.GrantAccess(ctx, diskId)
+	if err != nil {
+		return err
+	}
+
+	oldAgentEnv, err := agentEnvService.Fetch()
+	if err != nil {
+		return bosherr.WrapError(err, "Fetching agent env")
+	}
+
+	newAgentEnv := oldAgentEnv.AttachPersistentDiskSettings(strconv.Itoa(diskId), settings)
+	err = agentEnvService.Update(ctx, newAgentEnv)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Recording iSCSI target of disk `%d` in agent env", diskId)
+	}
+
+	return nil
+}
+
+// DetachDiskForAgent revokes the server's access to the disk and drops it
+// from the agent settings
+func DetachDiskForAgent(ctx context.Context, iscsiAttacher IscsiAttacher, agentEnvService AgentEnvService, diskId int) error {
+	err := iscsiAttacher.RevokeAccess(diskId)
+	if err != nil {
+		return err
+	}
+
+	oldAgentEnv, err := agentEnvService.Fetch()
+	if err != nil {
+		return bosherr.WrapError(err, "Fetching agent env")
+	}
+
+	newAgentEnv := oldAgentEnv.DetachPersistentDisk(strconv.Itoa(diskId))
+	err = agentEnvService.Update(ctx, newAgentEnv)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing disk `%d` from agent env", diskId)
+	}
+
+	return nil
+}
+
+func (a *iscsiAttacher) GrantAccess(ctx context.Context, volumeId int) (PersistentDiskSettings, error) {
+	volume, err := a.fetchIscsiVolume(volumeId)
+	if err != nil {
+		return PersistentDiskSettings{}, bosherr.WrapError(err, fmt.Sprintf("Failed to fetch disk `%d`", volumeId))
+	}
+
+	err = a.allowAccess(ctx, volumeId)
+	if err != nil {
+		return PersistentDiskSettings{}, err
+	}
+
+	credential, err := a.getAllowedHostCredential()
+	if err != nil {
+		return PersistentDiskSettings{}, bosherr.WrapError(err, fmt.Sprintf("Failed to get iscsi host auth from %s", a.access.Name()))
+	}
+
+	// Same fallback as the iscsid.conf template
+	username, password := credential.Username, credential.Password
+	if len(password) == 0 {
+		username, password = volume.Username, volume.Password
+	}
+
+	return PersistentDiskSettings{
+		ID:       strconv.Itoa(volumeId),
+		VolumeID: strconv.Itoa(volumeId),
+		Lun:      volume.LunId,
+
+		ISCSISettings: ISCSISettings{
+			InitiatorName: credential.Iqn,
+			Target:        volume.ServiceResourceBackendIpAddress,
+			Username:      username,
+			Password:      password,
+		},
+	}, nil
+}
+
+func (a *iscsiAttacher) RevokeAccess(volumeId int) error {
+	allowed, err := a.access.HasAllowed(volumeId)
+	if err == nil && allowed == true {
+		err = a.access.Revoke(volumeId)
+	}
+	if err != nil {
+		return bosherr.WrapError(err, fmt.Sprintf("Failed to revoke access of disk `%d` from %s", volumeId, a.access.Name()))
+	}
+
+	return nil
+}
+
+func (a *iscsiAttacher) AttachVolume(ctx context.Context, volumeId int) (string, error) {
+	volume, err := a.fetchIscsiVolume(volumeId)
+	if err != nil {
+		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to fetch disk `%d`", volumeId))
+	}
+
+	err = a.allowAccess(ctx, volumeId)
+	if err != nil {
+		return "", err
+	}
+
+	hasMultiPath, err := a.hasMulitPathToolBasedOnShellScript()
+	if err != nil {
+		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to get multipath information from %s", a.access.Name()))
+	}
+
+	deviceName, err := a.waitForVolumeAttached(ctx, volume, hasMultiPath)
+	if err != nil {
+		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to attach volume `%d` to %s", volumeId, a.access.Name()))
+	}
+
+	if hasMultiPath {
+		return "/dev/mapper/" + deviceName, nil
+	}
+
+	return "/dev/" + deviceName, nil
+}
+
+func (a *iscsiAttacher) DetachVolume(volumeId int) error {
+	volume, err := a.fetchIscsiVolume(volumeId)
+	if err != nil {
+		return bosherr.WrapError(err, fmt.Sprintf("failed in disk `%d`", volumeId))
+	}
+
+	hasMultiPath, err := a.hasMulitPathToolBasedOnShellScript()
+	if err != nil {
+		return bosherr.WrapError(err, fmt.Sprintf("Failed to get multipath information from %s", a.access.Name()))
+	}
+
+	err = a.detachVolumeBasedOnShellScript(hasMultiPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Failed to detach volume with id %d from %s", volume.Id, a.access.Name())
+	}
+
+	return a.RevokeAccess(volumeId)
+}
+
+func (a *iscsiAttacher) ReattachVolume(volumeId int, devicePath string) error {
+	a.logger.Debug(ISCSI_ATTACHER_LOG_TAG, "Left Disk Id %d", volumeId)
+	a.logger.Debug(ISCSI_ATTACHER_LOG_TAG, "Left Disk device path %s", devicePath)
+
+	volume, err := a.fetchIscsiVolume(volumeId)
+	if err != nil {
+		return bosherr.WrapError(err, fmt.Sprintf("Failed to fetch disk `%d` and %s", volumeId, a.access.Name()))
+	}
+
+	_, err = a.discoveryOpenIscsiTargetsBasedOnShellScript(volume)
+	if err != nil {
+		return bosherr.WrapError(err, fmt.Sprintf("Failed to reattach volume `%d` to %s", volumeId, a.access.Name()))
+	}
+
+	command := fmt.Sprintf("sleep 5; mount %s-part1 /var/vcap/store", devicePath)
+	_, err = a.runCommand(command)
+	if err != nil {
+		return bosherr.WrapError(err, "mount /var/vcap/store")
+	}
+
+	return nil
+}
+
+// Private methods
+func (a *iscsiAttacher) allowAccess(ctx context.Context, volumeId int) error {
+	allowed, err := a.access.HasAllowed(volumeId)
+	if err != nil || allowed {
+		return nil
+	}
+
+	allowed, err = bslcommon.NewPoller(a.waitPolicies.Attach, clock.NewClock()).Poll(ctx, func() (bool, error) {
+		allowable, err := a.access.Allow(volumeId)
+		if err != nil {
+			if !bslcommon.IsSoftLayerNotFoundError(err) && !bslcommon.IsSoftLayerTransientError(err) {
+				return false, bosherr.WrapError(err, fmt.Sprintf("Granting volume access to %s", a.access.Name()))
+			}
+			return false, nil
+		}
+
+		return allowable, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return bosherr.Errorf("Waiting for grantting access to %s TIME OUT!", a.access.Name())
+	}
+
+	return nil
+}
+
+func (a *iscsiAttacher) waitForVolumeAttached(ctx context.Context, volume datatypes.SoftLayer_Network_Storage, hasMultiPath bool) (string, error) {
+	oldDisks, err := a.getIscsiDeviceNamesBasedOnShellScript(hasMultiPath)
+	if err != nil {
+		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to get devices names from %s", a.access.Name()))
+	}
+	if len(oldDisks) > 2 {
+		return "", bosherr.Error(fmt.Sprintf("Too manay persistent disks attached to %s", a.access.Name()))
+	}
+
+	credential, err := a.getAllowedHostCredential()
+	if err != nil {
+		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to get iscsi host auth from %s", a.access.Name()))
+	}
+
+	_, err = a.backupOpenIscsiConfBasedOnShellScript()
+	if err != nil {
+		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to backup open iscsi conf files from %s", a.access.Name()))
+	}
+
+	_, err = a.writeOpenIscsiInitiatornameBasedOnShellScript(credential)
+	if err != nil {
+		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to write open iscsi initiatorname from %s", a.access.Name()))
+	}
+
+	_, err = a.writeOpenIscsiConfBasedOnShellScript(volume, credential)
+	if err != nil {
+		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to write open iscsi conf from %s", a.access.Name()))
+	}
+
+	_, err = a.restartOpenIscsiBasedOnShellScript()
+	if err != nil {
+		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to restart open iscsi from %s", a.access.Name()))
+	}
+
+	_, err = a.discoveryOpenIscsiTargetsBasedOnShellScript(volume)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Failed to attach volume with id %d to %s", volume.Id, a.access.Name())
+	}
+
+	var deviceName string
+	attached, err := bslcommon.NewPoller(a.waitPolicies.Attach, clock.NewClock()).Poll(ctx, func() (bool, error) {
+		newDisks, err := a.getIscsiDeviceNamesBasedOnShellScript(hasMultiPath)
+		if err != nil {
+			return false, bosherr.WrapError(err, fmt.Sprintf("Failed to get devices names from %s", a.access.Name()))
+		}
+
+		if len(oldDisks) == 0 {
+			if len(newDisks) > 0 {
+				deviceName = newDisks[0]
+				return true, nil
+			}
+		}
+
+		var included bool
+		for _, newDisk := range newDisks {
+			for _, oldDisk := range oldDisks {
+				if strings.EqualFold(newDisk, oldDisk) {
+					included = true
+				}
+			}
+			if !included {
+				deviceName = newDisk
+			}
+			included = false
+		}
+
+		return len(deviceName) > 0, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !attached {
+		return "", bosherr.Errorf("Failed to attach disk '%d' to %s", volume.Id, a.access.Name())
+	}
+
+	return deviceName, nil
+}
+
+func (a *iscsiAttacher) hasMulitPathToolBasedOnShellScript() (bool, error) {
+	command := fmt.Sprintf("echo `command -v multipath`")
+	output, err := a.runCommand(command)
+	if err != nil {
+		return false, err
+	}
+
+	if len(output) > 0 && strings.Contains(output, "multipath") {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (a *iscsiAttacher) getIscsiDeviceNamesBasedOnShellScript(hasMultiPath bool) ([]string, error) {
+	devices := []string{}
+
+	command1 := fmt.Sprintf("dmsetup ls")
+	command2 := fmt.Sprintf("cat /proc/partitions")
+
+	if hasMultiPath {
+		result, err := a.runCommand(command1)
+		if err != nil {
+			return devices, err
+		}
+		if strings.Contains(result, "No devices found") {
+			return devices, nil
+		}
+		a.logger.Info(ISCSI_ATTACHER_LOG_TAG, fmt.Sprintf("Devices on %s: %s", a.access.Name(), result))
+		lines := strings.Split(strings.Trim(result, "\n"), "\n")
+		for i := 0; i < len(lines); i++ {
+			if match, _ := regexp.MatchString("-part1", lines[i]); !match {
+				devices = append(devices, strings.Fields(lines[i])[0])
+			}
+		}
+	} else {
+		result, err := a.runCommand(command2)
+		if err != nil {
+			return devices, err
+		}
+
+		a.logger.Info(ISCSI_ATTACHER_LOG_TAG, fmt.Sprintf("Devices on %s: %s", a.access.Name(), result))
+		lines := strings.Split(strings.Trim(result, "\n"), "\n")
+		for i := 0; i < len(lines); i++ {
+			if match, _ := regexp.MatchString("sd[a-z]$", lines[i]); match {
+				vals := strings.Fields(lines[i])
+				devices = append(devices, vals[len(vals)-1])
+			}
+		}
+	}
+
+	return devices, nil
+}
+
+func (a *iscsiAttacher) fetchIscsiVolume(volumeId int) (datatypes.SoftLayer_Network_Storage, error) {
+	networkStorageService, err := a.softLayerClient.GetSoftLayer_Network_Storage_Service()
+	if err != nil {
+		return datatypes.SoftLayer_Network_Storage{}, bosherr.WrapError(err, "Cannot get network storage service.")
+	}
+
+	volume, err := networkStorageService.GetNetworkStorage(volumeId)
+	if err != nil {
+		return datatypes.SoftLayer_Network_Storage{}, bosherr.WrapErrorf(err, "Cannot get iSCSI volume with id: %d", volumeId)
+	}
+
+	return volume, nil
+}
+
+func (a *iscsiAttacher) getAllowedHostCredential() (AllowedHostCredential, error) {
+	allowedHost, err := a.access.GetAllowedHost()
+	if err != nil {
+		return AllowedHostCredential{}, bosherr.WrapErrorf(err, "Cannot get allowed host with instance id: %d", a.access.ID())
+	}
+
+	if allowedHost.Id == 0 {
+		return AllowedHostCredential{}, bosherr.Errorf("Cannot get allowed host with instance id: %d", a.access.ID())
+	}
+
+	allowedHostService, err := a.softLayerClient.GetSoftLayer_Network_Storage_Allowed_Host_Service()
+	if err != nil {
+		return AllowedHostCredential{}, bosherr.WrapError(err, "Cannot get network storage allowed host service.")
+	}
+
+	credential, err := allowedHostService.GetCredential(allowedHost.Id)
+	if err != nil {
+		return AllowedHostCredential{}, bosherr.WrapErrorf(err, "Cannot get credential with allowed host id: %d", allowedHost.Id)
+	}
+
+	return AllowedHostCredential{
+		Iqn:      allowedHost.Name,
+		Username: credential.Username,
+		Password: credential.Password,
+	}, nil
+}
+
+func (a *iscsiAttacher) backupOpenIscsiConfBasedOnShellScript() (bool, error) {
+	command := fmt.Sprintf("cp /etc/iscsi/iscsid.conf{,.save}")
+	_, err := a.runCommand(command)
+	if err != nil {
+		return false, bosherr.WrapError(err, "backuping open iscsi conf")
+	}
+
+	return true, nil
+}
+
+func (a *iscsiAttacher) restartOpenIscsiBasedOnShellScript() (bool, error) {
+	command := fmt.Sprintf("/etc/init.d/open-iscsi restart")
+	_, err := a.runCommand(command)
+	if err != nil {
+		return false, bosherr.WrapError(err, "restarting open iscsi")
+	}
+
+	return true, nil
+}
+
+func (a *iscsiAttacher) discoveryOpenIscsiTargetsBasedOnShellScript(volume datatypes.SoftLayer_Network_Storage) (bool, error) {
+	command := fmt.Sprintf("sleep 5; iscsiadm -m discovery -t sendtargets -p %s", volume.ServiceResourceBackendIpAddress)
+	_, err := a.runCommand(command)
+	if err != nil {
+		return false, bosherr.WrapError(err, "discoverying open iscsi targets")
+	}
+
+	command = "sleep 5; echo `iscsiadm -m node -l`"
+	_, err = a.runCommand(command)
+	if err != nil {
+		return false, bosherr.WrapError(err, "login iscsi targets")
+	}
+
+	return true, nil
+}
+
+func (a *iscsiAttacher) writeOpenIscsiInitiatornameBasedOnShellScript(credential AllowedHostCredential) (bool, error) {
+	if len(credential.Iqn) > 0 {
+		command := fmt.Sprintf("echo 'InitiatorName=%s' > /etc/iscsi/initiatorname.iscsi", credential.Iqn)
+		_, err := a.runCommand(command)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Writing to /etc/iscsi/initiatorname.iscsi")
+		}
+	}
+
+	return true, nil
+}
+
+func (a *iscsiAttacher) writeOpenIscsiConfBasedOnShellScript(volume datatypes.SoftLayer_Network_Storage, credential AllowedHostCredential) (bool, error) {
+	buffer := bytes.NewBuffer([]byte{})
+	t := template.Must(template.New("open_iscsid_conf").Parse(etcIscsidConfTemplate))
+	if len(credential.Password) == 0 {
+		err := t.Execute(buffer, volume)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Generating config from template")
+		}
+	} else {
+		err := t.Execute(buffer, credential)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Generating config from template")
+		}
+	}
+
+	file, err := ioutil.TempFile(os.TempDir(), "iscsid_conf_")
+	if err != nil {
+		return false, bosherr.WrapError(err, "Generating config from template")
+	}
+
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(buffer.String())
+	if err != nil {
+		return false, bosherr.WrapError(err, "Generating config from template")
+	}
+
+	if err = a.sshClient.UploadFile(ROOT_USER_NAME, a.password, a.ip, file.Name(), "/etc/iscsi/iscsid.conf"); err != nil {
+		return false, bosherr.WrapError(err, "Writing to /etc/iscsi/iscsid.conf")
+	}
+
+	return true, nil
+}
+
+func (a *iscsiAttacher) detachVolumeBasedOnShellScript(hasMultiPath bool) error {
+	// umount /var/vcap/store in case read-only mount
+	isMounted, err := a.isMountPoint("/var/vcap/store")
+	if err != nil {
+		return bosherr.WrapError(err, "check mount point /var/vcap/store")
+	}
+
+	if isMounted {
+		step00 := fmt.Sprintf("umount -l /var/vcap/store")
+		_, err := a.runCommand(step00)
+		if err != nil {
+			return bosherr.WrapError(err, "umount -l /var/vcap/store")
+		}
+		a.logger.Debug(ISCSI_ATTACHER_LOG_TAG, "umount -l /var/vcap/store", nil)
+	}
+
+	// stop open-iscsi
+	step1 := fmt.Sprintf("/etc/init.d/open-iscsi stop")
+	_, err = a.runCommand(step1)
+	if err != nil {
+		return bosherr.WrapError(err, "Restarting open iscsi")
+	}
+	a.logger.Debug(ISCSI_ATTACHER_LOG_TAG, "/etc/init.d/open-iscsi stop", nil)
+
+	// clean up /etc/iscsi/send_targets/
+	step2 := fmt.Sprintf("rm -rf /etc/iscsi/send_targets")
+	_, err = a.runCommand(step2)
+	if err != nil {
+		return bosherr.WrapError(err, "Removing /etc/iscsi/send_targets")
+	}
+	a.logger.Debug(ISCSI_ATTACHER_LOG_TAG, "rm -rf /etc/iscsi/send_targets", nil)
+
+	// clean up /etc/iscsi/nodes/
+	step3 := fmt.Sprintf("rm -rf /etc/iscsi/nodes")
+	_, err = a.runCommand(step3)
+	if err != nil {
+		return bosherr.WrapError(err, "Removing /etc/iscsi/nodes")
+	}
+
+	a.logger.Debug(ISCSI_ATTACHER_LOG_TAG, "rm -rf /etc/iscsi/nodes", nil)
+
+	// start open-iscsi
+	step4 := fmt.Sprintf("/etc/init.d/open-iscsi start")
+	_, err = a.runCommand(step4)
+	if err != nil {
+		return bosherr.WrapError(err, "Restarting open iscsi")
+	}
+	a.logger.Debug(ISCSI_ATTACHER_LOG_TAG, "/etc/init.d/open-iscsi start", nil)
+
+	if hasMultiPath {
+		// restart dm-multipath tool
+		step5 := fmt.Sprintf("service multipath-tools restart")
+		_, err = a.runCommand(step5)
+		if err != nil {
+			return bosherr.WrapError(err, "Restarting Multipath deamon")
+		}
+		a.logger.Debug(ISCSI_ATTACHER_LOG_TAG, "service multipath-tools restart", nil)
+	}
+
+	return nil
+}
+
+func (a *iscsiAttacher) runCommand(command string) (string, error) {
+	return runRemoteCommand(a.sshClient, a.password, a.ip, command, a.logger, ISCSI_ATTACHER_LOG_TAG)
+}
+
+func (a *iscsiAttacher) isMountPoint(path string) (bool, error) {
+	mounts, err := a.searchMounts()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Searching mounts")
+	}
+
+	for _, mount := range mounts {
+		if mount.MountPoint == path {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (a *iscsiAttacher) searchMounts() ([]Mount, error) {
+	var mounts []Mount
+	stdout, err := a.runCommand("mount")
+	if err != nil {
+		return mounts, bosherr.WrapError(err, "Running mount")
+	}
+
+	// e.g. '/dev/sda on /boot type ext2 (rw)'
+	for _, mountEntry := range strings.Split(stdout, "\n") {
+		if mountEntry == "" {
+			continue
+		}
+
+		mountFields := strings.Fields(mountEntry)
+
+		mounts = append(mounts, Mount{
+			PartitionPath: mountFields[0],
+			MountPoint:    mountFields[2],
+		})
+
+	}
+
+	return mounts, nil
+}
@@ -0,0 +1,381 @@
+package vm_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	testhelpers "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+
+	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
+	util "github.com/cloudfoundry/bosh-softlayer-cpi/util"
+	fakesutil "github.com/cloudfoundry/bosh-softlayer-cpi/util/fakes"
+	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
+
+	datatypes "github.com/maximilien/softlayer-go/data_types"
+)
+
+var _ = Describe("IscsiAttacher", func() {
+	const (
+		multipathCommand  = "echo `command -v multipath`"
+		dmsetupCommand    = "dmsetup ls"
+		partitionsCommand = "cat /proc/partitions"
+		discoveryCommand  = "sleep 5; iscsiadm -m discovery -t sendtargets -p fake-ip"
+		loginCommand      = "sleep 5; echo `iscsiadm -m node -l`"
+	)
+
+	const partitionsWithoutIscsi = `major minor  #blocks  name
+
+ 202        0   26214400 xvda
+ 202        1     248832 xvda1
+`
+	const partitionsWithIscsi = `major minor  #blocks  name
+
+ 202        0   26214400 xvda
+ 202        1     248832 xvda1
+   8       16  314572800 sdb
+   8       17  314572799 sdb1
+`
+	const dmsetupWithIscsi = `
+36090a0c8600058baa8283574c302c0fc-part1	(252:1)
+36090a0c8600058baa8283574c302c0fc	(252:0)
+`
+	const mountsWithStore = `/dev/xvda1 on /boot type ext3 (rw,noatime,barrier=0)
+/dev/mapper/36090a0c8600058baa8283574c302c0fc-part1 on /var/vcap/store type ext4 (rw)
+`
+
+	var (
+		fakeSoftLayerClient *fakeslclient.FakeSoftLayerClient
+		sshClient           *fakesutil.FakeSshClient
+		access              *fakevm.FakeIscsiAccess
+		attacher            IscsiAttacher
+
+		// Each command answers with the first of its outputs, moving on to the
+		// next one until only the last is left
+		outputs  map[string][]string
+		failures map[string]util.CommandResult
+		commands []string
+	)
+
+	BeforeEach(func() {
+		fakeSoftLayerClient = fakeslclient.NewFakeSoftLayerClient("fake-username", "fake-api-key")
+		sshClient = &fakesutil.FakeSshClient{}
+		access = &fakevm.FakeIscsiAccess{
+			IDValue:          5678,
+			HasAllowedResult: true,
+			GetAllowedHostResult: datatypes.SoftLayer_Network_Storage_Allowed_Host{
+				Id:   11,
+				Name: "iqn.2005-05.com.softlayer:fake-initiator",
+			},
+		}
+
+		outputs = map[string][]string{}
+		failures = map[string]util.CommandResult{}
+		commands = []string{}
+
+		sshClient.RunStub = func(_, _, _, command string, _ time.Duration) (util.CommandResult, error) {
+			commands = append(commands, command)
+
+			if result, found := failures[command]; found {
+				return result, nil
+			}
+
+			stdout := ""
+			if len(outputs[command]) > 0 {
+				stdout = outputs[command][0]
+				if len(outputs[command]) > 1 {
+					outputs[command] = outputs[command][1:]
+				}
+			}
+			return util.CommandResult{Stdout: stdout}, nil
+		}
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		waitPolicies := bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(50*time.Millisecond, 10*time.Millisecond))
+		attacher = NewIscsiAttacher(access, fakeSoftLayerClient, sshClient, "fake-root-password", "fake-backend-ip", waitPolicies, logger)
+	})
+
+	Describe("GrantAccess", func() {
+		BeforeEach(func() {
+			access.HasAllowedResult = false
+			access.AllowResult = true
+
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, []string{
+				"SoftLayer_Network_Storage_Service_getIscsiVolume.json",
+				"SoftLayer_Network_Storage_Allowed_Host_Service_getCredential.json",
+			})
+		})
+
+		It("grants access and returns the iSCSI target without touching the server", func() {
+			settings, err := attacher.GrantAccess(context.Background(), 1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings).To(Equal(PersistentDiskSettings{
+				ID:       "1234",
+				VolumeID: "1234",
+				Lun:      "0",
+				ISCSISettings: ISCSISettings{
+					InitiatorName: "iqn.2005-05.com.softlayer:fake-initiator",
+					Target:        "fake-ip",
+					Username:      "fake-username",
+					Password:      "fake-password",
+				},
+			}))
+
+			Expect(access.AllowVolumeId).To(Equal(1234))
+			Expect(sshClient.RunCallCount()).To(Equal(0))
+			Expect(sshClient.UploadFileCallCount()).To(Equal(0))
+		})
+
+		It("returns an error when the server has no allowed host", func() {
+			access.GetAllowedHostResult = datatypes.SoftLayer_Network_Storage_Allowed_Host{}
+
+			_, err := attacher.GrantAccess(context.Background(), 1234)
+			Expect(err).To(MatchError(ContainSubstring("Failed to get iscsi host auth from fake server `5678`")))
+		})
+	})
+
+	Describe("RevokeAccess", func() {
+		It("revokes access the server has", func() {
+			err := attacher.RevokeAccess(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(access.RevokeVolumeId).To(Equal(1234))
+			Expect(sshClient.RunCallCount()).To(Equal(0))
+		})
+
+		It("returns an error when revoking fails", func() {
+			access.RevokeErr = errors.New("fake-revoke-error")
+
+			err := attacher.RevokeAccess(1234)
+			Expect(err).To(MatchError(ContainSubstring("fake-revoke-error")))
+		})
+	})
+
+	Describe("AttachDiskForAgent", func() {
+		var agentEnvService *fakevm.FakeAgentEnvService
+
+		BeforeEach(func() {
+			agentEnvService = &fakevm.FakeAgentEnvService{}
+			access.HasAllowedResult = false
+			access.AllowResult = true
+
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, []string{
+				"SoftLayer_Network_Storage_Service_getIscsiVolume.json",
+				"SoftLayer_Network_Storage_Allowed_Host_Service_getCredential.json",
+			})
+		})
+
+		It("records the iSCSI target in the agent env", func() {
+			err := AttachDiskForAgent(context.Background(), attacher, agentEnvService, 1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agentEnvService.UpdateAgentEnv.Disks.Persistent["1234"].(PersistentDiskSettings).ISCSISettings.Target).To(Equal("fake-ip"))
+			Expect(sshClient.RunCallCount()).To(Equal(0))
+		})
+
+		It("returns an error when the agent env cannot be updated", func() {
+			agentEnvService.UpdateErr = errors.New("fake-update-error")
+
+			err := AttachDiskForAgent(context.Background(), attacher, agentEnvService, 1234)
+			Expect(err).To(MatchError(ContainSubstring("Recording iSCSI target of disk `1234` in agent env")))
+		})
+	})
+
+	Describe("DetachDiskForAgent", func() {
+		It("revokes access and drops the disk from the agent env", func() {
+			agentEnvService := &fakevm.FakeAgentEnvService{
+				FetchAgentEnv: AgentEnv{Disks: DisksSpec{Persistent: PersistentSpec{"1234": PersistentDiskSettings{ID: "1234"}}}},
+			}
+
+			err := DetachDiskForAgent(context.Background(), attacher, agentEnvService, 1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(access.RevokeVolumeId).To(Equal(1234))
+			Expect(agentEnvService.UpdateAgentEnv.Disks.Persistent).To(BeEmpty())
+		})
+	})
+
+	Describe("AttachVolume", func() {
+		BeforeEach(func() {
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, []string{
+				"SoftLayer_Network_Storage_Service_getIscsiVolume.json",
+				"SoftLayer_Network_Storage_Allowed_Host_Service_getCredential.json",
+			})
+		})
+
+		Context("when multipath-tools is installed", func() {
+			BeforeEach(func() {
+				outputs[multipathCommand] = []string{"/sbin/multipath"}
+				outputs[dmsetupCommand] = []string{"No devices found", dmsetupWithIscsi}
+			})
+
+			It("logs in to the volume and returns its multipath device", func() {
+				devicePath, err := attacher.AttachVolume(context.Background(), 1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(devicePath).To(Equal("/dev/mapper/36090a0c8600058baa8283574c302c0fc"))
+
+				Expect(commands).To(Equal([]string{
+					multipathCommand,
+					dmsetupCommand,
+					"cp /etc/iscsi/iscsid.conf{,.save}",
+					"echo 'InitiatorName=iqn.2005-05.com.softlayer:fake-initiator' > /etc/iscsi/initiatorname.iscsi",
+					"/etc/init.d/open-iscsi restart",
+					discoveryCommand,
+					loginCommand,
+					dmsetupCommand,
+				}))
+
+				Expect(sshClient.UploadFileCallCount()).To(Equal(1))
+				username, password, ip, _, destination := sshClient.UploadFileArgsForCall(0)
+				Expect(username).To(Equal("root"))
+				Expect(password).To(Equal("fake-root-password"))
+				Expect(ip).To(Equal("fake-backend-ip"))
+				Expect(destination).To(Equal("/etc/iscsi/iscsid.conf"))
+			})
+		})
+
+		Context("when multipath-tools is not installed", func() {
+			BeforeEach(func() {
+				outputs[partitionsCommand] = []string{partitionsWithoutIscsi, partitionsWithoutIscsi, partitionsWithIscsi}
+			})
+
+			It("polls /proc/partitions until the new disk appears", func() {
+				devicePath, err := attacher.AttachVolume(context.Background(), 1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(devicePath).To(Equal("/dev/sdb"))
+
+				Expect(commands[len(commands)-2:]).To(Equal([]string{partitionsCommand, partitionsCommand}))
+			})
+		})
+
+		Context("when the disk never appears", func() {
+			BeforeEach(func() {
+				outputs[partitionsCommand] = []string{partitionsWithoutIscsi}
+			})
+
+			It("returns an error once the attach wait policy times out", func() {
+				_, err := attacher.AttachVolume(context.Background(), 1234)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Failed to attach disk '1234' to fake server `5678`"))
+			})
+		})
+
+		Context("when the server has no access to the volume yet", func() {
+			BeforeEach(func() {
+				access.HasAllowedResult = false
+				outputs[partitionsCommand] = []string{partitionsWithoutIscsi, partitionsWithIscsi}
+			})
+
+			It("grants access before logging in", func() {
+				access.AllowResult = true
+
+				_, err := attacher.AttachVolume(context.Background(), 1234)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(access.AllowCallCount).To(Equal(1))
+				Expect(access.AllowVolumeId).To(Equal(1234))
+			})
+
+			It("returns an error without logging in when access is never granted", func() {
+				_, err := attacher.AttachVolume(context.Background(), 1234)
+				Expect(err).To(MatchError(ContainSubstring("Waiting for grantting access to fake server `5678` TIME OUT!")))
+				Expect(access.AllowCallCount).To(BeNumerically(">", 1))
+				Expect(commands).To(BeEmpty())
+			})
+
+			It("returns an error when granting access fails", func() {
+				access.AllowErr = errors.New("fake-allow-error")
+
+				_, err := attacher.AttachVolume(context.Background(), 1234)
+				Expect(err).To(MatchError(ContainSubstring("fake-allow-error")))
+				Expect(commands).To(BeEmpty())
+			})
+		})
+
+		Context("when a command fails", func() {
+			BeforeEach(func() {
+				outputs[partitionsCommand] = []string{partitionsWithoutIscsi}
+				failures["/etc/init.d/open-iscsi restart"] = util.CommandResult{ExitStatus: 1, Stderr: "fake-stderr"}
+			})
+
+			It("stops and reports the remote stderr", func() {
+				_, err := attacher.AttachVolume(context.Background(), 1234)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Failed to restart open iscsi from fake server `5678`"))
+				Expect(err.Error()).To(ContainSubstring("fake-stderr"))
+				Expect(commands).ToNot(ContainElement(discoveryCommand))
+			})
+		})
+	})
+
+	Describe("DetachVolume", func() {
+		BeforeEach(func() {
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, []string{
+				"SoftLayer_Network_Storage_Service_getIscsiVolume.json",
+			})
+
+			outputs[multipathCommand] = []string{"/sbin/multipath"}
+			outputs["mount"] = []string{mountsWithStore}
+		})
+
+		It("unmounts the store, logs out of all targets and revokes access", func() {
+			err := attacher.DetachVolume(1234)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(commands).To(Equal([]string{
+				multipathCommand,
+				"mount",
+				"umount -l /var/vcap/store",
+				"/etc/init.d/open-iscsi stop",
+				"rm -rf /etc/iscsi/send_targets",
+				"rm -rf /etc/iscsi/nodes",
+				"/etc/init.d/open-iscsi start",
+				"service multipath-tools restart",
+			}))
+
+			Expect(access.RevokeCalled).To(BeTrue())
+			Expect(access.RevokeVolumeId).To(Equal(1234))
+		})
+
+		It("does not revoke access the server does not have", func() {
+			access.HasAllowedResult = false
+
+			err := attacher.DetachVolume(1234)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(access.RevokeCalled).To(BeFalse())
+		})
+
+		It("keeps access to the volume when logging out fails", func() {
+			failures["/etc/init.d/open-iscsi stop"] = util.CommandResult{ExitStatus: 1, Stderr: "fake-stderr"}
+
+			err := attacher.DetachVolume(1234)
+			Expect(err).To(MatchError(ContainSubstring("Failed to detach volume with id 1234 from fake server `5678`")))
+			Expect(commands).ToNot(ContainElement("rm -rf /etc/iscsi/send_targets"))
+			Expect(access.RevokeCalled).To(BeFalse())
+		})
+	})
+
+	Describe("ReattachVolume", func() {
+		BeforeEach(func() {
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, []string{
+				"SoftLayer_Network_Storage_Service_getIscsiVolume.json",
+			})
+		})
+
+		It("logs back in to the volume and mounts the store", func() {
+			err := attacher.ReattachVolume(1234, "/dev/mapper/fake-device")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(commands).To(Equal([]string{
+				discoveryCommand,
+				loginCommand,
+				"sleep 5; mount /dev/mapper/fake-device-part1 /var/vcap/store",
+			}))
+		})
+	})
+})
@@ -0,0 +1,169 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	slcommon "github.com/maximilien/softlayer-go/common"
+	sl "github.com/maximilien/softlayer-go/softlayer"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+type softLayerSubnet struct {
+	Id                int    `json:"id"`
+	NetworkIdentifier string `json:"networkIdentifier"`
+	Cidr              int    `json:"cidr"`
+	Netmask           string `json:"netmask"`
+	Gateway           string `json:"gateway"`
+	AddressSpace      string `json:"addressSpace"`
+	NetworkVlanId     int    `json:"networkVlanId"`
+}
+
+type softLayerIpAddress struct {
+	Id           int                `json:"id"`
+	VirtualGuest *softLayerResource `json:"virtualGuest,omitempty"`
+	Hardware     *softLayerResource `json:"hardware,omitempty"`
+}
+
+type softLayerResource struct {
+	Id int `json:"id"`
+}
+
+// ResolveManualNetworks picks the SoftLayer VLAN matching the subnet of each
+// manual network's IP and fills in the static settings the agent needs
+func ResolveManualNetworks(softLayerClient sl.Client, cloudProps *VMCloudProperties, networks Networks) (Networks, error) {
+	names := []string{}
+	for name, network := range networks {
+		if network.Type == "manual" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return networks, nil
+	}
+	sort.Strings(names)
+
+	subnets, err := getSubnets(softLayerClient)
+	if err != nil {
+		return networks, err
+	}
+
+	resolved := Networks{}
+	for name, network := range networks {
+		resolved[name] = network
+	}
+
+	for _, name := range names {
+		network := networks[name]
+
+		ip := net.ParseIP(network.IP)
+		if ip == nil {
+			return networks, bosherr.Errorf("Manual network '%s' must specify a valid IP, got '%s'", name, network.IP)
+		}
+
+		subnet, found := findSubnetForIp(subnets, ip)
+		if !found {
+			return networks, bosherr.Errorf("No SoftLayer subnet found for IP '%s' of manual network '%s'", network.IP, name)
+		}
+
+		inUse, err := isIpAddressInUse(softLayerClient, network.IP)
+		if err != nil {
+			return networks, err
+		}
+
+		if inUse {
+			return networks, bosherr.Errorf("IP '%s' of manual network '%s' is already in use", network.IP, name)
+		}
+
+		if subnet.AddressSpace == "PUBLIC" {
+			vlanId := cloudProps.PrimaryNetworkComponent.NetworkVlan.Id
+			if vlanId != 0 && vlanId != subnet.NetworkVlanId {
+				return networks, bosherr.Errorf("IP '%s' of manual network '%s' belongs to VLAN '%d', conflicting with public VLAN '%d' in cloud properties", network.IP, name, subnet.NetworkVlanId, vlanId)
+			}
+			cloudProps.PrimaryNetworkComponent.NetworkVlan.Id = subnet.NetworkVlanId
+		} else {
+			vlanId := cloudProps.PrimaryBackendNetworkComponent.NetworkVlan.Id
+			if vlanId != 0 && vlanId != subnet.NetworkVlanId {
+				return networks, bosherr.Errorf("IP '%s' of manual network '%s' belongs to VLAN '%d', conflicting with private VLAN '%d' in cloud properties", network.IP, name, subnet.NetworkVlanId, vlanId)
+			}
+			cloudProps.PrimaryBackendNetworkComponent.NetworkVlan.Id = subnet.NetworkVlanId
+		}
+
+		if len(network.Netmask) == 0 {
+			network.Netmask = subnet.Netmask
+		}
+
+		if len(network.Gateway) == 0 {
+			network.Gateway = subnet.Gateway
+		}
+
+		resolved[name] = network
+	}
+
+	return resolved, nil
+}
+
+func getSubnets(softLayerClient sl.Client) ([]softLayerSubnet, error) {
+	objectMask := []string{"id", "networkIdentifier", "cidr", "netmask", "gateway", "addressSpace", "networkVlanId"}
+	response, errorCode, err := softLayerClient.GetHttpClient().DoRawHttpRequestWithObjectMask("SoftLayer_Account/getSubnets.json", objectMask, "GET", new(bytes.Buffer))
+	if err != nil {
+		return []softLayerSubnet{}, bosherr.WrapError(err, "Getting subnets of SoftLayer account")
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return []softLayerSubnet{}, bslcommon.NewSoftLayerHttpError(errorCode, "Getting subnets of SoftLayer account")
+	}
+
+	subnets := []softLayerSubnet{}
+	err = json.Unmarshal(response, &subnets)
+	if err != nil {
+		return []softLayerSubnet{}, bosherr.WrapError(err, "Unmarshalling subnets of SoftLayer account")
+	}
+
+	return subnets, nil
+}
+
+func findSubnetForIp(subnets []softLayerSubnet, ip net.IP) (softLayerSubnet, bool) {
+	for _, subnet := range subnets {
+		_, ipNet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", subnet.NetworkIdentifier, subnet.Cidr))
+		if err != nil {
+			continue
+		}
+
+		if ipNet.Contains(ip) {
+			return subnet, true
+		}
+	}
+
+	return softLayerSubnet{}, false
+}
+
+func isIpAddressInUse(softLayerClient sl.Client, ip string) (bool, error) {
+	objectMask := []string{"id", "virtualGuest.id", "hardware.id"}
+	response, errorCode, err := softLayerClient.GetHttpClient().DoRawHttpRequestWithObjectMask(fmt.Sprintf("SoftLayer_Network_Subnet_IpAddress/getByIpAddress/%s.json", ip), objectMask, "GET", new(bytes.Buffer))
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Getting IP address '%s'", ip)
+	}
+
+	if errorCode == 404 {
+		return false, nil
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return false, bslcommon.NewSoftLayerHttpError(errorCode, "Getting IP address '%s'", ip)
+	}
+
+	ipAddress := softLayerIpAddress{}
+	err = json.Unmarshal(response, &ipAddress)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Unmarshalling IP address '%s'", ip)
+	}
+
+	return ipAddress.VirtualGuest != nil || ipAddress.Hardware != nil, nil
+}
@@ -0,0 +1,144 @@
+package vm_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+
+	testhelpers "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
+
+	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
+	sldatatypes "github.com/maximilien/softlayer-go/data_types"
+)
+
+var _ = Describe("ResolveManualNetworks", func() {
+	var (
+		softLayerClient *fakeslclient.FakeSoftLayerClient
+		cloudProps      VMCloudProperties
+		networks        Networks
+	)
+
+	BeforeEach(func() {
+		softLayerClient = fakeslclient.NewFakeSoftLayerClient("fake-username", "fake-api-key")
+		cloudProps = VMCloudProperties{}
+		networks = Networks{
+			"fake-network0": Network{
+				Type: "manual",
+				IP:   "10.0.0.10",
+				DNS:  []string{"fake-dns0"},
+			},
+		}
+	})
+
+	It("does nothing when there are no manual networks", func() {
+		networks = Networks{"fake-network0": Network{Type: "dynamic"}}
+
+		resolved, err := ResolveManualNetworks(softLayerClient, &cloudProps, networks)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resolved).To(Equal(networks))
+		Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(0))
+	})
+
+	Context("when the IP belongs to a private subnet and is free", func() {
+		BeforeEach(func() {
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+				"SoftLayer_Account_Service_getSubnets.json",
+				"SoftLayer_Network_Subnet_IpAddress_Service_getByIpAddress.json",
+			})
+		})
+
+		It("selects the backend VLAN and fills in the static network settings", func() {
+			resolved, err := ResolveManualNetworks(softLayerClient, &cloudProps, networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cloudProps.PrimaryBackendNetworkComponent.NetworkVlan.Id).To(Equal(524956))
+			Expect(cloudProps.PrimaryNetworkComponent.NetworkVlan.Id).To(Equal(0))
+
+			Expect(resolved["fake-network0"].IP).To(Equal("10.0.0.10"))
+			Expect(resolved["fake-network0"].Netmask).To(Equal("255.255.255.192"))
+			Expect(resolved["fake-network0"].Gateway).To(Equal("10.0.0.1"))
+			Expect(resolved["fake-network0"].DNS).To(Equal([]string{"fake-dns0"}))
+			Expect(networks["fake-network0"].Netmask).To(BeEmpty())
+		})
+
+		It("keeps the netmask and gateway given by the manifest", func() {
+			network := networks["fake-network0"]
+			network.Netmask = "255.255.255.0"
+			network.Gateway = "10.0.0.254"
+			networks["fake-network0"] = network
+
+			resolved, err := ResolveManualNetworks(softLayerClient, &cloudProps, networks)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolved["fake-network0"].Netmask).To(Equal("255.255.255.0"))
+			Expect(resolved["fake-network0"].Gateway).To(Equal("10.0.0.254"))
+		})
+
+		It("returns error when the VLAN conflicts with cloud properties", func() {
+			cloudProps.PrimaryBackendNetworkComponent = sldatatypes.PrimaryBackendNetworkComponent{
+				NetworkVlan: sldatatypes.NetworkVlan{Id: 111111},
+			}
+
+			_, err := ResolveManualNetworks(softLayerClient, &cloudProps, networks)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("conflicting with private VLAN '111111'"))
+		})
+	})
+
+	Context("when the IP belongs to a public subnet", func() {
+		BeforeEach(func() {
+			networks["fake-network0"] = Network{Type: "manual", IP: "169.50.10.5"}
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+				"SoftLayer_Account_Service_getSubnets.json",
+				"SoftLayer_Network_Subnet_IpAddress_Service_getByIpAddress.json",
+			})
+		})
+
+		It("selects the frontend VLAN", func() {
+			resolved, err := ResolveManualNetworks(softLayerClient, &cloudProps, networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cloudProps.PrimaryNetworkComponent.NetworkVlan.Id).To(Equal(524954))
+			Expect(resolved["fake-network0"].Gateway).To(Equal("169.50.10.1"))
+		})
+	})
+
+	It("returns error when the IP is invalid", func() {
+		networks["fake-network0"] = Network{Type: "manual", IP: "fake-ip"}
+		testhelpers.SetTestFixtureForFakeSoftLayerClient(softLayerClient, "SoftLayer_Account_Service_getSubnets.json")
+
+		_, err := ResolveManualNetworks(softLayerClient, &cloudProps, networks)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("must specify a valid IP"))
+	})
+
+	It("returns error when no subnet contains the IP", func() {
+		networks["fake-network0"] = Network{Type: "manual", IP: "192.168.1.10"}
+		testhelpers.SetTestFixtureForFakeSoftLayerClient(softLayerClient, "SoftLayer_Account_Service_getSubnets.json")
+
+		_, err := ResolveManualNetworks(softLayerClient, &cloudProps, networks)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("No SoftLayer subnet found for IP '192.168.1.10'"))
+	})
+
+	It("returns error when the IP is already in use", func() {
+		testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+			"SoftLayer_Account_Service_getSubnets.json",
+			"SoftLayer_Network_Subnet_IpAddress_Service_getByIpAddress_InUse.json",
+		})
+
+		_, err := ResolveManualNetworks(softLayerClient, &cloudProps, networks)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("IP '10.0.0.10' of manual network 'fake-network0' is already in use"))
+	})
+
+	It("returns error when getting subnets fails", func() {
+		softLayerClient.FakeHttpClient.DoRawHttpRequestError = errors.New("fake-error")
+
+		_, err := ResolveManualNetworks(softLayerClient, &cloudProps, networks)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Getting subnets of SoftLayer account"))
+	})
+})
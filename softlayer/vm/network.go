@@ -1,5 +1,20 @@
 package vm
 
+import (
+	"net"
+	"sort"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	"github.com/cloudfoundry/bosh-softlayer-cpi/common"
+)
+
+const (
+	PublicNetworkComponent  = "public"
+	PrivateNetworkComponent = "private"
+)
+
 type Networks map[string]Network
 
 type Network struct {
@@ -27,8 +42,174 @@ func (ns Networks) First() Network {
 	return Network{}
 }
 
+func (ns Networks) Names() []string {
+	names := []string{}
+	for name := range ns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// DefaultGatewayNetwork returns the name of the network that provides the
+// default gateway, which must be unique when there are several networks
+func (ns Networks) DefaultGatewayNetwork() (string, error) {
+	candidates := []string{}
+	withGateway := []string{}
+	for _, name := range ns.Names() {
+		if ns[name].IsVip() {
+			continue
+		}
+
+		candidates = append(candidates, name)
+		if ns[name].HasDefaultGateway() {
+			withGateway = append(withGateway, name)
+		}
+	}
+
+	switch {
+	case len(candidates) == 0:
+		return "", bosherr.Error("At least one non-vip network is required")
+	case len(withGateway) == 1:
+		return withGateway[0], nil
+	case len(withGateway) > 1:
+		return "", bosherr.Errorf("Only one network may provide the default gateway, got '%s'", strings.Join(withGateway, "', '"))
+	case len(candidates) == 1:
+		return candidates[0], nil
+	default:
+		return "", bosherr.Errorf("One of networks '%s' must provide the default gateway", strings.Join(candidates, "', '"))
+	}
+}
+
+// Components maps each non-vip network onto the public or private network
+// component of the VM. A network with an IP lands on the component matching
+// the address, a single network without an IP takes the remaining one.
+func (ns Networks) Components(privateNetworkOnly bool) (map[string]string, error) {
+	components := map[string]string{}
+	owners := map[string]string{}
+	unassigned := []string{}
+
+	for _, name := range ns.Names() {
+		network := ns[name]
+		switch network.Type {
+		case "dynamic", "manual":
+		case "vip":
+			if privateNetworkOnly {
+				return nil, bosherr.Errorf("Network '%s' is a vip network, but the VM is private network only", name)
+			}
+			continue
+		default:
+			return nil, bosherr.Errorf("Network '%s' has unsupported type '%s'", name, network.Type)
+		}
+
+		if len(network.IP) == 0 {
+			unassigned = append(unassigned, name)
+			continue
+		}
+
+		ip := net.ParseIP(network.IP)
+		if ip == nil {
+			return nil, bosherr.Errorf("Network '%s' has invalid IP '%s'", name, network.IP)
+		}
+
+		component := PublicNetworkComponent
+		if common.IsPrivateSubnet(ip) {
+			component = PrivateNetworkComponent
+		}
+
+		if owner, found := owners[component]; found {
+			return nil, bosherr.Errorf("Networks '%s' and '%s' both map to the %s network component", owner, name, component)
+		}
+		owners[component] = name
+		components[name] = component
+	}
+
+	if len(unassigned) > 1 {
+		return nil, bosherr.Errorf("Networks '%s' have no IP and cannot be mapped to network components unambiguously", strings.Join(unassigned, "', '"))
+	}
+
+	if len(unassigned) == 1 {
+		name := unassigned[0]
+		_, publicTaken := owners[PublicNetworkComponent]
+		_, privateTaken := owners[PrivateNetworkComponent]
+
+		switch {
+		case publicTaken && privateTaken:
+			return nil, bosherr.Errorf("Network '%s' cannot be mapped, both network components are already taken", name)
+		case privateTaken:
+			components[name] = PublicNetworkComponent
+		default:
+			components[name] = PrivateNetworkComponent
+		}
+	}
+
+	if privateNetworkOnly {
+		for _, name := range ns.Names() {
+			if components[name] == PublicNetworkComponent {
+				return nil, bosherr.Errorf("Network '%s' requires a public network component, but the VM is private network only", name)
+			}
+		}
+	}
+
+	return components, nil
+}
+
+// ValidateComponents checks that every non-vip network maps onto a network
+// component of the VM. SoftLayer attaches the components by itself, so the
+// creators only need to know that the mapping exists.
+func (ns Networks) ValidateComponents(privateNetworkOnly bool) error {
+	_, err := ns.Components(privateNetworkOnly)
+	return err
+}
+
+// ReloadNetwork returns the name of the network whose IP identifies an
+// existing server to OS reload, which is requested by giving a dynamic network
+// an IP. The default gateway network wins when several networks qualify.
+func (ns Networks) ReloadNetwork() (string, error) {
+	defaultNetwork, err := ns.DefaultGatewayNetwork()
+	if err != nil {
+		return "", err
+	}
+
+	reload := false
+	for _, network := range ns {
+		if network.IsDynamic() && len(network.IP) > 0 {
+			reload = true
+		}
+	}
+
+	if !reload {
+		return "", nil
+	}
+
+	if len(ns[defaultNetwork].IP) > 0 {
+		return defaultNetwork, nil
+	}
+
+	for _, name := range ns.Names() {
+		if ns[name].IsDynamic() && len(ns[name].IP) > 0 {
+			return name, nil
+		}
+	}
+
+	return "", nil
+}
+
 func (n Network) IsDynamic() bool { return n.Type == "dynamic" }
 
+func (n Network) IsVip() bool { return n.Type == "vip" }
+
+func (n Network) HasDefaultGateway() bool {
+	for _, property := range n.Default {
+		if property == "gateway" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (n Network) AppendDNS(dns string) Network {
 	if len(dns) > 0 {
 		n.DNS = append(n.DNS, dns)
@@ -133,4 +133,180 @@ var _ = Describe("Network", func() {
 			Expect(network2).To(Equal(dnsNetwork))
 		})
 	})
+
+	Describe("#DefaultGatewayNetwork", func() {
+		It("returns the only network when there is one", func() {
+			networks = Networks{"fake-network0": Network{Type: "dynamic"}}
+
+			name, err := networks.DefaultGatewayNetwork()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("fake-network0"))
+		})
+
+		It("returns the network providing the default gateway", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "manual", IP: "10.0.0.10", Default: []string{"dns"}},
+				"fake-network1": Network{Type: "manual", IP: "169.50.10.5", Default: []string{"dns", "gateway"}},
+				"fake-network2": Network{Type: "vip", IP: "169.50.20.5"},
+			}
+
+			name, err := networks.DefaultGatewayNetwork()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("fake-network1"))
+		})
+
+		It("returns error when several networks provide the default gateway", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "manual", IP: "10.0.0.10", Default: []string{"gateway"}},
+				"fake-network1": Network{Type: "manual", IP: "169.50.10.5", Default: []string{"gateway"}},
+			}
+
+			_, err := networks.DefaultGatewayNetwork()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Only one network may provide the default gateway, got 'fake-network0', 'fake-network1'"))
+		})
+
+		It("returns error when none of several networks provides the default gateway", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "manual", IP: "10.0.0.10"},
+				"fake-network1": Network{Type: "manual", IP: "169.50.10.5"},
+			}
+
+			_, err := networks.DefaultGatewayNetwork()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must provide the default gateway"))
+		})
+
+		It("returns error when there are no networks", func() {
+			_, err := Networks{}.DefaultGatewayNetwork()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("#Components", func() {
+		It("maps networks onto components by IP", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "manual", IP: "10.0.0.10"},
+				"fake-network1": Network{Type: "dynamic", IP: "169.50.10.5"},
+				"fake-network2": Network{Type: "vip", IP: "169.50.20.5"},
+			}
+
+			components, err := networks.Components(false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(components).To(Equal(map[string]string{
+				"fake-network0": PrivateNetworkComponent,
+				"fake-network1": PublicNetworkComponent,
+			}))
+		})
+
+		It("maps a single network without IP onto the private component", func() {
+			networks = Networks{"fake-network0": Network{Type: "dynamic"}}
+
+			components, err := networks.Components(false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(components).To(Equal(map[string]string{"fake-network0": PrivateNetworkComponent}))
+		})
+
+		It("maps a network without IP onto the component left free", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "manual", IP: "10.0.0.10"},
+				"fake-network1": Network{Type: "dynamic"},
+			}
+
+			components, err := networks.Components(false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(components["fake-network1"]).To(Equal(PublicNetworkComponent))
+		})
+
+		It("returns error when two networks map to the same component", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "manual", IP: "10.0.0.10"},
+				"fake-network1": Network{Type: "manual", IP: "10.0.0.20"},
+			}
+
+			_, err := networks.Components(false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Networks 'fake-network0' and 'fake-network1' both map to the private network component"))
+		})
+
+		It("returns error when several networks have no IP", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "dynamic"},
+				"fake-network1": Network{Type: "dynamic"},
+			}
+
+			_, err := networks.Components(false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot be mapped to network components unambiguously"))
+		})
+
+		It("returns error when a public network is used on a private network only VM", func() {
+			networks = Networks{"fake-network0": Network{Type: "manual", IP: "169.50.10.5"}}
+
+			_, err := networks.Components(true)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("private network only"))
+		})
+
+		It("returns error for unsupported network types", func() {
+			networks = Networks{"fake-network0": Network{Type: "fake-type"}}
+
+			_, err := networks.Components(false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported type 'fake-type'"))
+		})
+	})
+
+	Describe("#ValidateComponents", func() {
+		It("accepts networks that map onto the network components", func() {
+			networks = Networks{
+				"fake-private": Network{Type: "manual", IP: "10.0.0.10"},
+				"fake-public":  Network{Type: "dynamic"},
+			}
+
+			Expect(networks.ValidateComponents(false)).To(Succeed())
+		})
+
+		It("returns the mapping error", func() {
+			networks = Networks{"fake-network0": Network{Type: "manual", IP: "169.50.10.5"}}
+
+			err := networks.ValidateComponents(true)
+			Expect(err).To(MatchError(ContainSubstring("private network only")))
+		})
+	})
+
+	Describe("#ReloadNetwork", func() {
+		It("returns no network when no dynamic network has an IP", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "dynamic"},
+				"fake-network1": Network{Type: "manual", IP: "10.0.0.10", Default: []string{"gateway"}},
+			}
+
+			name, err := networks.ReloadNetwork()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		It("prefers the default gateway network", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "dynamic", IP: "10.0.0.10"},
+				"fake-network1": Network{Type: "dynamic", IP: "169.50.10.5", Default: []string{"gateway"}},
+			}
+
+			name, err := networks.ReloadNetwork()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("fake-network1"))
+		})
+
+		It("falls back to the first dynamic network with an IP", func() {
+			networks = Networks{
+				"fake-network0": Network{Type: "dynamic", Default: []string{"gateway"}},
+				"fake-network1": Network{Type: "dynamic", IP: "10.0.0.10"},
+			}
+
+			name, err := networks.ReloadNetwork()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("fake-network1"))
+		})
+	})
 })
@@ -0,0 +1,370 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/pivotal-golang/clock"
+
+	slcommon "github.com/maximilien/softlayer-go/common"
+	sldatatypes "github.com/maximilien/softlayer-go/data_types"
+	sl "github.com/maximilien/softlayer-go/softlayer"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
+)
+
+const (
+	VIRTUAL_GUEST_PACKAGE_ID = 46
+
+	virtualGuestOrderComplexType = "SoftLayer_Container_Product_Order_Virtual_Guest"
+
+	defaultOrderRootDiskSizeGb = 25
+	defaultOrderPortSpeedMbps  = 100
+)
+
+// Items SoftLayer requires on every virtual guest order, on top of the ones
+// resolved from the cloud properties
+var defaultVirtualGuestOrderItems = []string{
+	"BANDWIDTH_0_GB_2",
+	"1_IP_ADDRESS",
+	"REBOOT_REMOTE_CONSOLE",
+	"MONITORING_HOST_PING",
+	"NOTIFICATION_EMAIL_AND_TICKET",
+	"AUTOMATED_NOTIFICATION",
+	"UNLIMITED_SSL_VPN_USERS_1_PPTP_VPN_USER_PER_ACCOUNT",
+	"NESSUS_VULNERABILITY_ASSESSMENT_REPORTING",
+}
+
+type softLayerVirtualGuestOrder struct {
+	ComplexType      string                       `json:"complexType"`
+	Location         string                       `json:"location"`
+	PackageId        int                          `json:"packageId"`
+	Prices           []softLayerOrderPrice        `json:"prices"`
+	Quantity         int                          `json:"quantity"`
+	UseHourlyPricing bool                         `json:"useHourlyPricing"`
+	ImageTemplateId  int                          `json:"imageTemplateId,omitempty"`
+	VirtualGuests    []softLayerOrderVirtualGuest `json:"virtualGuests"`
+	SshKeys          []softLayerOrderSshKeys      `json:"sshKeys,omitempty"`
+	ProvisionScripts []string                     `json:"provisionScripts,omitempty"`
+}
+
+type softLayerOrderPrice struct {
+	Id int `json:"id"`
+}
+
+type softLayerOrderItemPrice struct {
+	Id              int `json:"id"`
+	LocationGroupId int `json:"locationGroupId"`
+
+	Item struct {
+		KeyName string `json:"keyName"`
+	} `json:"item"`
+}
+
+type softLayerOrderVirtualGuest struct {
+	Hostname                       string                                      `json:"hostname"`
+	Domain                         string                                      `json:"domain"`
+	PrimaryNetworkComponent        *sldatatypes.PrimaryNetworkComponent        `json:"primaryNetworkComponent,omitempty"`
+	PrimaryBackendNetworkComponent *sldatatypes.PrimaryBackendNetworkComponent `json:"primaryBackendNetworkComponent,omitempty"`
+	UserData                       []sldatatypes.UserData                      `json:"userData,omitempty"`
+}
+
+type softLayerOrderSshKeys struct {
+	SshKeyIds []int `json:"sshKeyIds"`
+}
+
+type softLayerOrderedVirtualGuest struct {
+	Id int `json:"id"`
+}
+
+// VirtualGuestOrderItems lists the item key names of the SoftLayer virtual
+// guest package to order for the given cloud properties
+func VirtualGuestOrderItems(cloudProps VMCloudProperties) []string {
+	coresKeyName := fmt.Sprintf("GUEST_CORE_%d", cloudProps.StartCpus)
+	if cloudProps.DedicatedAccountHostOnlyFlag {
+		coresKeyName += "_DEDICATED"
+	}
+
+	ramGb := (cloudProps.MaxMemory + 1023) / 1024
+
+	rootDiskSize := cloudProps.RootDiskSize
+	if rootDiskSize == 0 {
+		rootDiskSize = defaultOrderRootDiskSizeGb
+	}
+
+	rootDiskType := "SAN"
+	if cloudProps.LocalDiskFlag {
+		rootDiskType = "LOCAL"
+	}
+
+	keyNames := []string{
+		coresKeyName,
+		fmt.Sprintf("RAM_%d_GB", ramGb),
+		portSpeedKeyName(cloudProps),
+		fmt.Sprintf("GUEST_DISK_%d_GB_%s", rootDiskSize, rootDiskType),
+	}
+	keyNames = append(keyNames, defaultVirtualGuestOrderItems...)
+	keyNames = append(keyNames, cloudProps.ProductOrderItems...)
+
+	return uniqueKeyNames(keyNames)
+}
+
+// PlaceVirtualGuestOrder resolves the cloud properties into item prices,
+// verifies and places the order and returns its id. Items the package does
+// not offer are reported together as an InvalidCloudPropertiesError. SoftLayer provisions the
+// guest asynchronously, see WaitForOrderedVirtualGuest.
+func PlaceVirtualGuestOrder(softLayerClient sl.Client, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties) (int, error) {
+	order, err := buildVirtualGuestOrder(softLayerClient, stemcell, cloudProps)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Building virtual guest order")
+	}
+
+	_, err = postVirtualGuestOrder(softLayerClient, "verifyOrder", order)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Verifying virtual guest order")
+	}
+
+	response, err := postVirtualGuestOrder(softLayerClient, "placeOrder", order)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Placing virtual guest order")
+	}
+
+	receipt := sldatatypes.SoftLayer_Container_Product_Order_Receipt{}
+	err = json.Unmarshal(response, &receipt)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Unmarshalling virtual guest order receipt")
+	}
+
+	if receipt.OrderId == 0 {
+		return 0, bosherr.Error("No virtual guest order was placed")
+	}
+
+	return receipt.OrderId, nil
+}
+
+// WaitForOrderedVirtualGuest waits for SoftLayer to provision the guest of
+// the order and returns its id
+func WaitForOrderedVirtualGuest(ctx context.Context, softLayerClient sl.Client, orderId int, waitPolicy bslcommon.WaitPolicy) (int, error) {
+	var virtualGuestId int
+	poller := bslcommon.NewPoller(waitPolicy, clock.NewClock())
+	found, err := poller.Poll(ctx, func() (bool, error) {
+		id, found, err := FindOrderedVirtualGuest(softLayerClient, orderId)
+		if err != nil {
+			if bslcommon.IsSoftLayerTransientError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		virtualGuestId = id
+		return found, nil
+	})
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Waiting for virtual guest of order '%d'", orderId)
+	}
+
+	if !found {
+		return 0, bosherr.Errorf("No virtual guest was provisioned for order '%d'", orderId)
+	}
+
+	return virtualGuestId, nil
+}
+
+// FindOrderedVirtualGuest looks up the guest SoftLayer provisioned for the order
+func FindOrderedVirtualGuest(softLayerClient sl.Client, orderId int) (int, bool, error) {
+	filters := fmt.Sprintf(`{"virtualGuests":{"billingItem":{"orderItem":{"order":{"id":{"operation":%d}}}}}}`, orderId)
+
+	response, errorCode, err := softLayerClient.GetHttpClient().DoRawHttpRequestWithObjectFilterAndObjectMask("SoftLayer_Account/getVirtualGuests.json", []string{"id"}, filters, "GET", new(bytes.Buffer))
+	if err != nil {
+		return 0, false, err
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return 0, false, bslcommon.NewSoftLayerHttpError(errorCode, "Getting virtual guests of order '%d'", orderId)
+	}
+
+	virtualGuests := []softLayerOrderedVirtualGuest{}
+	err = json.Unmarshal(response, &virtualGuests)
+	if err != nil {
+		return 0, false, bosherr.WrapError(err, "Unmarshalling virtual guests")
+	}
+
+	if len(virtualGuests) == 0 {
+		return 0, false, nil
+	}
+
+	return virtualGuests[0].Id, true, nil
+}
+
+// Private methods
+
+func buildVirtualGuestOrder(softLayerClient sl.Client, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties) (softLayerVirtualGuestOrder, error) {
+	problems := []string{}
+	if len(cloudProps.Domain) == 0 {
+		problems = append(problems, "domain must be set")
+	}
+
+	keyNames := VirtualGuestOrderItems(cloudProps)
+	priceIds, err := findItemPriceIds(softLayerClient, keyNames)
+	if err != nil {
+		return softLayerVirtualGuestOrder{}, err
+	}
+
+	prices := []softLayerOrderPrice{}
+	for _, keyName := range keyNames {
+		priceId, found := priceIds[keyName]
+		if !found {
+			problems = append(problems, fmt.Sprintf("item '%s' is not offered by package %d", keyName, VIRTUAL_GUEST_PACKAGE_ID))
+			continue
+		}
+
+		prices = append(prices, softLayerOrderPrice{Id: priceId})
+	}
+
+	if len(problems) > 0 {
+		return softLayerVirtualGuestOrder{}, InvalidCloudPropertiesError{Problems: problems}
+	}
+
+	virtualGuest := softLayerOrderVirtualGuest{
+		Hostname: cloudProps.VmNamePrefix,
+		Domain:   cloudProps.Domain,
+		UserData: cloudProps.UserData,
+	}
+
+	if cloudProps.PrimaryNetworkComponent.NetworkVlan.Id != 0 {
+		virtualGuest.PrimaryNetworkComponent = &cloudProps.PrimaryNetworkComponent
+	}
+
+	if cloudProps.PrimaryBackendNetworkComponent.NetworkVlan.Id != 0 {
+		virtualGuest.PrimaryBackendNetworkComponent = &cloudProps.PrimaryBackendNetworkComponent
+	}
+
+	order := softLayerVirtualGuestOrder{
+		ComplexType:      virtualGuestOrderComplexType,
+		Location:         cloudProps.Datacenter.Name,
+		PackageId:        VIRTUAL_GUEST_PACKAGE_ID,
+		Prices:           prices,
+		Quantity:         1,
+		UseHourlyPricing: cloudProps.HourlyBillingFlag,
+		ImageTemplateId:  stemcell.ID(),
+		VirtualGuests:    []softLayerOrderVirtualGuest{virtualGuest},
+	}
+
+	if len(cloudProps.SshKeys) > 0 {
+		sshKeyIds := []int{}
+		for _, sshKey := range cloudProps.SshKeys {
+			sshKeyIds = append(sshKeyIds, sshKey.Id)
+		}
+		order.SshKeys = []softLayerOrderSshKeys{{SshKeyIds: sshKeyIds}}
+	}
+
+	if len(cloudProps.PostInstallScriptUri) > 0 {
+		order.ProvisionScripts = []string{cloudProps.PostInstallScriptUri}
+	}
+
+	return order, nil
+}
+
+// findItemPriceIds gets the prices of all key names from the virtual guest
+// package in one call and maps each offered key name to its standard price
+func findItemPriceIds(softLayerClient sl.Client, keyNames []string) (map[string]int, error) {
+	keyNamesJSON, err := json.Marshal(keyNames)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Marshalling item key names")
+	}
+
+	objectMask := []string{
+		"id",
+		"locationGroupId",
+		"item.keyName",
+	}
+	filters := fmt.Sprintf(`{"itemPrices":{"item":{"keyName":{"operation":"in","options":[{"name":"data","value":%s}]}}}}`, keyNamesJSON)
+
+	response, errorCode, err := softLayerClient.GetHttpClient().DoRawHttpRequestWithObjectFilterAndObjectMask(fmt.Sprintf("SoftLayer_Product_Package/%d/getItemPrices.json", VIRTUAL_GUEST_PACKAGE_ID), objectMask, filters, "GET", new(bytes.Buffer))
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Getting item prices of package %d", VIRTUAL_GUEST_PACKAGE_ID)
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return nil, bslcommon.NewSoftLayerHttpError(errorCode, "Getting item prices of package %d", VIRTUAL_GUEST_PACKAGE_ID)
+	}
+
+	itemPrices := []softLayerOrderItemPrice{}
+	err = json.Unmarshal(response, &itemPrices)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling item prices")
+	}
+
+	priceIds := map[string]int{}
+	for _, itemPrice := range itemPrices {
+		if itemPrice.LocationGroupId != 0 {
+			continue
+		}
+
+		if _, found := priceIds[itemPrice.Item.KeyName]; !found {
+			priceIds[itemPrice.Item.KeyName] = itemPrice.Id
+		}
+	}
+
+	return priceIds, nil
+}
+
+func postVirtualGuestOrder(softLayerClient sl.Client, method string, order softLayerVirtualGuestOrder) ([]byte, error) {
+	parameters := map[string][]interface{}{
+		"parameters": []interface{}{order},
+	}
+	requestBody, err := json.Marshal(parameters)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Marshalling virtual guest order")
+	}
+
+	response, errorCode, err := softLayerClient.GetHttpClient().DoRawHttpRequest(fmt.Sprintf("SoftLayer_Product_Order/%s.json", method), "POST", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return nil, bslcommon.NewSoftLayerHttpError(errorCode, "Calling SoftLayer_Product_Order#%s", method)
+	}
+
+	return response, nil
+}
+
+func portSpeedKeyName(cloudProps VMCloudProperties) string {
+	portSpeed := defaultOrderPortSpeedMbps
+	for _, networkComponent := range cloudProps.NetworkComponents {
+		if networkComponent.MaxSpeed > 0 {
+			portSpeed = networkComponent.MaxSpeed
+			break
+		}
+	}
+
+	speed := fmt.Sprintf("%d_MBPS", portSpeed)
+	if portSpeed >= 1000 {
+		speed = fmt.Sprintf("%d_GBPS", portSpeed/1000)
+	}
+
+	if cloudProps.PrivateNetworkOnlyFlag {
+		return speed + "_PRIVATE_NETWORK_UPLINK"
+	}
+
+	return speed + "_PUBLIC_PRIVATE_NETWORK_UPLINKS"
+}
+
+func uniqueKeyNames(keyNames []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, keyName := range keyNames {
+		if seen[keyName] {
+			continue
+		}
+		seen[keyName] = true
+		unique = append(unique, keyName)
+	}
+
+	return unique
+}
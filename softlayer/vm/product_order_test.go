@@ -0,0 +1,208 @@
+package vm_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+	fakestem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell/fakes"
+
+	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
+	sldatatypes "github.com/maximilien/softlayer-go/data_types"
+)
+
+var _ = Describe("ProductOrder", func() {
+	var (
+		cloudProps VMCloudProperties
+	)
+
+	BeforeEach(func() {
+		cloudProps = VMCloudProperties{
+			VmNamePrefix: "fake-hostname",
+			Domain:       "fake-domain.com",
+			StartCpus:    4,
+			MaxMemory:    8192,
+			Datacenter:   sldatatypes.Datacenter{Name: "fake-datacenter"},
+			SshKeys:      []sldatatypes.SshKey{{Id: 74826}},
+		}
+	})
+
+	Describe("VirtualGuestOrderItems", func() {
+		It("resolves cores, memory, port speed and root disk from the cloud properties", func() {
+			keyNames := VirtualGuestOrderItems(cloudProps)
+			Expect(keyNames[:4]).To(Equal([]string{
+				"GUEST_CORE_4",
+				"RAM_8_GB",
+				"100_MBPS_PUBLIC_PRIVATE_NETWORK_UPLINKS",
+				"GUEST_DISK_25_GB_SAN",
+			}))
+			Expect(keyNames).To(ContainElement("1_IP_ADDRESS"))
+		})
+
+		It("orders dedicated cores, private uplinks and local disks when requested", func() {
+			cloudProps.DedicatedAccountHostOnlyFlag = true
+			cloudProps.PrivateNetworkOnlyFlag = true
+			cloudProps.LocalDiskFlag = true
+			cloudProps.RootDiskSize = 100
+			cloudProps.NetworkComponents = []sldatatypes.NetworkComponents{{MaxSpeed: 1000}}
+
+			keyNames := VirtualGuestOrderItems(cloudProps)
+			Expect(keyNames[:4]).To(Equal([]string{
+				"GUEST_CORE_4_DEDICATED",
+				"RAM_8_GB",
+				"1_GBPS_PRIVATE_NETWORK_UPLINK",
+				"GUEST_DISK_100_GB_LOCAL",
+			}))
+		})
+
+		It("appends the product order items once", func() {
+			cloudProps.ProductOrderItems = []string{"fake-item", "1_IP_ADDRESS", "fake-item"}
+
+			keyNames := VirtualGuestOrderItems(cloudProps)
+			Expect(keyNames[len(keyNames)-1]).To(Equal("fake-item"))
+			Expect(len(keyNames)).To(Equal(len(VirtualGuestOrderItems(VMCloudProperties{})) + 1))
+		})
+	})
+
+	Describe("PlaceVirtualGuestOrder", func() {
+		var (
+			softLayerClient *fakeslclient.FakeSoftLayerClient
+			stemcell        *fakestem.FakeStemcell
+			itemCount       int
+		)
+
+		BeforeEach(func() {
+			softLayerClient = fakeslclient.NewFakeSoftLayerClient("fake-username", "fake-api-key")
+			stemcell = fakestem.NewFakeStemcell(1234, "fake-stemcell-uuid")
+			itemCount = len(VirtualGuestOrderItems(cloudProps))
+		})
+
+		itemPrices := func(cloudProps VMCloudProperties) []map[string]interface{} {
+			itemPrices := []map[string]interface{}{}
+			for i, keyName := range VirtualGuestOrderItems(cloudProps) {
+				itemPrices = append(itemPrices, map[string]interface{}{
+					"id":              1000 + i,
+					"locationGroupId": 0,
+					"item":            map[string]string{"keyName": keyName},
+				})
+			}
+			return itemPrices
+		}
+
+		itemPricesResponse := func(itemPrices []map[string]interface{}) []byte {
+			response, err := json.Marshal(itemPrices)
+			Expect(err).ToNot(HaveOccurred())
+			return response
+		}
+
+		It("verifies and places the order and returns its id", func() {
+			softLayerClient.FakeHttpClient.DoRawHttpRequestResponses = [][]byte{
+				itemPricesResponse(itemPrices(cloudProps)),
+				[]byte(`{"packageId": 46}`),
+				[]byte(`{"orderId": 4321}`),
+			}
+
+			orderId, err := PlaceVirtualGuestOrder(softLayerClient, stemcell, cloudProps)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(orderId).To(Equal(4321))
+
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Product_Order/placeOrder.json"))
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestWithObjectFilterAndObjectMaskPath).To(Equal("SoftLayer_Product_Package/46/getItemPrices.json"))
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(3))
+
+			var parameters struct {
+				Parameters []struct {
+					ComplexType     string `json:"complexType"`
+					Location        string `json:"location"`
+					PackageId       int    `json:"packageId"`
+					ImageTemplateId int    `json:"imageTemplateId"`
+					Prices          []struct {
+						Id int `json:"id"`
+					} `json:"prices"`
+					VirtualGuests []struct {
+						Hostname string `json:"hostname"`
+						Domain   string `json:"domain"`
+					} `json:"virtualGuests"`
+					SshKeys []struct {
+						SshKeyIds []int `json:"sshKeyIds"`
+					} `json:"sshKeys"`
+				} `json:"parameters"`
+			}
+			err = json.Unmarshal(softLayerClient.FakeHttpClient.DoRawHttpRequestRequestBody.Bytes(), &parameters)
+			Expect(err).ToNot(HaveOccurred())
+
+			order := parameters.Parameters[0]
+			Expect(order.ComplexType).To(Equal("SoftLayer_Container_Product_Order_Virtual_Guest"))
+			Expect(order.Location).To(Equal("fake-datacenter"))
+			Expect(order.PackageId).To(Equal(VIRTUAL_GUEST_PACKAGE_ID))
+			Expect(order.ImageTemplateId).To(Equal(1234))
+			Expect(order.Prices).To(HaveLen(itemCount))
+			Expect(order.Prices[0].Id).To(Equal(1000))
+			Expect(order.VirtualGuests[0].Hostname).To(Equal("fake-hostname"))
+			Expect(order.VirtualGuests[0].Domain).To(Equal("fake-domain.com"))
+			Expect(order.SshKeys[0].SshKeyIds).To(Equal([]int{74826}))
+		})
+
+		It("reports every item without a standard price and a missing domain", func() {
+			cloudProps.Domain = ""
+			prices := itemPrices(cloudProps)
+			prices[0]["locationGroupId"] = 503
+			softLayerClient.FakeHttpClient.DoRawHttpRequestResponses = [][]byte{
+				itemPricesResponse(append(prices[:1], prices[2:]...)),
+			}
+
+			_, err := PlaceVirtualGuestOrder(softLayerClient, stemcell, cloudProps)
+			Expect(err).To(HaveOccurred())
+
+			cloudErr, found := bslcapi.FindCloudError(err)
+			Expect(found).To(BeTrue())
+			Expect(cloudErr.Type()).To(Equal("Bosh::Clouds::CloudError"))
+			Expect(err.Error()).To(ContainSubstring("Invalid cloud properties: domain must be set; item 'GUEST_CORE_4' is not offered by package 46; item 'RAM_8_GB' is not offered by package 46"))
+		})
+	})
+
+	Describe("WaitForOrderedVirtualGuest", func() {
+		var (
+			softLayerClient *fakeslclient.FakeSoftLayerClient
+			waitPolicy      bslcommon.WaitPolicy
+		)
+
+		BeforeEach(func() {
+			softLayerClient = fakeslclient.NewFakeSoftLayerClient("fake-username", "fake-api-key")
+			waitPolicy = bslcommon.NewWaitPolicy(1*time.Second, 10*time.Millisecond)
+		})
+
+		It("waits for the guest provisioned for the order", func() {
+			softLayerClient.FakeHttpClient.DoRawHttpRequestResponses = [][]byte{
+				[]byte(`[]`),
+				[]byte(`[{"id": 5678}]`),
+			}
+
+			virtualGuestId, err := WaitForOrderedVirtualGuest(context.Background(), softLayerClient, 4321, waitPolicy)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(virtualGuestId).To(Equal(5678))
+
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestWithObjectFilterAndObjectMaskPath).To(Equal("SoftLayer_Account/getVirtualGuests.json"))
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestWithObjectFilterAndObjectMaskFilters).To(ContainSubstring(`"operation":4321`))
+		})
+
+		It("returns error if no guest is provisioned before the timeout", func() {
+			waitPolicy = bslcommon.NewWaitPolicy(30*time.Millisecond, 10*time.Millisecond)
+			softLayerClient.FakeHttpClient.DoRawHttpRequestResponses = [][]byte{}
+			for i := 0; i < 10; i++ {
+				softLayerClient.FakeHttpClient.DoRawHttpRequestResponses = append(softLayerClient.FakeHttpClient.DoRawHttpRequestResponses, []byte(`[]`))
+			}
+
+			_, err := WaitForOrderedVirtualGuest(context.Background(), softLayerClient, 4321, waitPolicy)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No virtual guest was provisioned for order '4321'"))
+		})
+	})
+})
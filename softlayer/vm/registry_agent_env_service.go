@@ -2,6 +2,7 @@ package vm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -80,7 +81,7 @@ func (s registryAgentEnvService) Fetch() (AgentEnv, error) {
 	return agentEnv, nil
 }
 
-func (s registryAgentEnvService) Update(agentEnv AgentEnv) error {
+func (s registryAgentEnvService) Update(ctx context.Context, agentEnv AgentEnv) error {
 	settingsJSON, err := json.Marshal(agentEnv)
 	if err != nil {
 		return bosherr.WrapError(err, "Marshalling agent env")
@@ -95,7 +96,7 @@ func (s registryAgentEnvService) Update(agentEnv AgentEnv) error {
 	}
 
 	httpClient := http.Client{}
-	httpResponse, err := httpClient.Do(request)
+	httpResponse, err := httpClient.Do(request.WithContext(ctx))
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Updating registry endpoint '%s' with settings: '%s'", s.endpoint, settingsJSON)
 	}
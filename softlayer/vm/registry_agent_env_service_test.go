@@ -1,6 +1,7 @@
 package vm_test
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -94,7 +95,7 @@ var _ = Describe("RegistryAgentEnvService", func() {
 	Describe("Update", func() {
 		It("updates settings in the registry", func() {
 			Expect(registryServer.InstanceSettings).To(BeNil())
-			err := agentEnvService.Update(expectedAgentEnv)
+			err := agentEnvService.Update(context.Background(), expectedAgentEnv)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(registryServer.InstanceSettings).To(Equal(expectedAgentEnvJSON))
 		})
@@ -1,6 +1,10 @@
 package vm
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
@@ -8,21 +12,29 @@ import (
 
 	bmscl "github.com/cloudfoundry-community/bosh-softlayer-tools/clients"
 	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
+	slcommon "github.com/maximilien/softlayer-go/common"
+	datatypes "github.com/maximilien/softlayer-go/data_types"
 	sl "github.com/maximilien/softlayer-go/softlayer"
 )
 
 type softLayerFinder struct {
 	softLayerClient        sl.Client
 	baremetalClient        bmscl.BmpClient
+	sshClient              util.SshClient
 	agentEnvServiceFactory AgentEnvServiceFactory
+	diskAttachMode         string
+	waitPolicies           bslcommon.WaitPolicies
 	logger                 boshlog.Logger
 }
 
-func NewSoftLayerFinder(softLayerClient sl.Client, baremetalClient bmscl.BmpClient, agentEnvServiceFactory AgentEnvServiceFactory, logger boshlog.Logger) Finder {
+func NewSoftLayerFinder(softLayerClient sl.Client, baremetalClient bmscl.BmpClient, sshClient util.SshClient, agentEnvServiceFactory AgentEnvServiceFactory, diskAttachMode string, waitPolicies bslcommon.WaitPolicies, logger boshlog.Logger) Finder {
 	return &softLayerFinder{
 		softLayerClient:        softLayerClient,
 		baremetalClient:        baremetalClient,
+		sshClient:              sshClient,
 		agentEnvServiceFactory: agentEnvServiceFactory,
+		diskAttachMode:         diskAttachMode,
+		waitPolicies:           waitPolicies,
 		logger:                 logger,
 	}
 }
@@ -31,17 +43,62 @@ func (f *softLayerFinder) Find(vmID int) (VM, bool, error) {
 	var vm VM
 	virtualGuest, err := bslcommon.GetObjectDetailsOnVirtualGuest(f.softLayerClient, vmID)
 	if err != nil {
+		if !bslcommon.IsSoftLayerNotFoundError(err) {
+			return nil, false, bosherr.WrapErrorf(err, "Finding virtual guest `%d`", vmID)
+		}
+
 		hardware, err := bslcommon.GetObjectDetailsOnHardware(f.softLayerClient, vmID)
 		if err != nil {
-			return nil, false, bosherr.Errorf("Failed to find VM or Baremetal %d", vmID)
+			if bslcommon.IsSoftLayerNotFoundError(err) {
+				return nil, false, nil
+			}
+
+			return nil, false, bosherr.WrapErrorf(err, "Finding hardware `%d`", vmID)
 		}
-		vm = NewSoftLayerHardware(hardware, f.softLayerClient, f.baremetalClient, util.GetSshClient(), f.logger)
+		vm = NewSoftLayerHardware(hardware, f.softLayerClient, f.baremetalClient, f.sshClient, f.diskAttachMode, f.waitPolicies, f.logger)
 	} else {
-		vm = NewSoftLayerVirtualGuest(virtualGuest, f.softLayerClient, util.GetSshClient(), f.logger)
+		vm = NewSoftLayerVirtualGuest(virtualGuest, f.softLayerClient, f.sshClient, f.diskAttachMode, f.waitPolicies, f.logger)
 	}
 
-	softlayerFileService := NewSoftlayerFileService(util.GetSshClient(), f.logger)
+	softlayerFileService := NewSoftlayerFileService(f.sshClient, f.logger)
 	agentEnvService := f.agentEnvServiceFactory.New(vm, softlayerFileService)
 	vm.SetAgentEnvService(agentEnvService)
 	return vm, true, nil
 }
+
+func (f *softLayerFinder) FindByPrimaryBackendIp(ip string) (VM, bool, error) {
+	accountService, err := f.softLayerClient.GetSoftLayer_Account_Service()
+	if err != nil {
+		return nil, false, bosherr.WrapError(err, "Cannot get softlayer account service.")
+	}
+
+	virtualGuests, err := accountService.GetVirtualGuestsByFilter(fmt.Sprintf(`{"virtualGuests":{"primaryBackendIpAddress":{"operation":"%s"}}}`, ip))
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Finding virtual guest with primary backend ip `%s`", ip)
+	}
+
+	if len(virtualGuests) > 0 {
+		return f.Find(virtualGuests[0].Id)
+	}
+
+	response, errorCode, err := f.softLayerClient.GetHttpClient().DoRawHttpRequestWithObjectFilterAndObjectMask("SoftLayer_Account/getHardware.json", []string{"id"}, fmt.Sprintf(`{"hardware":{"primaryBackendIpAddress":{"operation":"%s"}}}`, ip), "GET", new(bytes.Buffer))
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Finding hardware with primary backend ip `%s`", ip)
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return nil, false, bslcommon.NewSoftLayerHttpError(errorCode, "Finding hardware with primary backend ip `%s`", ip)
+	}
+
+	hardwares := []datatypes.SoftLayer_Hardware{}
+	err = json.Unmarshal(response, &hardwares)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Unmarshalling hardware with primary backend ip `%s`", ip)
+	}
+
+	if len(hardwares) > 0 {
+		return f.Find(hardwares[0].Id)
+	}
+
+	return nil, false, nil
+}
@@ -6,12 +6,14 @@ import (
 
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	testhelpers "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
 	fakebmsclient "github.com/cloudfoundry-community/bosh-softlayer-tools/clients/fakes"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
+	fakesutil "github.com/cloudfoundry/bosh-softlayer-cpi/util/fakes"
 	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
 )
 
@@ -33,7 +35,10 @@ var _ = Describe("SoftLayerFinder", func() {
 		finder = NewSoftLayerFinder(
 			softLayerClient,
 			baremetalClient,
+			&fakesutil.FakeSshClient{},
 			agentEnvServiceFactory,
+			DiskAttachModeSsh,
+			bslcommon.DefaultWaitPolicies(),
 			logger,
 		)
 	})
@@ -57,5 +62,89 @@ var _ = Describe("SoftLayerFinder", func() {
 			})
 		})
 
+		Context("when neither a virtual guest nor a hardware has the VM ID", func() {
+			BeforeEach(func() {
+				vmID = 1234567
+				testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+					"SoftLayer_Virtual_Guest_Service_getObject.json",
+					"SoftLayer_Virtual_Guest_Service_getObject.json",
+				})
+				softLayerClient.FakeHttpClient.DoRawHttpRequestError = bslcommon.NewSoftLayerHttpError(404, "fake-not-found")
+			})
+
+			It("returns found as false without error", func() {
+				vm, found, err := finder.Find(vmID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeFalse())
+				Expect(vm).To(BeNil())
+			})
+		})
+
+		Context("when looking up the VM fails", func() {
+			BeforeEach(func() {
+				vmID = 1234567
+				testhelpers.SetTestFixtureForFakeSoftLayerClient(softLayerClient, "SoftLayer_Virtual_Guest_Service_getObject.json")
+				softLayerClient.FakeHttpClient.DoRawHttpRequestError = bslcommon.NewSoftLayerHttpError(500, "fake-server-error")
+			})
+
+			It("returns a retryable error", func() {
+				_, found, err := finder.Find(vmID)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Finding virtual guest `1234567`"))
+				Expect(bslcommon.IsSoftLayerTransientError(err)).To(BeTrue())
+				Expect(found).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("FindByPrimaryBackendIp", func() {
+		Context("when a virtual guest has the primary backend ip", func() {
+			BeforeEach(func() {
+				testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+					"SoftLayer_Account_Service_getVirtualGuests.json",
+					"SoftLayer_Virtual_Guest_Service_getObject.json",
+				})
+			})
+
+			It("finds and returns the virtual guest", func() {
+				vm, found, err := finder.FindByPrimaryBackendIp("10.0.0.1")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(vm.ID()).To(Equal(1234567))
+			})
+		})
+
+		Context("when a hardware has the primary backend ip", func() {
+			BeforeEach(func() {
+				testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+					"SoftLayer_Account_Service_getVirtualGuests_None.json",
+					"SoftLayer_Account_Service_getHardware.json",
+					"SoftLayer_Virtual_Guest_Service_getObject.json",
+				})
+			})
+
+			It("finds and returns the VM", func() {
+				vm, found, err := finder.FindByPrimaryBackendIp("10.0.0.1")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(vm.ID()).To(Equal(1234567))
+			})
+		})
+
+		Context("when nothing has the primary backend ip", func() {
+			BeforeEach(func() {
+				testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+					"SoftLayer_Account_Service_getVirtualGuests_None.json",
+					"SoftLayer_Account_Service_getHardware_None.json",
+				})
+			})
+
+			It("returns found as false", func() {
+				vm, found, err := finder.FindByPrimaryBackendIp("10.0.0.1")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeFalse())
+				Expect(vm).To(BeNil())
+			})
+		})
 	})
 })
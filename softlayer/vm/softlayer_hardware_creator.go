@@ -1,68 +1,92 @@
 package vm
 
 import (
+	"context"
 	"fmt"
-	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
 
 	bmslc "github.com/cloudfoundry-community/bosh-softlayer-tools/clients"
 	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
+	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
 	sl "github.com/maximilien/softlayer-go/softlayer"
 )
 
 type baremetalCreator struct {
 	softLayerClient        sl.Client
+	sshClient              util.SshClient
 	bmsClient              bmslc.BmpClient
 	agentEnvServiceFactory AgentEnvServiceFactory
 
 	agentOptions AgentOptions
+	waitPolicies bslcommon.WaitPolicies
 	logger       boshlog.Logger
 	vmFinder     Finder
 }
 
-func NewBaremetalCreator(vmFinder Finder, softLayerClient sl.Client, bmsClient bmslc.BmpClient, agentOptions AgentOptions, logger boshlog.Logger) VMCreator {
-	bslcommon.TIMEOUT = 15 * time.Minute
-	bslcommon.POLLING_INTERVAL = 5 * time.Second
-
+func NewBaremetalCreator(vmFinder Finder, softLayerClient sl.Client, bmsClient bmslc.BmpClient, sshClient util.SshClient, agentOptions AgentOptions, waitPolicies bslcommon.WaitPolicies, logger boshlog.Logger) VMCreator {
 	return &baremetalCreator{
 		vmFinder:        vmFinder,
 		softLayerClient: softLayerClient,
+		sshClient:       sshClient,
 		bmsClient:       bmsClient,
 		agentOptions:    agentOptions,
+		waitPolicies:    waitPolicies,
 		logger:          logger,
 	}
 }
 
-func (c *baremetalCreator) Create(agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
-	for _, network := range networks {
-		switch network.Type {
-		case "dynamic":
-			if len(network.IP) == 0 {
-				return c.createByBaremetal(agentID, stemcell, cloudProps, networks, env)
-			} else {
-				return c.createByOSReload(agentID, stemcell, cloudProps, networks, env)
-			}
-		case "manual":
-			return nil, bosherr.Error("Manual networking is not currently supported")
-		case "vip":
-			return nil, bosherr.Error("SoftLayer Not Support VIP netowrk")
-		default:
-			return nil, bosherr.Errorf("Softlayer Not Support This Kind Of Network: %s", network.Type)
+func (c *baremetalCreator) Create(ctx context.Context, agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
+	err := networks.ValidateComponents(cloudProps.PrivateNetworkOnlyFlag)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Validating network components")
+	}
+
+	reloadNetwork, err := networks.ReloadNetwork()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Selecting default gateway network")
+	}
+
+	var vm VM
+	if len(reloadNetwork) > 0 {
+		vm, err = c.createByOSReload(ctx, agentID, stemcell, cloudProps, networks, networks[reloadNetwork].IP, env)
+	} else {
+		var resolvedNetworks Networks
+		resolvedNetworks, err = ResolveManualNetworks(c.softLayerClient, &cloudProps, networks)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Resolving manual networks")
 		}
+
+		vm, err = c.createByBaremetal(ctx, agentID, stemcell, cloudProps, resolvedNetworks, env)
 	}
 
-	return nil, nil
+	if err != nil {
+		return nil, err
+	}
+
+	return vm, nil
 }
 
-func (c *baremetalCreator) createByBaremetal(agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
-	hardwareId, err := c.provisionBaremetal(cloudProps.VmNamePrefix, cloudProps.BaremetalStemcell, cloudProps.BaremetalNetbootImage)
+func (c *baremetalCreator) createByBaremetal(ctx context.Context, agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
+	hardwareId, err := c.provisionBaremetal(ctx, cloudProps.VmNamePrefix, cloudProps.BaremetalStemcell, cloudProps.BaremetalNetbootImage)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Create baremetal error")
 	}
 
+	hardware, err := c.setUpBaremetal(ctx, hardwareId, agentID, cloudProps, networks, env)
+	if err != nil {
+		rollbackFailedVM(c.vmFinder, hardwareId, cloudProps, c.logger)
+		return nil, err
+	}
+
+	return hardware, nil
+}
+
+// setUpBaremetal prepares a freshly provisioned baremetal server for the agent
+func (c *baremetalCreator) setUpBaremetal(ctx context.Context, hardwareId int, agentID string, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
 	hardware, found, err := c.vmFinder.Find(hardwareId)
 	if err != nil || !found {
 		return nil, bosherr.WrapErrorf(err, "Cannot find hardware with id: %d.", hardwareId)
@@ -82,7 +106,12 @@ func (c *baremetalCreator) createByBaremetal(agentID string, stemcell bslcstem.S
 
 	agentEnv := CreateAgentUserData(agentID, cloudProps, networks, env, c.agentOptions)
 
-	err = hardware.UpdateAgentEnv(agentEnv)
+	err = c.sshClient.ForgetHostKey(hardware.GetPrimaryBackendIP())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Forgetting previous host key of VM `%d`", hardware.ID())
+	}
+
+	err = hardware.UpdateAgentEnv(ctx, agentEnv)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Updating VM's agent env")
 	}
@@ -94,10 +123,15 @@ func (c *baremetalCreator) createByBaremetal(agentID string, stemcell bslcstem.S
 		}
 	}
 
+	err = RouteVipNetworks(c.softLayerClient, networks, hardware.GetPrimaryIP())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Routing VIPs to VM '%d'", hardware.ID())
+	}
+
 	return hardware, nil
 }
 
-func (c *baremetalCreator) createByOSReload(agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
+func (c *baremetalCreator) createByOSReload(ctx context.Context, agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, reloadIP string, env Environment) (VM, error) {
 	if len(cloudProps.BaremetalStemcell) == 0 {
 		return nil, bosherr.Error("No stemcell provided to do os_reload.")
 	}
@@ -107,9 +141,9 @@ func (c *baremetalCreator) createByOSReload(agentID string, stemcell bslcstem.St
 		return nil, bosherr.WrapError(err, "Creating HardwareService from SoftLayer client")
 	}
 
-	hardware, err := hardwareService.FindByIpAddress(networks.First().IP)
+	hardware, err := hardwareService.FindByIpAddress(reloadIP)
 	if err != nil || hardware.Id == 0 {
-		return nil, bosherr.WrapErrorf(err, "Could not find hardware by ip address: %s", networks.First().IP)
+		return nil, bosherr.WrapErrorf(err, "Could not find hardware by ip address: %s", reloadIP)
 	}
 
 	c.logger.Info(SOFTLAYER_VM_CREATOR_LOG_TAG, fmt.Sprintf("OS reload on Hardware %d using stemcell %d", hardware.Id, stemcell.ID()))
@@ -119,7 +153,7 @@ func (c *baremetalCreator) createByOSReload(agentID string, stemcell bslcstem.St
 		return nil, bosherr.WrapErrorf(err, "Cannot find hardware with id: %d", hardware.Id)
 	}
 
-	err = vm.ReloadOSForBaremetal(cloudProps.BaremetalStemcell, cloudProps.BaremetalNetbootImage)
+	err = vm.ReloadOSForBaremetal(ctx, cloudProps.BaremetalStemcell, cloudProps.BaremetalNetbootImage)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Failed to reload OS")
 	}
@@ -147,7 +181,12 @@ func (c *baremetalCreator) createByOSReload(agentID string, stemcell bslcstem.St
 		return nil, bosherr.WrapErrorf(err, "Cannot create agent env for baremetal with id: %d.", vm.ID())
 	}
 
-	err = vm.UpdateAgentEnv(agentEnv)
+	err = c.sshClient.ForgetHostKey(vm.GetPrimaryBackendIP())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Forgetting previous host key of VM `%d`", vm.ID())
+	}
+
+	err = vm.UpdateAgentEnv(ctx, agentEnv)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Updating VM's agent env")
 	}
@@ -159,11 +198,16 @@ func (c *baremetalCreator) createByOSReload(agentID string, stemcell bslcstem.St
 		}
 	}
 
+	err = RouteVipNetworks(c.softLayerClient, networks, vm.GetPrimaryIP())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Routing VIPs to VM '%d'", vm.ID())
+	}
+
 	return vm, nil
 }
 
 // Private methods
-func (c *baremetalCreator) provisionBaremetal(server_name string, stemcell string, netboot_image string) (int, error) {
+func (c *baremetalCreator) provisionBaremetal(ctx context.Context, server_name string, stemcell string, netboot_image string) (int, error) {
 	provisioningBaremetalInfo := bmslc.ProvisioningBaremetalInfo{
 		VmNamePrefix:     server_name,
 		Bm_stemcell:      stemcell,
@@ -174,33 +218,43 @@ func (c *baremetalCreator) provisionBaremetal(server_name string, stemcell strin
 		return 0, bosherr.WrapErrorf(err, "Failed to provisioning baremetal")
 	}
 
-	task_id := createBaremetalResponse.Data.TaskId
-	bslcommon.TIMEOUT = 120 * time.Minute
-	totalTime := time.Duration(0)
-	for totalTime < bslcommon.TIMEOUT {
+	return waitForBaremetalTask(ctx, c.bmsClient, createBaremetalResponse.Data.TaskId, c.waitPolicies.Create)
+}
 
-		taskOutput, err := c.bmsClient.TaskJsonOutput(task_id, "task")
+// waitForBaremetalTask waits for the BMS provisioning task to complete and
+// returns the id of the server it provisioned
+func waitForBaremetalTask(ctx context.Context, bmsClient bmslc.BmpClient, task_id int, waitPolicy bslcommon.WaitPolicy) (int, error) {
+	var serverId int
+	done, err := bslcommon.NewPoller(waitPolicy, clock.NewClock()).Poll(ctx, func() (bool, error) {
+		taskOutput, err := bmsClient.TaskJsonOutput(task_id, "task")
 		if err != nil {
-			return 0, bosherr.WrapErrorf(err, "Failed to get state with task_id: %d", task_id)
+			return false, bosherr.WrapErrorf(err, "Failed to get state with task_id: %d", task_id)
 		}
 
 		info := taskOutput.Data["info"].(map[string]interface{})
 		switch info["status"].(string) {
 		case "failed":
-			return 0, bosherr.Errorf("Failed to install the stemcell: %v", taskOutput)
+			return false, bosherr.Errorf("Failed to install the stemcell: %v", taskOutput)
 
 		case "completed":
-			serverOutput, err := c.bmsClient.TaskJsonOutput(task_id, "server")
+			serverOutput, err := bmsClient.TaskJsonOutput(task_id, "server")
 			if err != nil {
-				return 0, bosherr.WrapErrorf(err, "Failed to get server_id with task_id: %d", task_id)
+				return false, bosherr.WrapErrorf(err, "Failed to get server_id with task_id: %d", task_id)
 			}
 			info = serverOutput.Data["info"].(map[string]interface{})
-			return int(info["id"].(float64)), nil
+			serverId = int(info["id"].(float64))
+			return true, nil
 		default:
-			totalTime += bslcommon.POLLING_INTERVAL
-			time.Sleep(bslcommon.POLLING_INTERVAL)
+			return false, nil
 		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if !done {
+		return 0, bosherr.Error("Provisioning baremetal timeout")
 	}
 
-	return 0, bosherr.Error("Provisioning baremetal timeout")
+	return serverId, nil
 }
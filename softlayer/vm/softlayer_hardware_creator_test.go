@@ -1,7 +1,9 @@
 package vm_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"time"
@@ -12,6 +14,7 @@ import (
 
 	fakebmsclient "github.com/cloudfoundry-community/bosh-softlayer-tools/clients/fakes"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
+	util "github.com/cloudfoundry/bosh-softlayer-cpi/util"
 	fakesutil "github.com/cloudfoundry/bosh-softlayer-cpi/util/fakes"
 	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
 
@@ -31,6 +34,7 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 		sshClient       *fakesutil.FakeSshClient
 		vmFinder        *fakevm.FakeFinder
 		agentOptions    AgentOptions
+		waitPolicies    bslcommon.WaitPolicies
 		logger          boshlog.Logger
 		creator         VMCreator
 	)
@@ -40,6 +44,7 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 		baremetalClient = fakebmsclient.NewFakeBmpClient("fake-username", "fake-api-key", "fake-url", "fake-config-path")
 		sshClient = &fakesutil.FakeSshClient{}
 		agentOptions = AgentOptions{Mbus: "fake-mbus"}
+		waitPolicies = bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(2*time.Second, 1*time.Second))
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 		vmFinder = &fakevm.FakeFinder{}
 
@@ -47,11 +52,11 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 			vmFinder,
 			softLayerClient,
 			baremetalClient,
+			sshClient,
 			agentOptions,
+			waitPolicies,
 			logger,
 		)
-		bslcommon.TIMEOUT = 2 * time.Second
-		bslcommon.POLLING_INTERVAL = 1 * time.Second
 	})
 
 	Describe("#Create", func() {
@@ -66,7 +71,7 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 		Context("valid arguments", func() {
 			BeforeEach(func() {
 				agentID = "fake-agent-id"
-				stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, logger)
+				stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, waitPolicies, logger)
 
 				env = Environment{}
 
@@ -127,8 +132,8 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 						expectedCmdResults := []string{
 							"",
 						}
-						sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-							return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+						sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+							return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 						}
 
 						baremetalClient.ProvisioningBaremetalResponse = bmsclients.CreateBaremetalsResponse{
@@ -163,7 +168,7 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 						baremetalClient.TaskJsonResponses = []bmsclients.TaskJsonResponse{taskJson, serverJson}
 
 						setFakeSoftlayerClientFixtures(softLayerClient)
-						vm, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(vm.ID()).To(Equal(1234567))
 					})
@@ -202,8 +207,8 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 						expectedCmdResults := []string{
 							"",
 						}
-						sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-							return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+						sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+							return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 						}
 
 						baremetalClient.ProvisioningBaremetalResponse = bmsclients.CreateBaremetalsResponse{
@@ -237,10 +242,47 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 
 						baremetalClient.TaskJsonResponses = []bmsclients.TaskJsonResponse{taskJson, serverJson}
 						setFakeSoftlayerClientFixtures(softLayerClient)
-						vm, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(vm.ID()).To(Equal(1234567))
 					})
+
+					It("releases the baremetal server when updating the agent env fails", func() {
+						cloudProps = VMCloudProperties{
+							VmNamePrefix:          "bosh-test",
+							BoshIp:                "10.0.0.1",
+							Baremetal:             true,
+							BaremetalStemcell:     "fake-stemcell",
+							BaremetalNetbootImage: "fake-netboot-image",
+						}
+
+						fakeVM := fakevm.NewFakeVM(1234567)
+						fakeVM.UpdateAgentEnvErr = errors.New("fake-update-agent-env-error")
+						vmFinder.FindVM = fakeVM
+
+						baremetalClient.ProvisioningBaremetalResponse = bmsclients.CreateBaremetalsResponse{
+							Status: 200,
+							Data: bmsclients.TaskInfo{
+								TaskId: 1234567,
+							},
+						}
+
+						taskJson := bmsclients.TaskJsonResponse{}
+						err := json.Unmarshal([]byte(`{"status": 200, "data": {"info": {"status": "completed"}}}`), &taskJson)
+						Expect(err).ToNot(HaveOccurred())
+
+						serverJson := bmsclients.TaskJsonResponse{}
+						err = json.Unmarshal([]byte(`{"status": 200, "data": {"info": {"id": 1234567}}}`), &serverJson)
+						Expect(err).ToNot(HaveOccurred())
+
+						baremetalClient.TaskJsonResponses = []bmsclients.TaskJsonResponse{taskJson, serverJson}
+
+						_, err = creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-update-agent-env-error"))
+						Expect(fakeVM.DeleteCalled).To(BeTrue())
+						Expect(sshClient.ForgetHostKeyCallCount()).To(Equal(1))
+					})
 				})
 			})
 		})
@@ -249,7 +291,7 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 			Context("missing correct VMProperties", func() {
 				BeforeEach(func() {
 					agentID = "fake-agent-id"
-					stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, logger)
+					stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, waitPolicies, logger)
 					networks = Networks{}
 					env = Environment{}
 
@@ -278,7 +320,7 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 						Datacenter: sldatatypes.Datacenter{Name: "fake-datacenter"},
 					}
 
-					_, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+					_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 					Expect(err).To(HaveOccurred())
 				})
 
@@ -288,7 +330,7 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 						Datacenter: sldatatypes.Datacenter{Name: "fake-datacenter"},
 					}
 
-					_, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+					_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 					Expect(err).To(HaveOccurred())
 				})
 
@@ -298,7 +340,7 @@ var _ = Describe("SoftLayer_Hardware_Creator", func() {
 						MaxMemory: 1024,
 					}
 
-					_, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+					_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 					Expect(err).To(HaveOccurred())
 				})
 			})
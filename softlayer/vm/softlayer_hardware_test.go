@@ -1,6 +1,7 @@
 package vm_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"time"
@@ -23,6 +24,7 @@ import (
 	fakedisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk/fakes"
 	fakestemcell "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell/fakes"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
+	util "github.com/cloudfoundry/bosh-softlayer-cpi/util"
 	fakesutil "github.com/cloudfoundry/bosh-softlayer-cpi/util/fakes"
 	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
 
@@ -38,6 +40,7 @@ var _ = Describe("SoftLayerHardware", func() {
 		logger              boshlog.Logger
 		vm                  VM
 		stemcell            *fakestemcell.FakeStemcell
+		hardware            datatypes.SoftLayer_Hardware
 	)
 
 	BeforeEach(func() {
@@ -47,7 +50,7 @@ var _ = Describe("SoftLayerHardware", func() {
 		agentEnvService = &fakevm.FakeAgentEnvService{}
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 
-		hardware := datatypes.SoftLayer_Hardware{
+		hardware = datatypes.SoftLayer_Hardware{
 			BareMetalInstanceFlag: 1,
 			Domain:                "fake-domain.com",
 			Hostname:              "fake-hostname",
@@ -67,37 +70,37 @@ var _ = Describe("SoftLayerHardware", func() {
 			},
 		}
 
-		vm = NewSoftLayerHardware(hardware, fakeSoftLayerClient, fakeBaremetalClient, sshClient, logger)
+		vm = NewSoftLayerHardware(hardware, fakeSoftLayerClient, fakeBaremetalClient, sshClient, DiskAttachModeSsh, bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(2*time.Second, 1*time.Second)), logger)
 		vm.SetAgentEnvService(agentEnvService)
 	})
 
 	Describe("Delete", func() {
 		It("deletes the VM successfully", func() {
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
 			expectedCmdResults := []string{
 				"",
 			}
 
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
 
 			fakeBaremetalClient.UpdateStateResponse = bmsclients.UpdateStateResponse{
 				Status: 200,
 			}
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, []string{
+				"SoftLayer_Account_Service_getGlobalIpRecords.json",
+				"SoftLayer_Network_Subnet_IpAddress_Global_Service_unroute_true.json",
+			})
 
-			err := vm.Delete("fake-agentID")
+			err := vm.Delete(context.Background(), "fake-agentID")
 			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeSoftLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Network_Subnet_IpAddress_Global/5555/unroute.json"))
+			Expect(agentEnvService.FetchCalled).To(BeFalse())
 		})
 	})
 
 	Describe("Reboot", func() {
 		It("returns unsupport error", func() {
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
 			err := vm.Reboot()
 			Expect(err).To(HaveOccurred())
 		})
@@ -110,7 +113,7 @@ var _ = Describe("SoftLayerHardware", func() {
 			})
 
 			It("returns unsupport error", func() {
-				err := vm.ReloadOS(stemcell)
+				err := vm.ReloadOS(context.Background(), stemcell)
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -162,8 +165,18 @@ var _ = Describe("SoftLayerHardware", func() {
 		})
 
 		It("returns the expected network", func() {
-			err := vm.ConfigureNetworks(networks)
+			err := vm.ConfigureNetworks(context.Background(), networks)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("GetAttachedDiskIds", func() {
+		It("returns the ids of the attached iSCSI volumes", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fakeSoftLayerClient, "SoftLayer_Hardware_Service_getAttachedNetworkStorages.json")
+
+			diskIds, err := vm.GetAttachedDiskIds()
 			Expect(err).ToNot(HaveOccurred())
+			Expect(diskIds).To(Equal([]int{1234, 9012}))
 		})
 	})
 
@@ -233,13 +246,10 @@ var _ = Describe("SoftLayerHardware", func() {
 				"",
 				expectedDmSetupLs1,
 			}
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.AttachDisk(disk)
+			err := vm.AttachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -254,13 +264,10 @@ var _ = Describe("SoftLayerHardware", func() {
 				"",
 				expectedPartitions2,
 			}
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.AttachDisk(disk)
+			err := vm.AttachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -276,24 +283,56 @@ var _ = Describe("SoftLayerHardware", func() {
 				expectedDmSetupLs2,
 			}
 
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.AttachDisk(disk)
+			err := vm.AttachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("reports error when failed to attach the iSCSI volume", func() {
 
-			sshClient.ExecCommandReturns("fake-result", errors.New("fake-error"))
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
+			sshClient.RunReturns(util.CommandResult{Stdout: "fake-result"}, errors.New("fake-error"))
+			err := vm.AttachDisk(context.Background(), disk)
+			Expect(err).To(HaveOccurred())
+		})
 
-			err := vm.AttachDisk(disk)
+		It("includes the remote stderr when a command exits with a non-zero status", func() {
+			sshClient.RunReturns(util.CommandResult{Stderr: "fake-stderr", ExitStatus: 127}, nil)
+			err := vm.AttachDisk(context.Background(), disk)
 			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("exited with status 127: fake-stderr"))
+
+			_, _, _, _, timeout := sshClient.RunArgsForCall(0)
+			Expect(timeout).To(Equal(REMOTE_COMMAND_TIMEOUT))
+		})
+
+		Context("in agent attach mode", func() {
+			BeforeEach(func() {
+				vm = NewSoftLayerHardware(hardware, fakeSoftLayerClient, fakeBaremetalClient, sshClient, DiskAttachModeAgent, bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(2*time.Second, 1*time.Second)), logger)
+				vm.SetAgentEnvService(agentEnvService)
+			})
+
+			It("grants access and hands the iSCSI target to the agent without SSH", func() {
+				err := vm.AttachDisk(context.Background(), disk)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(sshClient.RunCallCount()).To(Equal(0))
+				Expect(sshClient.UploadFileCallCount()).To(Equal(0))
+				Expect(agentEnvService.UpdateAgentEnv.Disks.Persistent).To(Equal(PersistentSpec{
+					"1234": PersistentDiskSettings{
+						ID:       "1234",
+						VolumeID: "1234",
+						Lun:      "0",
+						ISCSISettings: ISCSISettings{
+							InitiatorName: "fake-iqn",
+							Target:        "fake-ip",
+							Username:      "fake-username",
+							Password:      "fake-password",
+						},
+					},
+				}))
+			})
 		})
 	})
 
@@ -358,13 +397,10 @@ iscsiadm: No records found
 				"",
 				expectStartOpenIscsi,
 			}
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.DetachDisk(disk)
+			err := vm.DetachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -379,23 +415,50 @@ iscsiadm: No records found
 				expectStartOpenIscsi,
 				expectRestartMultipathd,
 			}
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.DetachDisk(disk)
+			err := vm.DetachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("reports error when failed to detach iSCSI volume", func() {
-			sshClient.ExecCommandReturns("fake-result", errors.New("fake-error"))
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.DetachDisk(disk)
+			sshClient.RunReturns(util.CommandResult{Stdout: "fake-result"}, errors.New("fake-error"))
+			err := vm.DetachDisk(context.Background(), disk)
 			Expect(err).To(HaveOccurred())
 		})
+
+		Context("in agent attach mode", func() {
+			BeforeEach(func() {
+				vm = NewSoftLayerHardware(hardware, fakeSoftLayerClient, fakeBaremetalClient, sshClient, DiskAttachModeAgent, bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(2*time.Second, 1*time.Second)), logger)
+				vm.SetAgentEnvService(agentEnvService)
+
+				// Nothing is fetched from the volume before revoking access
+				fakeSoftLayerClient.FakeHttpClient.DoRawHttpRequestResponses = nil
+				testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, []string{
+					"SoftLayer_Network_Storage_Service_getAllowedVirtualGuests.json",
+					"SoftLayer_Network_Storage_Service_removeAccessFromVirtualGuest.json",
+				})
+
+				agentEnvService.FetchAgentEnv = AgentEnv{
+					Disks: DisksSpec{
+						Persistent: PersistentSpec{
+							"1234": PersistentDiskSettings{ID: "1234"},
+							"5678": PersistentDiskSettings{ID: "5678"},
+						},
+					},
+				}
+			})
+
+			It("revokes access and drops the disk from the agent env without SSH", func() {
+				err := vm.DetachDisk(context.Background(), disk)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(sshClient.RunCallCount()).To(Equal(0))
+				Expect(agentEnvService.UpdateAgentEnv.Disks.Persistent).To(Equal(PersistentSpec{
+					"5678": PersistentDiskSettings{ID: "5678"},
+				}))
+			})
+		})
 	})
 })
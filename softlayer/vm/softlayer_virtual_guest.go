@@ -2,19 +2,18 @@ package vm
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"regexp"
 	"strconv"
 	"strings"
-	"text/template"
-	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
 
+	slcommon "github.com/maximilien/softlayer-go/common"
 	sl "github.com/maximilien/softlayer-go/softlayer"
 
 	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
@@ -34,15 +33,16 @@ type softLayerVirtualGuest struct {
 	softLayerClient sl.Client
 	sshClient       util.SshClient
 
+	diskAttachMode string
+
 	agentEnvService AgentEnvService
 
+	waitPolicies bslcommon.WaitPolicies
+
 	logger boshlog.Logger
 }
 
-func NewSoftLayerVirtualGuest(virtualGuest datatypes.SoftLayer_Virtual_Guest, softLayerClient sl.Client, sshClient util.SshClient, logger boshlog.Logger) VM {
-	bslcommon.TIMEOUT = 60 * time.Minute
-	bslcommon.POLLING_INTERVAL = 10 * time.Second
-
+func NewSoftLayerVirtualGuest(virtualGuest datatypes.SoftLayer_Virtual_Guest, softLayerClient sl.Client, sshClient util.SshClient, diskAttachMode string, waitPolicies bslcommon.WaitPolicies, logger boshlog.Logger) VM {
 	return &softLayerVirtualGuest{
 		id: virtualGuest.Id,
 
@@ -51,12 +51,43 @@ func NewSoftLayerVirtualGuest(virtualGuest datatypes.SoftLayer_Virtual_Guest, so
 		softLayerClient: softLayerClient,
 		sshClient:       sshClient,
 
+		diskAttachMode: diskAttachMode,
+
+		waitPolicies: waitPolicies,
+
 		logger: logger,
 	}
 }
 
 func (vm *softLayerVirtualGuest) ID() int { return vm.id }
 
+func (vm *softLayerVirtualGuest) GetAttachedDiskIds() ([]int, error) {
+	diskIds := []int{}
+
+	response, errorCode, err := vm.softLayerClient.GetHttpClient().DoRawHttpRequestWithObjectMask(fmt.Sprintf("SoftLayer_Virtual_Guest/%d/getAllowedNetworkStorage.json", vm.ID()), []string{"id", "nasType"}, "GET", new(bytes.Buffer))
+	if err != nil {
+		return diskIds, bosherr.WrapErrorf(err, "Getting allowed network storage of virtual guest `%d`", vm.ID())
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return diskIds, bslcommon.NewSoftLayerHttpError(errorCode, "Getting allowed network storage of virtual guest `%d`", vm.ID())
+	}
+
+	volumes := []datatypes.SoftLayer_Network_Storage{}
+	err = json.Unmarshal(response, &volumes)
+	if err != nil {
+		return diskIds, bosherr.WrapErrorf(err, "Unmarshalling allowed network storage of virtual guest `%d`", vm.ID())
+	}
+
+	for _, volume := range volumes {
+		if strings.EqualFold(volume.NasType, "ISCSI") {
+			diskIds = append(diskIds, volume.Id)
+		}
+	}
+
+	return diskIds, nil
+}
+
 func (vm *softLayerVirtualGuest) GetDataCenterId() int {
 	return vm.virtualGuest.Datacenter.Id
 }
@@ -86,7 +117,7 @@ func (vm *softLayerVirtualGuest) GetFullyQualifiedDomainName() string {
 
 func (vm *softLayerVirtualGuest) SetVcapPassword(encryptedPwd string) (err error) {
 	command := fmt.Sprintf("usermod -p '%s' vcap", encryptedPwd)
-	_, err = vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command)
+	_, err = vm.runCommand(command)
 	if err != nil {
 		return bosherr.WrapError(err, "Shelling out to usermod vcap")
 	}
@@ -100,27 +131,32 @@ func (vm *softLayerVirtualGuest) SetAgentEnvService(agentEnvService AgentEnvServ
 	return nil
 }
 
-func (vm *softLayerVirtualGuest) Delete(agentID string) error {
-	return vm.DeleteVM()
+func (vm *softLayerVirtualGuest) Delete(ctx context.Context, agentID string) error {
+	err := ReleaseGlobalIps(vm.softLayerClient, vm.GetPrimaryIP())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Releasing VIPs of VirtualGuest `%d`", vm.ID())
+	}
+
+	return vm.DeleteVM(ctx)
 }
 
-func (vm *softLayerVirtualGuest) DeleteVM() error {
+func (vm *softLayerVirtualGuest) DeleteVM(ctx context.Context) error {
 	virtualGuestService, err := vm.softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating SoftLayer VirtualGuestService from client")
 	}
 
 	vmCID := vm.ID()
-	err = bslcommon.WaitForVirtualGuestToHaveNoRunningTransactions(vm.softLayerClient, vmCID)
+	err = bslcommon.WaitForVirtualGuestToHaveNoRunningTransactions(ctx, vm.softLayerClient, vmCID, vm.waitPolicies.Delete)
 	if err != nil {
-		if !strings.Contains(err.Error(), "HTTP error code") {
+		if !bslcommon.IsSoftLayerNotFoundError(err) && !bslcommon.IsSoftLayerTransientError(err) {
 			return bosherr.WrapError(err, fmt.Sprintf("Waiting for VirtualGuest `%d` to have no pending transactions before deleting vm", vmCID))
 		}
 	}
 
 	deleted, err := virtualGuestService.DeleteObject(vm.ID())
 	if err != nil {
-		if !strings.Contains(err.Error(), "HTTP error code") {
+		if !bslcommon.IsSoftLayerNotFoundError(err) {
 			return bosherr.WrapError(err, "Deleting SoftLayer VirtualGuest from client")
 		}
 	}
@@ -129,7 +165,7 @@ func (vm *softLayerVirtualGuest) DeleteVM() error {
 		return bosherr.WrapError(nil, "Did not delete SoftLayer VirtualGuest from client")
 	}
 
-	err = vm.postCheckActiveTransactionsForDeleteVM(vm.softLayerClient, vmCID)
+	err = vm.postCheckActiveTransactionsForDeleteVM(ctx, vm.softLayerClient, vmCID)
 	if err != nil {
 		return err
 	}
@@ -155,7 +191,7 @@ func (vm *softLayerVirtualGuest) Reboot() error {
 	return nil
 }
 
-func (vm *softLayerVirtualGuest) ReloadOS(stemcell bslcstem.Stemcell) error {
+func (vm *softLayerVirtualGuest) ReloadOS(ctx context.Context, stemcell bslcstem.Stemcell) error {
 	reload_OS_Config := sldatatypes.Image_Template_Config{
 		ImageTemplateId: strconv.Itoa(stemcell.ID()),
 	}
@@ -165,7 +201,7 @@ func (vm *softLayerVirtualGuest) ReloadOS(stemcell bslcstem.Stemcell) error {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	err = bslcommon.WaitForVirtualGuestToHaveNoRunningTransactions(vm.softLayerClient, vm.ID())
+	err = bslcommon.WaitForVirtualGuestToHaveNoRunningTransactions(ctx, vm.softLayerClient, vm.ID(), vm.waitPolicies.OSReload)
 	if err != nil {
 		return bosherr.WrapError(err, fmt.Sprintf("Waiting for VirtualGuest %d to have no pending transactions before os reload", vm.ID()))
 	}
@@ -176,7 +212,7 @@ func (vm *softLayerVirtualGuest) ReloadOS(stemcell bslcstem.Stemcell) error {
 		return bosherr.WrapError(err, "Failed to reload OS on the specified VirtualGuest from SoftLayer client")
 	}
 
-	err = vm.postCheckActiveTransactionsForOSReload(vm.softLayerClient)
+	err = vm.postCheckActiveTransactionsForOSReload(ctx, vm.softLayerClient)
 	if err != nil {
 		return err
 	}
@@ -184,7 +220,7 @@ func (vm *softLayerVirtualGuest) ReloadOS(stemcell bslcstem.Stemcell) error {
 	return nil
 }
 
-func (vm *softLayerVirtualGuest) ReloadOSForBaremetal(string, string) error {
+func (vm *softLayerVirtualGuest) ReloadOSForBaremetal(context.Context, string, string) error {
 	return NotSupportedError{}
 }
 
@@ -211,14 +247,19 @@ func (vm *softLayerVirtualGuest) SetMetadata(vmMetadata VMMetadata) error {
 	return nil
 }
 
-func (vm *softLayerVirtualGuest) ConfigureNetworks(networks Networks) error {
+func (vm *softLayerVirtualGuest) ConfigureNetworks(ctx context.Context, networks Networks) error {
 	oldAgentEnv, err := vm.agentEnvService.Fetch()
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Failed to unmarshal userdata from virutal guest with id: %d.", vm.ID())
 	}
 
+	err = ReconfigureVipNetworks(vm.softLayerClient, oldAgentEnv.Networks, networks, vm.GetPrimaryIP())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Configuring VIPs of virtual guest with id: %d.", vm.ID())
+	}
+
 	oldAgentEnv.Networks = networks
-	err = vm.agentEnvService.Update(oldAgentEnv)
+	err = vm.agentEnvService.Update(ctx, oldAgentEnv)
 	if err != nil {
 		return bosherr.WrapError(err, fmt.Sprintf("Configuring network setting on VirtualGuest with id: `%d`", vm.ID()))
 	}
@@ -226,63 +267,23 @@ func (vm *softLayerVirtualGuest) ConfigureNetworks(networks Networks) error {
 	return nil
 }
 
-func (vm *softLayerVirtualGuest) AttachDisk(disk bslcdisk.Disk) error {
-	volume, err := vm.fetchIscsiVolume(disk.ID())
-	if err != nil {
-		return bosherr.WrapError(err, fmt.Sprintf("Failed to fetch disk `%d`", disk.ID()))
-	}
-
-	networkStorageService, err := vm.softLayerClient.GetSoftLayer_Network_Storage_Service()
-	if err != nil {
-		return bosherr.WrapError(err, "Cannot get network storage service.")
-	}
-
-	allowed, err := networkStorageService.HasAllowedVirtualGuest(disk.ID(), vm.ID())
-
-	totalTime := time.Duration(0)
-	if err == nil && allowed == false {
-		for totalTime < bslcommon.TIMEOUT {
-			allowable, err := networkStorageService.AttachNetworkStorageToVirtualGuest(vm.virtualGuest, disk.ID())
-			if err != nil {
-				if !strings.Contains(err.Error(), "HTTP error code") {
-					return bosherr.WrapError(err, fmt.Sprintf("Granting volume access to vitrual guest %d", vm.ID()))
-				}
-			} else {
-				if allowable {
-					break
-				}
-			}
-
-			totalTime += bslcommon.POLLING_INTERVAL
-			time.Sleep(bslcommon.POLLING_INTERVAL)
-		}
-	}
-	if totalTime >= bslcommon.TIMEOUT {
-		return bosherr.Error("Waiting for grantting access to virutal guest TIME OUT!")
+func (vm *softLayerVirtualGuest) AttachDisk(ctx context.Context, disk bslcdisk.Disk) error {
+	if vm.diskAttachMode == DiskAttachModeAgent {
+		return AttachDiskForAgent(ctx, vm.iscsiAttacher(), vm.agentEnvService, disk.ID())
 	}
 
-	hasMultiPath, err := vm.hasMulitPathToolBasedOnShellScript()
+	devicePath, err := vm.iscsiAttacher().AttachVolume(ctx, disk.ID())
 	if err != nil {
-		return bosherr.WrapError(err, fmt.Sprintf("Failed to get multipath information from virtual guest `%d`", vm.ID()))
+		return err
 	}
 
-	deviceName, err := vm.waitForVolumeAttached(volume, hasMultiPath)
-	if err != nil {
-		return bosherr.WrapError(err, fmt.Sprintf("Failed to attach volume `%d` to virtual guest `%d`", disk.ID(), vm.ID()))
-	}
 	oldAgentEnv, err := vm.agentEnvService.Fetch()
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Failed to unmarshal userdata from virutal guest with id: %d.", vm.ID())
 	}
 
-	var newAgentEnv AgentEnv
-	if hasMultiPath {
-		newAgentEnv = oldAgentEnv.AttachPersistentDisk(strconv.Itoa(disk.ID()), "/dev/mapper/"+deviceName)
-	} else {
-		newAgentEnv = oldAgentEnv.AttachPersistentDisk(strconv.Itoa(disk.ID()), "/dev/"+deviceName)
-	}
-
-	err = vm.agentEnvService.Update(newAgentEnv)
+	newAgentEnv := oldAgentEnv.AttachPersistentDisk(strconv.Itoa(disk.ID()), devicePath)
+	err = vm.agentEnvService.Update(ctx, newAgentEnv)
 	if err != nil {
 		return bosherr.WrapError(err, fmt.Sprintf("Configuring userdata on VirtualGuest with id: `%d`", vm.ID()))
 	}
@@ -290,33 +291,16 @@ func (vm *softLayerVirtualGuest) AttachDisk(disk bslcdisk.Disk) error {
 	return nil
 }
 
-func (vm *softLayerVirtualGuest) DetachDisk(disk bslcdisk.Disk) error {
-	volume, err := vm.fetchIscsiVolume(disk.ID())
-	if err != nil {
-		return bosherr.WrapError(err, fmt.Sprintf("failed in disk `%d`", disk.ID()))
-	}
+func (vm *softLayerVirtualGuest) DetachDisk(ctx context.Context, disk bslcdisk.Disk) error {
+	iscsiAttacher := vm.iscsiAttacher()
 
-	hasMultiPath, err := vm.hasMulitPathToolBasedOnShellScript()
-	if err != nil {
-		return bosherr.WrapError(err, fmt.Sprintf("Failed to get multipath information from virtual guest `%d`", vm.ID()))
+	if vm.diskAttachMode == DiskAttachModeAgent {
+		return DetachDiskForAgent(ctx, iscsiAttacher, vm.agentEnvService, disk.ID())
 	}
 
-	err = vm.detachVolumeBasedOnShellScript(volume, hasMultiPath)
+	err := iscsiAttacher.DetachVolume(disk.ID())
 	if err != nil {
-		return bosherr.WrapErrorf(err, "Failed to detach volume with id %d from virtual guest with id: %d.", volume.Id, vm.ID())
-	}
-
-	networkStorageService, err := vm.softLayerClient.GetSoftLayer_Network_Storage_Service()
-	if err != nil {
-		return bosherr.WrapError(err, "Cannot get network storage service.")
-	}
-
-	allowed, err := networkStorageService.HasAllowedVirtualGuest(disk.ID(), vm.ID())
-	if err == nil && allowed == true {
-		err = networkStorageService.DetachNetworkStorageFromVirtualGuest(vm.virtualGuest, disk.ID())
-	}
-	if err != nil {
-		return bosherr.WrapError(err, fmt.Sprintf("Failed to revoke access of disk `%d` from virtual gusest `%d`", disk.ID(), vm.ID()))
+		return err
 	}
 
 	oldAgentEnv, err := vm.agentEnvService.Fetch()
@@ -325,33 +309,27 @@ func (vm *softLayerVirtualGuest) DetachDisk(disk bslcdisk.Disk) error {
 	}
 
 	newAgentEnv := oldAgentEnv.DetachPersistentDisk(strconv.Itoa(disk.ID()))
-	err = vm.UpdateAgentEnv(newAgentEnv)
+	err = vm.UpdateAgentEnv(ctx, newAgentEnv)
 	if err != nil {
 		return bosherr.WrapError(err, fmt.Sprintf("Configuring userdata on VirtualGuest with id: `%d`", vm.ID()))
 	}
 
 	if len(newAgentEnv.Disks.Persistent) == 1 {
-		for key, devicePath := range newAgentEnv.Disks.Persistent {
-			leftDiskId, err := strconv.Atoi(key)
-			if err != nil {
-				return bosherr.WrapError(err, fmt.Sprintf("Failed to transfer disk id %s from string to int", key))
-			}
-			vm.logger.Debug(SOFTLAYER_VM_LOG_TAG, "Left Disk Id %d", leftDiskId)
-			vm.logger.Debug(SOFTLAYER_VM_LOG_TAG, "Left Disk device path %s", devicePath)
-			volume, err := vm.fetchIscsiVolume(leftDiskId)
-			if err != nil {
-				return bosherr.WrapError(err, fmt.Sprintf("Failed to fetch disk `%d` and virtual gusest `%d`", disk.ID(), vm.ID()))
+		for key, hint := range newAgentEnv.Disks.Persistent {
+			devicePath, ok := hint.(string)
+			if !ok {
+				// Left by the agent attach mode, the agent logs in to it by itself
+				continue
 			}
 
-			_, err = vm.discoveryOpenIscsiTargetsBasedOnShellScript(volume)
+			leftDiskId, err := strconv.Atoi(key)
 			if err != nil {
-				return bosherr.WrapError(err, fmt.Sprintf("Failed to reattach volume `%s` to virtual guest `%d`", key, vm.ID()))
+				return bosherr.WrapError(err, fmt.Sprintf("Failed to transfer disk id %s from string to int", key))
 			}
 
-			command := fmt.Sprintf("sleep 5; mount %s-part1 /var/vcap/store", devicePath)
-			_, err = vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command)
+			err = iscsiAttacher.ReattachVolume(leftDiskId, devicePath)
 			if err != nil {
-				return bosherr.WrapError(err, "mount /var/vcap/store")
+				return err
 			}
 		}
 	}
@@ -359,8 +337,12 @@ func (vm *softLayerVirtualGuest) DetachDisk(disk bslcdisk.Disk) error {
 	return nil
 }
 
-func (vm *softLayerVirtualGuest) UpdateAgentEnv(agentEnv AgentEnv) error {
-	return vm.agentEnvService.Update(agentEnv)
+func (vm *softLayerVirtualGuest) FetchAgentEnv() (AgentEnv, error) {
+	return vm.agentEnvService.Fetch()
+}
+
+func (vm *softLayerVirtualGuest) UpdateAgentEnv(ctx context.Context, agentEnv AgentEnv) error {
+	return vm.agentEnvService.Update(ctx, agentEnv)
 }
 
 // Private methods
@@ -368,7 +350,7 @@ func (vm *softLayerVirtualGuest) extractTagsFromVMMetadata(vmMetadata VMMetadata
 	tags := []string{}
 	status := ""
 	for key, value := range vmMetadata {
-		if key == "compiling" || key == "job" || key == "index" || key == "deployment" || key == "deleted" {
+		if bslcommon.IsTaggedMetadataKey(key) {
 			stringValue, err := value.(string)
 			if !err {
 				return []string{}, bosherr.Errorf("Cannot convert tags metadata value `%v` to string", value)
@@ -390,363 +372,34 @@ func (vm *softLayerVirtualGuest) parseTags(value string) []string {
 	return strings.Split(value, ",")
 }
 
-func (vm *softLayerVirtualGuest) waitForVolumeAttached(volume datatypes.SoftLayer_Network_Storage, hasMultiPath bool) (string, error) {
-
-	oldDisks, err := vm.getIscsiDeviceNamesBasedOnShellScript(hasMultiPath)
-	if err != nil {
-		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to get devices names from virtual guest `%d`", vm.ID()))
-	}
-	if len(oldDisks) > 2 {
-		return "", bosherr.Error(fmt.Sprintf("Too manay persistent disks attached to virtual guest `%d`", vm.ID()))
-	}
-
-	credential, err := vm.getAllowedHostCredential()
-	if err != nil {
-		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to get iscsi host auth from virtual guest `%d`", vm.ID()))
-	}
-
-	_, err = vm.backupOpenIscsiConfBasedOnShellScript()
-	if err != nil {
-		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to backup open iscsi conf files from virtual guest `%d`", vm.ID()))
-	}
-
-	_, err = vm.writeOpenIscsiInitiatornameBasedOnShellScript(credential)
-	if err != nil {
-		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to write open iscsi initiatorname from virtual guest `%d`", vm.ID()))
-	}
-
-	_, err = vm.writeOpenIscsiConfBasedOnShellScript(volume, credential)
-	if err != nil {
-		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to write open iscsi conf from virtual guest `%d`", vm.ID()))
-	}
-
-	_, err = vm.restartOpenIscsiBasedOnShellScript()
-	if err != nil {
-		return "", bosherr.WrapError(err, fmt.Sprintf("Failed to restart open iscsi from virtual guest `%d`", vm.ID()))
-	}
-
-	_, err = vm.discoveryOpenIscsiTargetsBasedOnShellScript(volume)
-	if err != nil {
-		return "", bosherr.WrapErrorf(err, "Failed to attach volume with id %d to virtual guest with id: %d.", volume.Id, vm.ID())
-	}
-
-	var deviceName string
-	totalTime := time.Duration(0)
-	for totalTime < bslcommon.TIMEOUT {
-		newDisks, err := vm.getIscsiDeviceNamesBasedOnShellScript(hasMultiPath)
-		if err != nil {
-			return "", bosherr.WrapError(err, fmt.Sprintf("Failed to get devices names from virtual guest `%d`", vm.ID()))
-		}
-
-		if len(oldDisks) == 0 {
-			if len(newDisks) > 0 {
-				deviceName = newDisks[0]
-				return deviceName, nil
-			}
-		}
-
-		var included bool
-		for _, newDisk := range newDisks {
-			for _, oldDisk := range oldDisks {
-				if strings.EqualFold(newDisk, oldDisk) {
-					included = true
-				}
-			}
-			if !included {
-				deviceName = newDisk
-			}
-			included = false
-		}
-
-		if len(deviceName) > 0 {
-			return deviceName, nil
-		}
-
-		totalTime += bslcommon.POLLING_INTERVAL
-		time.Sleep(bslcommon.POLLING_INTERVAL)
-	}
-
-	return "", bosherr.Errorf("Failed to attach disk '%d' to virtual guest '%d'", volume.Id, vm.ID())
-}
-
-func (vm *softLayerVirtualGuest) hasMulitPathToolBasedOnShellScript() (bool, error) {
-	command := fmt.Sprintf("echo `command -v multipath`")
-	output, err := vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command)
-	if err != nil {
-		return false, err
-	}
-
-	if len(output) > 0 && strings.Contains(output, "multipath") {
-		return true, nil
-	}
-
-	return false, nil
-}
-
-func (vm *softLayerVirtualGuest) getIscsiDeviceNamesBasedOnShellScript(hasMultiPath bool) ([]string, error) {
-	devices := []string{}
-
-	command1 := fmt.Sprintf("dmsetup ls")
-	command2 := fmt.Sprintf("cat /proc/partitions")
-
-	if hasMultiPath {
-		result, err := vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command1)
-		if err != nil {
-			return devices, err
-		}
-		if strings.Contains(result, "No devices found") {
-			return devices, nil
-		}
-		vm.logger.Info(SOFTLAYER_VM_LOG_TAG, fmt.Sprintf("Devices on VM %d: %s", vm.ID(), result))
-		lines := strings.Split(strings.Trim(result, "\n"), "\n")
-		for i := 0; i < len(lines); i++ {
-			if match, _ := regexp.MatchString("-part1", lines[i]); !match {
-				devices = append(devices, strings.Fields(lines[i])[0])
-			}
-		}
-	} else {
-		result, err := vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command2)
-		if err != nil {
-			return devices, err
-		}
-
-		vm.logger.Info(SOFTLAYER_VM_LOG_TAG, fmt.Sprintf("Devices on VM %d: %s", vm.ID(), result))
-		lines := strings.Split(strings.Trim(result, "\n"), "\n")
-		for i := 0; i < len(lines); i++ {
-			if match, _ := regexp.MatchString("sd[a-z]$", lines[i]); match {
-				vals := strings.Fields(lines[i])
-				devices = append(devices, vals[len(vals)-1])
-			}
-		}
-	}
-
-	return devices, nil
-}
-
-func (vm *softLayerVirtualGuest) fetchIscsiVolume(volumeId int) (datatypes.SoftLayer_Network_Storage, error) {
-	networkStorageService, err := vm.softLayerClient.GetSoftLayer_Network_Storage_Service()
-	if err != nil {
-		return datatypes.SoftLayer_Network_Storage{}, bosherr.WrapError(err, "Cannot get network storage service.")
-	}
-
-	volume, err := networkStorageService.GetNetworkStorage(volumeId)
-	if err != nil {
-		return datatypes.SoftLayer_Network_Storage{}, bosherr.WrapErrorf(err, "Cannot get iSCSI volume with id: %d", volumeId)
-	}
-
-	return volume, nil
-}
-
-func (vm *softLayerVirtualGuest) getAllowedHostCredential() (AllowedHostCredential, error) {
-	var allowedHost datatypes.SoftLayer_Network_Storage_Allowed_Host
-	var err error
-
-	virtualGuestService, err := vm.softLayerClient.GetSoftLayer_Virtual_Guest_Service()
-	if err != nil {
-		return AllowedHostCredential{}, bosherr.WrapError(err, "Cannot get softlayer virtual guest service.")
-	}
-
-	allowedHost, err = virtualGuestService.GetAllowedHost(vm.ID())
-	if err != nil {
-		return AllowedHostCredential{}, bosherr.WrapErrorf(err, "Cannot get allowed host with instance id: %d", vm.ID())
-	}
-
-	if allowedHost.Id == 0 {
-		return AllowedHostCredential{}, bosherr.Errorf("Cannot get allowed host with instance id: %d", vm.ID())
-	}
-
-	allowedHostService, err := vm.softLayerClient.GetSoftLayer_Network_Storage_Allowed_Host_Service()
-	if err != nil {
-		return AllowedHostCredential{}, bosherr.WrapError(err, "Cannot get network storage allowed host service.")
-	}
-
-	credential, err := allowedHostService.GetCredential(allowedHost.Id)
-	if err != nil {
-		return AllowedHostCredential{}, bosherr.WrapErrorf(err, "Cannot get credential with allowed host id: %d", allowedHost.Id)
-	}
-
-	return AllowedHostCredential{
-		Iqn:      allowedHost.Name,
-		Username: credential.Username,
-		Password: credential.Password,
-	}, nil
-}
-
-func (vm *softLayerVirtualGuest) backupOpenIscsiConfBasedOnShellScript() (bool, error) {
-	command := fmt.Sprintf("cp /etc/iscsi/iscsid.conf{,.save}")
-	_, err := vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command)
-	if err != nil {
-		return false, bosherr.WrapError(err, "backuping open iscsi conf")
-	}
-
-	return true, nil
-}
-
-func (vm *softLayerVirtualGuest) restartOpenIscsiBasedOnShellScript() (bool, error) {
-	command := fmt.Sprintf("/etc/init.d/open-iscsi restart")
-	_, err := vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command)
-	if err != nil {
-		return false, bosherr.WrapError(err, "restarting open iscsi")
-	}
-
-	return true, nil
-}
-
-func (vm *softLayerVirtualGuest) discoveryOpenIscsiTargetsBasedOnShellScript(volume datatypes.SoftLayer_Network_Storage) (bool, error) {
-	command := fmt.Sprintf("sleep 5; iscsiadm -m discovery -t sendtargets -p %s", volume.ServiceResourceBackendIpAddress)
-	_, err := vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command)
-	if err != nil {
-		return false, bosherr.WrapError(err, "discoverying open iscsi targets")
-	}
-
-	command = "sleep 5; echo `iscsiadm -m node -l`"
-	_, err = vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command)
-	if err != nil {
-		return false, bosherr.WrapError(err, "login iscsi targets")
-	}
-
-	return true, nil
-}
-
-func (vm *softLayerVirtualGuest) writeOpenIscsiInitiatornameBasedOnShellScript(credential AllowedHostCredential) (bool, error) {
-	if len(credential.Iqn) > 0 {
-		command := fmt.Sprintf("echo 'InitiatorName=%s' > /etc/iscsi/initiatorname.iscsi", credential.Iqn)
-		_, err := vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command)
-		if err != nil {
-			return false, bosherr.WrapError(err, "Writing to /etc/iscsi/initiatorname.iscsi")
-		}
-	}
-
-	return true, nil
-}
-
-func (vm *softLayerVirtualGuest) writeOpenIscsiConfBasedOnShellScript(volume datatypes.SoftLayer_Network_Storage, credential AllowedHostCredential) (bool, error) {
-	buffer := bytes.NewBuffer([]byte{})
-	t := template.Must(template.New("open_iscsid_conf").Parse(etcIscsidConfTemplate))
-	if len(credential.Password) == 0 {
-		err := t.Execute(buffer, volume)
-		if err != nil {
-			return false, bosherr.WrapError(err, "Generating config from template")
-		}
-	} else {
-		err := t.Execute(buffer, credential)
-		if err != nil {
-			return false, bosherr.WrapError(err, "Generating config from template")
-		}
-	}
-
-	file, err := ioutil.TempFile(os.TempDir(), "iscsid_conf_")
-	if err != nil {
-		return false, bosherr.WrapError(err, "Generating config from template")
-	}
-
-	defer os.Remove(file.Name())
-
-	_, err = file.WriteString(buffer.String())
-	if err != nil {
-		return false, bosherr.WrapError(err, "Generating config from template")
-	}
-
-	if err = vm.sshClient.UploadFile(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), file.Name(), "/etc/iscsi/iscsid.conf"); err != nil {
-		return false, bosherr.WrapError(err, "Writing to /etc/iscsi/iscsid.conf")
-	}
-
-	return true, nil
-}
-
-func (vm *softLayerVirtualGuest) detachVolumeBasedOnShellScript(volume datatypes.SoftLayer_Network_Storage, hasMultiPath bool) error {
-	// umount /var/vcap/store in case read-only mount
-	isMounted, err := vm.isMountPoint("/var/vcap/store")
-	if err != nil {
-		return bosherr.WrapError(err, "check mount point /var/vcap/store")
-	}
-
-	if isMounted {
-		step00 := fmt.Sprintf("umount -l /var/vcap/store")
-		_, err := vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), step00)
-		if err != nil {
-			return bosherr.WrapError(err, "umount -l /var/vcap/store")
-		}
-		vm.logger.Debug(SOFTLAYER_VM_LOG_TAG, "umount -l /var/vcap/store", nil)
-	}
-
-	// stop open-iscsi
-	step1 := fmt.Sprintf("/etc/init.d/open-iscsi stop")
-	_, err = vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), step1)
-	if err != nil {
-		return bosherr.WrapError(err, "Restarting open iscsi")
-	}
-	vm.logger.Debug(SOFTLAYER_VM_LOG_TAG, "/etc/init.d/open-iscsi stop", nil)
-
-	// clean up /etc/iscsi/send_targets/
-	step2 := fmt.Sprintf("rm -rf /etc/iscsi/send_targets")
-	_, err = vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), step2)
-	if err != nil {
-		return bosherr.WrapError(err, "Removing /etc/iscsi/send_targets")
-	}
-	vm.logger.Debug(SOFTLAYER_VM_LOG_TAG, "rm -rf /etc/iscsi/send_targets", nil)
-
-	// clean up /etc/iscsi/nodes/
-	step3 := fmt.Sprintf("rm -rf /etc/iscsi/nodes")
-	_, err = vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), step3)
-	if err != nil {
-		return bosherr.WrapError(err, "Removing /etc/iscsi/nodes")
-	}
-
-	vm.logger.Debug(SOFTLAYER_VM_LOG_TAG, "rm -rf /etc/iscsi/nodes", nil)
-
-	// start open-iscsi
-	step4 := fmt.Sprintf("/etc/init.d/open-iscsi start")
-	_, err = vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), step4)
-	if err != nil {
-		return bosherr.WrapError(err, "Restarting open iscsi")
-	}
-	vm.logger.Debug(SOFTLAYER_VM_LOG_TAG, "/etc/init.d/open-iscsi start", nil)
-
-	if hasMultiPath {
-		// restart dm-multipath tool
-		step5 := fmt.Sprintf("service multipath-tools restart")
-		_, err = vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), step5)
-		if err != nil {
-			return bosherr.WrapError(err, "Restarting Multipath deamon")
-		}
-		vm.logger.Debug(SOFTLAYER_VM_LOG_TAG, "service multipath-tools restart", nil)
-	}
-
-	return nil
-}
-
-func (vm *softLayerVirtualGuest) postCheckActiveTransactionsForOSReload(softLayerClient sl.Client) error {
+func (vm *softLayerVirtualGuest) postCheckActiveTransactionsForOSReload(ctx context.Context, softLayerClient sl.Client) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	totalTime := time.Duration(0)
-	for totalTime < bslcommon.TIMEOUT {
+	started, err := bslcommon.NewPoller(vm.waitPolicies.OSReload, clock.NewClock()).Poll(ctx, func() (bool, error) {
 		activeTransactions, err := virtualGuestService.GetActiveTransactions(vm.ID())
 		if err != nil {
-			if !strings.Contains(err.Error(), "HTTP error code") {
-				return bosherr.WrapError(err, "Getting active transactions from SoftLayer client")
+			if !bslcommon.IsSoftLayerNotFoundError(err) && !bslcommon.IsSoftLayerTransientError(err) {
+				return false, bosherr.WrapError(err, "Getting active transactions from SoftLayer client")
 			}
 		}
 
-		if len(activeTransactions) > 0 {
-			vm.logger.Info(SOFTLAYER_VM_OS_RELOAD_TAG, "OS Reload transaction started")
-			break
-		}
-
-		totalTime += bslcommon.POLLING_INTERVAL
-		time.Sleep(bslcommon.POLLING_INTERVAL)
+		return len(activeTransactions) > 0, nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if totalTime >= bslcommon.TIMEOUT {
+	if !started {
 		return errors.New(fmt.Sprintf("Waiting for OS Reload transaction to start TIME OUT!"))
 	}
+	vm.logger.Info(SOFTLAYER_VM_OS_RELOAD_TAG, "OS Reload transaction started")
 
-	err = bslcommon.WaitForVirtualGuest(vm.softLayerClient, vm.ID(), "RUNNING")
+	err = bslcommon.WaitForVirtualGuest(ctx, vm.softLayerClient, vm.ID(), "RUNNING", vm.waitPolicies.OSReload)
 	if err != nil {
-		if !strings.Contains(err.Error(), "HTTP error code") {
+		if !bslcommon.IsSoftLayerNotFoundError(err) && !bslcommon.IsSoftLayerTransientError(err) {
 			return bosherr.WrapError(err, fmt.Sprintf("PowerOn failed with VirtualGuest id %d", vm.ID()))
 		}
 	}
@@ -756,46 +409,44 @@ func (vm *softLayerVirtualGuest) postCheckActiveTransactionsForOSReload(softLaye
 	return nil
 }
 
-func (vm *softLayerVirtualGuest) postCheckActiveTransactionsForDeleteVM(softLayerClient sl.Client, virtualGuestId int) error {
+func (vm *softLayerVirtualGuest) postCheckActiveTransactionsForDeleteVM(ctx context.Context, softLayerClient sl.Client, virtualGuestId int) error {
 	virtualGuestService, err := softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
 	}
 
-	totalTime := time.Duration(0)
-	for totalTime < bslcommon.TIMEOUT {
+	poller := bslcommon.NewPoller(vm.waitPolicies.Delete, clock.NewClock())
+
+	started, err := poller.Poll(ctx, func() (bool, error) {
 		activeTransactions, err := virtualGuestService.GetActiveTransactions(virtualGuestId)
 		if err != nil {
-			if !strings.Contains(err.Error(), "HTTP error code") {
-				return bosherr.WrapError(err, "Getting active transactions from SoftLayer client")
+			if !bslcommon.IsSoftLayerNotFoundError(err) && !bslcommon.IsSoftLayerTransientError(err) {
+				return false, bosherr.WrapError(err, "Getting active transactions from SoftLayer client")
 			}
 		}
 
-		if len(activeTransactions) > 0 {
-			vm.logger.Info(SOFTLAYER_VM_LOG_TAG, "Delete VM transaction started", nil)
-			break
-		}
-
-		totalTime += bslcommon.POLLING_INTERVAL
-		time.Sleep(bslcommon.POLLING_INTERVAL)
+		return len(activeTransactions) > 0, nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if totalTime >= bslcommon.TIMEOUT {
+	if !started {
 		return errors.New(fmt.Sprintf("Waiting for DeleteVM transaction to start TIME OUT!"))
 	}
+	vm.logger.Info(SOFTLAYER_VM_LOG_TAG, "Delete VM transaction started", nil)
 
-	totalTime = time.Duration(0)
-	for totalTime < bslcommon.TIMEOUT {
+	completed, err := poller.Poll(ctx, func() (bool, error) {
 		vm1, err := virtualGuestService.GetObject(virtualGuestId)
 		if err != nil || vm1.Id == 0 {
 			vm.logger.Info(SOFTLAYER_VM_LOG_TAG, "VM doesn't exist. Delete done", nil)
-			break
+			return true, nil
 		}
 
 		activeTransaction, err := virtualGuestService.GetActiveTransaction(virtualGuestId)
 		if err != nil {
-			if !strings.Contains(err.Error(), "HTTP error code") {
-				return bosherr.WrapError(err, "Getting active transactions from SoftLayer client")
+			if !bslcommon.IsSoftLayerNotFoundError(err) && !bslcommon.IsSoftLayerTransientError(err) {
+				return false, bosherr.WrapError(err, "Getting active transactions from SoftLayer client")
 			}
 		}
 
@@ -811,57 +462,27 @@ func (vm *softLayerVirtualGuest) postCheckActiveTransactionsForDeleteVM(softLaye
 
 		if averageTransactionDuration > 30 {
 			vm.logger.Info(SOFTLAYER_VM_LOG_TAG, "Deleting VM instance had been launched and it is a long transaction. Please check Softlayer Portal", nil)
-			break
+			return true, nil
 		}
 
 		vm.logger.Info(SOFTLAYER_VM_LOG_TAG, "This is a short transaction, waiting for all active transactions to complete", nil)
-		totalTime += bslcommon.POLLING_INTERVAL
-		time.Sleep(bslcommon.POLLING_INTERVAL)
+		return false, nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if totalTime >= bslcommon.TIMEOUT {
+	if !completed {
 		return errors.New(fmt.Sprintf("After deleting a vm, waiting for active transactions to complete TIME OUT!"))
 	}
 
 	return nil
 }
 
-func (vm *softLayerVirtualGuest) isMountPoint(path string) (bool, error) {
-	mounts, err := vm.searchMounts()
-	if err != nil {
-		return false, bosherr.WrapError(err, "Searching mounts")
-	}
-
-	for _, mount := range mounts {
-		if mount.MountPoint == path {
-			return true, nil
-		}
-	}
-
-	return false, nil
+func (vm *softLayerVirtualGuest) iscsiAttacher() IscsiAttacher {
+	return NewIscsiAttacher(NewVirtualGuestIscsiAccess(vm.virtualGuest, vm.softLayerClient), vm.softLayerClient, vm.sshClient, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), vm.waitPolicies, vm.logger)
 }
 
-func (vm *softLayerVirtualGuest) searchMounts() ([]Mount, error) {
-	var mounts []Mount
-	stdout, err := vm.sshClient.ExecCommand(ROOT_USER_NAME, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), "mount")
-	if err != nil {
-		return mounts, bosherr.WrapError(err, "Running mount")
-	}
-
-	// e.g. '/dev/sda on /boot type ext2 (rw)'
-	for _, mountEntry := range strings.Split(stdout, "\n") {
-		if mountEntry == "" {
-			continue
-		}
-
-		mountFields := strings.Fields(mountEntry)
-
-		mounts = append(mounts, Mount{
-			PartitionPath: mountFields[0],
-			MountPoint:    mountFields[2],
-		})
-
-	}
-
-	return mounts, nil
+func (vm *softLayerVirtualGuest) runCommand(command string) (string, error) {
+	return runRemoteCommand(vm.sshClient, vm.GetRootPassword(), vm.GetPrimaryBackendIP(), command, vm.logger, SOFTLAYER_VM_LOG_TAG)
 }
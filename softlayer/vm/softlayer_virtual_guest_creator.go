@@ -1,97 +1,115 @@
 package vm
 
 import (
+	"context"
 	"fmt"
-	"net"
-	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
 	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
+	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
 	datatypes "github.com/maximilien/softlayer-go/data_types"
 	sl "github.com/maximilien/softlayer-go/softlayer"
-
-	"github.com/cloudfoundry/bosh-softlayer-cpi/common"
 )
 
 const SOFTLAYER_VM_CREATOR_LOG_TAG = "SoftLayerVMCreator"
 
 type softLayerVirtualGuestCreator struct {
 	softLayerClient        sl.Client
+	sshClient              util.SshClient
 	agentEnvServiceFactory AgentEnvServiceFactory
 
 	agentOptions AgentOptions
+	waitPolicies bslcommon.WaitPolicies
 	logger       boshlog.Logger
 	vmFinder     Finder
 }
 
-func NewSoftLayerCreator(vmFinder Finder, softLayerClient sl.Client, agentOptions AgentOptions, logger boshlog.Logger) VMCreator {
-	bslcommon.TIMEOUT = 120 * time.Minute
-	bslcommon.POLLING_INTERVAL = 5 * time.Second
-
+func NewSoftLayerCreator(vmFinder Finder, softLayerClient sl.Client, sshClient util.SshClient, agentOptions AgentOptions, waitPolicies bslcommon.WaitPolicies, logger boshlog.Logger) VMCreator {
 	return &softLayerVirtualGuestCreator{
 		vmFinder:        vmFinder,
 		softLayerClient: softLayerClient,
+		sshClient:       sshClient,
 		agentOptions:    agentOptions,
+		waitPolicies:    waitPolicies,
 		logger:          logger,
 	}
 }
 
-func (c *softLayerVirtualGuestCreator) Create(agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
-	for _, network := range networks {
-		switch network.Type {
-		case "dynamic":
-			if len(network.IP) == 0 {
-				return c.createBySoftlayer(agentID, stemcell, cloudProps, networks, env)
-			} else {
-				return c.createByOSReload(agentID, stemcell, cloudProps, networks, env)
+func (c *softLayerVirtualGuestCreator) Create(ctx context.Context, agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
+	components, err := networks.Components(cloudProps.PrivateNetworkOnlyFlag)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Validating network components")
+	}
+
+	reloadNetwork, err := networks.ReloadNetwork()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Selecting default gateway network")
+	}
+
+	var vm VM
+	if len(reloadNetwork) > 0 {
+		vm, err = c.createByOSReload(ctx, agentID, stemcell, cloudProps, networks, networks[reloadNetwork].IP, components[reloadNetwork], env)
+	} else {
+		if !cloudProps.UseProductOrder {
+			err = ValidateCloudProperties(c.softLayerClient, cloudProps)
+			if err != nil {
+				return nil, bosherr.WrapError(err, "Validating cloud properties")
 			}
-		case "manual":
-			return nil, bosherr.Error("Manual networking is not currently supported")
-		case "vip":
-			return nil, bosherr.Error("SoftLayer Not Support VIP netowrk")
-		default:
-			return nil, bosherr.Errorf("Softlayer Not Support This Kind Of Network: %s", network.Type)
 		}
+
+		var resolvedNetworks Networks
+		resolvedNetworks, err = ResolveManualNetworks(c.softLayerClient, &cloudProps, networks)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Resolving manual networks")
+		}
+
+		vm, err = c.createBySoftlayer(ctx, agentID, stemcell, cloudProps, resolvedNetworks, env)
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, nil
+	return vm, nil
 }
 
 // Private methods
-func (c *softLayerVirtualGuestCreator) createBySoftlayer(agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
-	virtualGuestTemplate, err := CreateVirtualGuestTemplate(stemcell, cloudProps)
+func (c *softLayerVirtualGuestCreator) createBySoftlayer(ctx context.Context, agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
+	virtualGuestId, err := c.provisionVirtualGuest(ctx, stemcell, cloudProps)
 	if err != nil {
-		return nil, bosherr.WrapError(err, "Creating VirtualGuest template")
+		return nil, err
 	}
 
-	virtualGuestService, err := c.softLayerClient.GetSoftLayer_Virtual_Guest_Service()
+	vm, err := c.setUpVirtualGuest(ctx, virtualGuestId, agentID, cloudProps, networks, env)
 	if err != nil {
-		return nil, bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
+		rollbackFailedVM(c.vmFinder, virtualGuestId, cloudProps, c.logger)
+		return nil, err
 	}
 
-	virtualGuest, err := virtualGuestService.CreateObject(virtualGuestTemplate)
-	if err != nil {
-		return nil, bosherr.WrapError(err, "Creating VirtualGuest from SoftLayer client")
-	}
+	return vm, nil
+}
 
+// setUpVirtualGuest prepares a freshly provisioned virtual guest for the agent
+func (c *softLayerVirtualGuestCreator) setUpVirtualGuest(ctx context.Context, virtualGuestId int, agentID string, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
+	var err error
 	if cloudProps.EphemeralDiskSize == 0 {
-		err = bslcommon.WaitForVirtualGuestLastCompleteTransaction(c.softLayerClient, virtualGuest.Id, "Service Setup")
+		err = bslcommon.WaitForVirtualGuestLastCompleteTransaction(ctx, c.softLayerClient, virtualGuestId, "Service Setup", c.waitPolicies.Create)
 		if err != nil {
-			return nil, bosherr.WrapErrorf(err, "Waiting for VirtualGuest `%d` has Service Setup transaction complete", virtualGuest.Id)
+			return nil, bosherr.WrapErrorf(err, "Waiting for VirtualGuest `%d` has Service Setup transaction complete", virtualGuestId)
 		}
 	} else {
-		err = bslcommon.AttachEphemeralDiskToVirtualGuest(c.softLayerClient, virtualGuest.Id, cloudProps.EphemeralDiskSize, c.logger)
+		err = bslcommon.AttachEphemeralDiskToVirtualGuest(ctx, c.softLayerClient, virtualGuestId, cloudProps.EphemeralDiskSize, c.waitPolicies.Create, c.logger)
 		if err != nil {
-			return nil, bosherr.WrapError(err, fmt.Sprintf("Attaching ephemeral disk to VirtualGuest `%d`", virtualGuest.Id))
+			return nil, bosherr.WrapError(err, fmt.Sprintf("Attaching ephemeral disk to VirtualGuest `%d`", virtualGuestId))
 		}
 	}
 
-	vm, found, err := c.vmFinder.Find(virtualGuest.Id)
+	vm, found, err := c.vmFinder.Find(virtualGuestId)
 	if err != nil || !found {
-		return nil, bosherr.WrapErrorf(err, "Cannot find VirtualGuest with id: %d.", virtualGuest.Id)
+		return nil, bosherr.WrapErrorf(err, "Cannot find VirtualGuest with id: %d.", virtualGuestId)
 	}
 
 	if len(cloudProps.BoshIp) == 0 {
@@ -117,7 +135,12 @@ func (c *softLayerVirtualGuestCreator) createBySoftlayer(agentID string, stemcel
 
 	agentEnv := CreateAgentUserData(agentID, cloudProps, networks, env, c.agentOptions)
 
-	err = vm.UpdateAgentEnv(agentEnv)
+	err = c.sshClient.ForgetHostKey(vm.GetPrimaryBackendIP())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Forgetting previous host key of VM `%d`", vm.ID())
+	}
+
+	err = vm.UpdateAgentEnv(ctx, agentEnv)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Updating VM's agent env")
 	}
@@ -129,10 +152,53 @@ func (c *softLayerVirtualGuestCreator) createBySoftlayer(agentID string, stemcel
 		}
 	}
 
+	err = RouteVipNetworks(c.softLayerClient, networks, vm.GetPrimaryIP())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Routing VIPs to VM '%d'", vm.ID())
+	}
+
 	return vm, nil
 }
 
-func (c *softLayerVirtualGuestCreator) createByOSReload(agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, env Environment) (VM, error) {
+// provisionVirtualGuest creates the virtual guest either from the simplified
+// createObject template or, when requested, through a product order
+func (c *softLayerVirtualGuestCreator) provisionVirtualGuest(ctx context.Context, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties) (int, error) {
+	if cloudProps.UseProductOrder {
+		orderId, err := PlaceVirtualGuestOrder(c.softLayerClient, stemcell, cloudProps)
+		if err != nil {
+			return 0, bosherr.WrapError(err, "Ordering VirtualGuest from SoftLayer client")
+		}
+
+		virtualGuestId, err := WaitForOrderedVirtualGuest(ctx, c.softLayerClient, orderId, c.waitPolicies.Create)
+		if err != nil {
+			rollbackFailedOrder(c.vmFinder, c.softLayerClient, orderId, cloudProps, c.logger)
+			return 0, bosherr.WrapError(err, "Ordering VirtualGuest from SoftLayer client")
+		}
+
+		return virtualGuestId, nil
+	}
+
+	virtualGuestTemplate, err := CreateVirtualGuestTemplate(stemcell, cloudProps)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Creating VirtualGuest template")
+	}
+
+	virtualGuestService, err := c.softLayerClient.GetSoftLayer_Virtual_Guest_Service()
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
+	}
+
+	virtualGuest, err := virtualGuestService.CreateObject(virtualGuestTemplate)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Creating VirtualGuest from SoftLayer client")
+	}
+
+	return virtualGuest.Id, nil
+}
+
+// createByOSReload reloads the guest whose primary IP on the network component
+// of the reload network is reloadIP
+func (c *softLayerVirtualGuestCreator) createByOSReload(ctx context.Context, agentID string, stemcell bslcstem.Stemcell, cloudProps VMCloudProperties, networks Networks, reloadIP string, reloadComponent string, env Environment) (VM, error) {
 	virtualGuestService, err := c.softLayerClient.GetSoftLayer_Virtual_Guest_Service()
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Creating VirtualGuestService from SoftLayer client")
@@ -140,15 +206,15 @@ func (c *softLayerVirtualGuestCreator) createByOSReload(agentID string, stemcell
 
 	var virtualGuest datatypes.SoftLayer_Virtual_Guest
 
-	if common.IsPrivateSubnet(net.ParseIP(networks.First().IP)) {
-		virtualGuest, err = virtualGuestService.GetObjectByPrimaryBackendIpAddress(networks.First().IP)
+	if reloadComponent == PrivateNetworkComponent {
+		virtualGuest, err = virtualGuestService.GetObjectByPrimaryBackendIpAddress(reloadIP)
 		c.logger.Info(SOFTLAYER_VM_CREATOR_LOG_TAG, fmt.Sprintf("OS reload on the server id %d with stemcell %d", virtualGuest.Id, stemcell.ID()))
 	} else {
-		virtualGuest, err = virtualGuestService.GetObjectByPrimaryIpAddress(networks.First().IP)
+		virtualGuest, err = virtualGuestService.GetObjectByPrimaryIpAddress(reloadIP)
 	}
 
 	if err != nil || virtualGuest.Id == 0 {
-		return nil, bosherr.WrapErrorf(err, "Could not find VirtualGuest by ip address: %s", networks.First().IP)
+		return nil, bosherr.WrapErrorf(err, "Could not find VirtualGuest by ip address: %s", reloadIP)
 	}
 
 	c.logger.Info(SOFTLAYER_VM_CREATOR_LOG_TAG, fmt.Sprintf("OS reload on VirtualGuest %d using stemcell %d", virtualGuest.Id, stemcell.ID()))
@@ -158,19 +224,18 @@ func (c *softLayerVirtualGuestCreator) createByOSReload(agentID string, stemcell
 		return nil, bosherr.WrapErrorf(err, "Cannot find virtualGuest with id: %d", virtualGuest.Id)
 	}
 
-	bslcommon.TIMEOUT = 4 * time.Hour
-	err = vm.ReloadOS(stemcell)
+	err = vm.ReloadOS(ctx, stemcell)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Failed to reload OS")
 	}
 
 	if cloudProps.EphemeralDiskSize == 0 {
-		err = bslcommon.WaitForVirtualGuestLastCompleteTransaction(c.softLayerClient, vm.ID(), "Service Setup")
+		err = bslcommon.WaitForVirtualGuestLastCompleteTransaction(ctx, c.softLayerClient, vm.ID(), "Service Setup", c.waitPolicies.OSReload)
 		if err != nil {
 			return nil, bosherr.WrapErrorf(err, "Waiting for VirtualGuest `%d` has Service Setup transaction complete", vm.ID())
 		}
 	} else {
-		err = bslcommon.AttachEphemeralDiskToVirtualGuest(c.softLayerClient, vm.ID(), cloudProps.EphemeralDiskSize, c.logger)
+		err = bslcommon.AttachEphemeralDiskToVirtualGuest(ctx, c.softLayerClient, vm.ID(), cloudProps.EphemeralDiskSize, c.waitPolicies.OSReload, c.logger)
 		if err != nil {
 			return nil, bosherr.WrapError(err, fmt.Sprintf("Attaching ephemeral disk to VirtualGuest `%d`", vm.ID()))
 		}
@@ -207,7 +272,12 @@ func (c *softLayerVirtualGuestCreator) createByOSReload(agentID string, stemcell
 		return nil, bosherr.WrapErrorf(err, "Cannot create agent env for virtual guest with id: %d", vm.ID())
 	}
 
-	err = vm.UpdateAgentEnv(agentEnv)
+	err = c.sshClient.ForgetHostKey(vm.GetPrimaryBackendIP())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Forgetting previous host key of VM `%d`", vm.ID())
+	}
+
+	err = vm.UpdateAgentEnv(ctx, agentEnv)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Updating VM's agent env")
 	}
@@ -218,5 +288,11 @@ func (c *softLayerVirtualGuestCreator) createByOSReload(agentID string, stemcell
 			return nil, bosherr.WrapError(err, "Updating VM's vcap password")
 		}
 	}
+
+	err = RouteVipNetworks(c.softLayerClient, networks, vm.GetPrimaryIP())
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Routing VIPs to VM '%d'", vm.ID())
+	}
+
 	return vm, nil
 }
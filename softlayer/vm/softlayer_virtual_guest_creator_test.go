@@ -1,15 +1,21 @@
 package vm_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"strings"
 	"time"
 
+	bslcapi "github.com/cloudfoundry/bosh-softlayer-cpi/api"
 	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
 
 	testhelpers "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
 
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
+	util "github.com/cloudfoundry/bosh-softlayer-cpi/util"
 	fakesutil "github.com/cloudfoundry/bosh-softlayer-cpi/util/fakes"
 	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
 
@@ -27,6 +33,7 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 		sshClient       *fakesutil.FakeSshClient
 		vmFinder        *fakevm.FakeFinder
 		agentOptions    AgentOptions
+		waitPolicies    bslcommon.WaitPolicies
 		logger          boshlog.Logger
 		creator         VMCreator
 	)
@@ -35,17 +42,18 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 		softLayerClient = fakeslclient.NewFakeSoftLayerClient("fake-username", "fake-api-key")
 		sshClient = &fakesutil.FakeSshClient{}
 		agentOptions = AgentOptions{Mbus: "fake-mbus"}
+		waitPolicies = bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(2*time.Second, 1*time.Second))
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 		vmFinder = &fakevm.FakeFinder{}
 
 		creator = NewSoftLayerCreator(
 			vmFinder,
 			softLayerClient,
+			sshClient,
 			agentOptions,
+			waitPolicies,
 			logger,
 		)
-		bslcommon.TIMEOUT = 2 * time.Second
-		bslcommon.POLLING_INTERVAL = 1 * time.Second
 	})
 
 	Describe("#Create", func() {
@@ -60,7 +68,7 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 		Context("valid arguments", func() {
 			BeforeEach(func() {
 				agentID = "fake-agent-id"
-				stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, logger)
+				stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, waitPolicies, logger)
 
 				env = Environment{}
 
@@ -88,6 +96,27 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						}
 					})
 
+					It("looks up the server by its backend IP when the reload network maps to the private component", func() {
+						cloudProps = VMCloudProperties{Domain: "fake-domain.com"}
+						vmFinder.FindFound = false
+						testhelpers.SetTestFixtureForFakeSoftLayerClient(softLayerClient, "SoftLayer_Account_Service_getVirtualGuests.json")
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestWithObjectFilterAndObjectMaskFilters).To(ContainSubstring(`"primaryBackendIpAddress":{"operation":"10.0.0.11"}`))
+					})
+
+					It("looks up the server by its primary IP when the reload network maps to the public component", func() {
+						networks["fake-network0"] = Network{Type: "dynamic", IP: "169.50.10.5"}
+						cloudProps = VMCloudProperties{Domain: "fake-domain.com"}
+						vmFinder.FindFound = false
+						testhelpers.SetTestFixtureForFakeSoftLayerClient(softLayerClient, "SoftLayer_Account_Service_getVirtualGuests.json")
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestWithObjectFilterAndObjectMaskFilters).To(ContainSubstring(`"primaryIpAddress":{"operation":"169.50.10.5"}`))
+					})
+
 					It("returns a new SoftLayerVM with ephemeral size", func() {
 						cloudProps = VMCloudProperties{
 							StartCpus: 4,
@@ -119,12 +148,12 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						expectedCmdResults := []string{
 							"",
 						}
-						sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-							return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+						sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+							return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 						}
 						setFakeSoftlayerClientCreateObjectTestFixturesWithEphemeralDiskSize_OS_Reload(softLayerClient)
 
-						vm, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(vm.ID()).To(Equal(1234567))
 					})
@@ -159,11 +188,11 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						expectedCmdResults := []string{
 							"",
 						}
-						sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-							return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+						sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+							return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 						}
 						setFakeSoflayerClientCreateObjectTestFixturesWithoutEphemeralDiskSize_OS_Reload(softLayerClient)
-						vm, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(vm.ID()).To(Equal(1234567))
 					})
@@ -198,11 +227,11 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						expectedCmdResults := []string{
 							"",
 						}
-						sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-							return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+						sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+							return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 						}
 						setFakeSoftlayerClientCreateObjectTestFixturesWithoutBoshIP_OS_Reload(softLayerClient)
-						vm, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(vm.ID()).To(Equal(1234567))
 					})
@@ -260,12 +289,12 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						expectedCmdResults := []string{
 							"",
 						}
-						sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-							return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+						sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+							return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 						}
 						setFakeSoftlayerClientCreateObjectTestFixturesWithEphemeralDiskSize(softLayerClient)
 
-						vm, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(vm.ID()).To(Equal(1234567))
 					})
@@ -299,11 +328,11 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						expectedCmdResults := []string{
 							"",
 						}
-						sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-							return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+						sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+							return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 						}
 						setFakeSoftlayerClientCreateObjectTestFixturesWithoutEphemeralDiskSize(softLayerClient)
-						vm, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(vm.ID()).To(Equal(1234567))
 					})
@@ -338,13 +367,301 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						expectedCmdResults := []string{
 							"",
 						}
-						sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-							return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+						sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+							return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 						}
 						setFakeSoftlayerClientCreateObjectTestFixturesWithoutBoshIP(softLayerClient)
-						vm, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(vm.ID()).To(Equal(1234567))
+					})
+				})
+
+				Context("when setting up the new VM fails", func() {
+					var fakeVM *fakevm.FakeVM
+
+					BeforeEach(func() {
+						networks = map[string]Network{
+							"fake-network0": Network{Type: "dynamic"},
+						}
+						cloudProps = VMCloudProperties{
+							StartCpus: 4,
+							MaxMemory: 2048,
+							Domain:    "fake-domain.com",
+							BlockDeviceTemplateGroup: sldatatypes.BlockDeviceTemplateGroup{
+								GlobalIdentifier: "fake-uuid",
+							},
+							RootDiskSize:      25,
+							BoshIp:            "10.0.0.1",
+							Datacenter:        sldatatypes.Datacenter{Name: "fake-datacenter"},
+							HourlyBillingFlag: true,
+							VmNamePrefix:      "bosh-test",
+						}
+
+						fakeVM = fakevm.NewFakeVM(1234567)
+						fakeVM.UpdateAgentEnvErr = errors.New("fake-update-agent-env-error")
+						vmFinder.FindVM = fakeVM
+
+						setFakeSoftlayerClientCreateObjectTestFixturesWithoutEphemeralDiskSize(softLayerClient)
+					})
+
+					It("deletes the partially created VM and returns the original error", func() {
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-update-agent-env-error"))
+
+						Expect(fakeVM.DeleteCalled).To(BeTrue())
+						Expect(fakeVM.DeleteContext.Err()).ToNot(HaveOccurred())
+					})
+
+					It("still deletes the VM when the request context has been cancelled", func() {
+						ctx, cancel := context.WithCancel(context.Background())
+						cancel()
+						fakeVM.UpdateAgentEnvErr = nil
+						fakeVM.SetVcapPasswordErr = errors.New("fake-set-vcap-password-error")
+						creator = NewSoftLayerCreator(vmFinder, softLayerClient, sshClient, AgentOptions{Mbus: "fake-mbus", VcapPassword: "fake-password"}, waitPolicies, logger)
+
+						_, err := creator.Create(ctx, agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-set-vcap-password-error"))
+
+						Expect(fakeVM.DeleteCalled).To(BeTrue())
+						Expect(fakeVM.DeleteContext.Err()).ToNot(HaveOccurred())
+					})
+
+					It("keeps the VM when keep_failed_vm is set", func() {
+						cloudProps.KeepFailedVM = true
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(fakeVM.DeleteCalled).To(BeFalse())
+					})
+
+					It("deletes the VM when its previous host key cannot be forgotten", func() {
+						sshClient.ForgetHostKeyReturns(errors.New("fake-forget-host-key-error"))
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-forget-host-key-error"))
+						Expect(fakeVM.DeleteCalled).To(BeTrue())
+					})
+
+					It("returns the original error when the VM cannot be deleted", func() {
+						fakeVM.DeleteErr = errors.New("fake-delete-error")
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-update-agent-env-error"))
+						Expect(err.Error()).ToNot(ContainSubstring("fake-delete-error"))
+					})
+				})
+
+				Context("when the VM is provisioned but creating it does not complete", func() {
+					var fakeVM *fakevm.FakeVM
+
+					BeforeEach(func() {
+						cloudProps = VMCloudProperties{
+							StartCpus: 4,
+							MaxMemory: 2048,
+							Domain:    "fake-domain.com",
+							BlockDeviceTemplateGroup: sldatatypes.BlockDeviceTemplateGroup{
+								GlobalIdentifier: "fake-uuid",
+							},
+							RootDiskSize:      25,
+							BoshIp:            "10.0.0.1",
+							Datacenter:        sldatatypes.Datacenter{Name: "fake-datacenter"},
+							HourlyBillingFlag: true,
+							VmNamePrefix:      "bosh-test",
+						}
+
+						fakeVM = fakevm.NewFakeVM(1234567)
+						vmFinder.FindVM = fakeVM
+					})
+
+					It("deletes the VM when its VIPs cannot be routed", func() {
+						networks = map[string]Network{
+							"fake-network0": Network{Type: "dynamic"},
+							"fake-vip":      Network{Type: "vip", IP: "169.50.20.99"},
+						}
+						setFakeSoftlayerClientCreateObjectTestFixturesWithVipNetwork(softLayerClient)
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("is not a global IP"))
+						Expect(fakeVM.DeleteCalled).To(BeTrue())
+					})
+
+					It("deletes the guest of a product order when waiting for it is cancelled", func() {
+						cloudProps.UseProductOrder = true
+						networks = map[string]Network{
+							"fake-network0": Network{Type: "dynamic"},
+						}
+
+						itemPrices := []string{}
+						for i, keyName := range VirtualGuestOrderItems(cloudProps) {
+							itemPrices = append(itemPrices, fmt.Sprintf(`{"id": %d, "locationGroupId": 0, "item": {"keyName": "%s"}}`, 1000+i, keyName))
+						}
+						softLayerClient.FakeHttpClient.DoRawHttpRequestResponses = [][]byte{
+							[]byte("[" + strings.Join(itemPrices, ",") + "]"),
+							[]byte(`{"packageId": 46}`),
+							[]byte(`{"orderId": 4321}`),
+							[]byte(`[]`),
+							[]byte(`[{"id": 1234567}]`),
+						}
+
+						ctx, cancel := context.WithCancel(context.Background())
+						cancel()
+
+						_, err := creator.Create(ctx, agentID, stemcell, cloudProps, networks, env)
+						cloudErr, found := bslcapi.FindCloudError(err)
+						Expect(found).To(BeTrue())
+						Expect(cloudErr).To(Equal(bslcapi.NewCancelledError(context.Canceled)))
+
+						Expect(vmFinder.FindID).To(Equal(1234567))
+						Expect(fakeVM.DeleteCalled).To(BeTrue())
+					})
+				})
+
+				Context("with manual networking", func() {
+					BeforeEach(func() {
+						networks = map[string]Network{
+							"fake-network0": Network{
+								Type: "manual",
+								IP:   "10.0.0.10",
+								DNS: []string{
+									"fake-dns0",
+								},
+								Default:         []string{"dns", "gateway"},
+								CloudProperties: map[string]interface{}{},
+							},
+						}
+						cloudProps = VMCloudProperties{
+							StartCpus: 4,
+							MaxMemory: 2048,
+							Domain:    "fake-domain.com",
+							BlockDeviceTemplateGroup: sldatatypes.BlockDeviceTemplateGroup{
+								GlobalIdentifier: "fake-uuid",
+							},
+							RootDiskSize:      25,
+							BoshIp:            "10.0.0.1",
+							Datacenter:        sldatatypes.Datacenter{Name: "fake-datacenter"},
+							HourlyBillingFlag: true,
+							VmNamePrefix:      "bosh-test",
+						}
+					})
+
+					It("returns a new SoftLayerVM on the VLAN of the requested IP", func() {
+						setFakeSoftlayerClientCreateObjectTestFixturesWithManualNetwork(softLayerClient)
+
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(vm.ID()).To(Equal(1234567))
+						Expect(sshClient.ForgetHostKeyCallCount()).To(Equal(1))
+
+						fakeVM := vm.(*fakevm.FakeVM)
+						Expect(fakeVM.UpdateAgentEnvCalled).To(BeTrue())
+						Expect(fakeVM.UpdateAgentEnvAgentEnv.Networks["fake-network0"].IP).To(Equal("10.0.0.10"))
+						Expect(fakeVM.UpdateAgentEnvAgentEnv.Networks["fake-network0"].Netmask).To(Equal("255.255.255.192"))
+						Expect(fakeVM.UpdateAgentEnvAgentEnv.Networks["fake-network0"].Gateway).To(Equal("10.0.0.1"))
+					})
+
+					It("returns error when no subnet contains the requested IP", func() {
+						networks["fake-network0"] = Network{Type: "manual", IP: "192.168.1.10"}
+						testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+							"SoftLayer_Virtual_Guest_Service_getCreateObjectOptions.json",
+							"SoftLayer_Account_Service_getSubnets.json",
+						})
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("No SoftLayer subnet found for IP '192.168.1.10'"))
+					})
+				})
+
+				Context("with multiple networks", func() {
+					BeforeEach(func() {
+						cloudProps = VMCloudProperties{
+							StartCpus: 4,
+							MaxMemory: 2048,
+							Domain:    "fake-domain.com",
+							BlockDeviceTemplateGroup: sldatatypes.BlockDeviceTemplateGroup{
+								GlobalIdentifier: "fake-uuid",
+							},
+							RootDiskSize:      25,
+							BoshIp:            "10.0.0.1",
+							Datacenter:        sldatatypes.Datacenter{Name: "fake-datacenter"},
+							HourlyBillingFlag: true,
+							VmNamePrefix:      "bosh-test",
+						}
+					})
+
+					It("creates a VM with a private manual network and a public dynamic network", func() {
+						networks = map[string]Network{
+							"fake-private": Network{
+								Type: "manual",
+								IP:   "10.0.0.10",
+							},
+							"fake-public": Network{
+								Type:    "dynamic",
+								Default: []string{"dns", "gateway"},
+							},
+						}
+						setFakeSoftlayerClientCreateObjectTestFixturesWithManualNetwork(softLayerClient)
+
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(vm.ID()).To(Equal(1234567))
+
+						fakeVM := vm.(*fakevm.FakeVM)
+						Expect(fakeVM.UpdateAgentEnvAgentEnv.Networks).To(HaveLen(2))
+						Expect(fakeVM.UpdateAgentEnvAgentEnv.Networks["fake-private"].Gateway).To(Equal("10.0.0.1"))
+					})
+
+					It("returns error when both networks provide the default gateway", func() {
+						networks = map[string]Network{
+							"fake-private": Network{Type: "manual", IP: "10.0.0.10", Default: []string{"gateway"}},
+							"fake-public":  Network{Type: "dynamic", Default: []string{"gateway"}},
+						}
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("Only one network may provide the default gateway"))
+					})
+
+					It("returns error when both networks map to the same component", func() {
+						networks = map[string]Network{
+							"fake-network0": Network{Type: "manual", IP: "10.0.0.10", Default: []string{"gateway"}},
+							"fake-network1": Network{Type: "manual", IP: "10.0.0.20"},
+						}
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("both map to the private network component"))
+					})
+
+					It("routes the VIP of a vip network to the new VM", func() {
+						networks = map[string]Network{
+							"fake-network0": Network{Type: "dynamic"},
+							"fake-vip":      Network{Type: "vip", IP: "169.50.20.5"},
+						}
+						setFakeSoftlayerClientCreateObjectTestFixturesWithVipNetwork(softLayerClient)
+
+						vm, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(vm.ID()).To(Equal(1234567))
+						Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Network_Subnet_IpAddress_Global/4444/route.json"))
+					})
+
+					It("returns error for a vip network on a private network only VM", func() {
+						cloudProps.PrivateNetworkOnlyFlag = true
+						networks = map[string]Network{
+							"fake-network0": Network{Type: "dynamic"},
+							"fake-vip":      Network{Type: "vip", IP: "169.50.20.5"},
+						}
+
+						_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("private network only"))
 					})
 				})
 			})
@@ -354,7 +671,7 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 			Context("missing correct VMProperties", func() {
 				BeforeEach(func() {
 					agentID = "fake-agent-id"
-					stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, logger)
+					stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, waitPolicies, logger)
 					networks = Networks{}
 					env = Environment{}
 
@@ -385,7 +702,7 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						Datacenter: sldatatypes.Datacenter{Name: "fake-datacenter"},
 					}
 
-					_, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+					_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 					Expect(err).To(HaveOccurred())
 				})
 
@@ -395,7 +712,7 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						Datacenter: sldatatypes.Datacenter{Name: "fake-datacenter"},
 					}
 
-					_, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+					_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 					Expect(err).To(HaveOccurred())
 				})
 
@@ -405,9 +722,29 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 						MaxMemory: 1024,
 					}
 
-					_, err := creator.Create(agentID, stemcell, cloudProps, networks, env)
+					_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
 					Expect(err).To(HaveOccurred())
 				})
+
+				It("reports every invalid property as a cloud error before creating the VM", func() {
+					cloudProps = VMCloudProperties{
+						StartCpus:  3,
+						MaxMemory:  3000,
+						Domain:     "fake-domain.com",
+						Datacenter: sldatatypes.Datacenter{Name: "fake-unknown-datacenter"},
+					}
+
+					_, err := creator.Create(context.Background(), agentID, stemcell, cloudProps, networks, env)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("datacenter 'fake-unknown-datacenter' is not available"))
+					Expect(err.Error()).To(ContainSubstring("startCpus 3 is not offered"))
+					Expect(err.Error()).To(ContainSubstring("maxMemory 3000 is not offered"))
+
+					cloudErr, found := bslcapi.FindCloudError(err)
+					Expect(found).To(BeTrue())
+					Expect(cloudErr.Type()).To(Equal("Bosh::Clouds::CloudError"))
+					Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Virtual_Guest/getCreateObjectOptions.json"))
+				})
 			})
 		})
 	})
@@ -415,6 +752,8 @@ var _ = Describe("SoftLayer_Virtual_Guest_Creator", func() {
 
 func setFakeSoftlayerClientCreateObjectTestFixturesWithEphemeralDiskSize(fakeSoftLayerClient *fakeslclient.FakeSoftLayerClient) {
 	fileNames := []string{
+		"SoftLayer_Virtual_Guest_Service_getCreateObjectOptions.json",
+
 		"SoftLayer_Virtual_Guest_Service_createObject.json",
 
 		"SoftLayer_Virtual_Guest_Service_getLastTransaction.json",
@@ -452,6 +791,8 @@ func setFakeSoftlayerClientCreateObjectTestFixturesWithEphemeralDiskSize_OS_Relo
 
 func setFakeSoftlayerClientCreateObjectTestFixturesWithoutEphemeralDiskSize(fakeSoftLayerClient *fakeslclient.FakeSoftLayerClient) {
 	fileNames := []string{
+		"SoftLayer_Virtual_Guest_Service_getCreateObjectOptions.json",
+
 		"SoftLayer_Virtual_Guest_Service_createObject.json",
 
 		"SoftLayer_Virtual_Guest_Service_getLastTransaction.json",
@@ -475,6 +816,8 @@ func setFakeSoflayerClientCreateObjectTestFixturesWithoutEphemeralDiskSize_OS_Re
 
 func setFakeSoftlayerClientCreateObjectTestFixturesWithoutBoshIP(fakeSoftLayerClient *fakeslclient.FakeSoftLayerClient) {
 	fileNames := []string{
+		"SoftLayer_Virtual_Guest_Service_getCreateObjectOptions.json",
+
 		"SoftLayer_Virtual_Guest_Service_createObject.json",
 		"SoftLayer_Virtual_Guest_Service_getLastTransaction.json",
 		"SoftLayer_Virtual_Guest_Service_getActiveTransactions_None.json",
@@ -508,3 +851,33 @@ func setFakeSoftlayerClientCreateObjectTestFixturesWithoutBoshIP_OS_Reload(fakeS
 	}
 	testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, fileNames)
 }
+
+func setFakeSoftlayerClientCreateObjectTestFixturesWithManualNetwork(fakeSoftLayerClient *fakeslclient.FakeSoftLayerClient) {
+	fileNames := []string{
+		"SoftLayer_Virtual_Guest_Service_getCreateObjectOptions.json",
+
+		"SoftLayer_Account_Service_getSubnets.json",
+		"SoftLayer_Network_Subnet_IpAddress_Service_getByIpAddress.json",
+
+		"SoftLayer_Virtual_Guest_Service_createObject.json",
+
+		"SoftLayer_Virtual_Guest_Service_getLastTransaction.json",
+
+		"SoftLayer_Virtual_Guest_Service_getObject.json",
+	}
+	testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, fileNames)
+}
+
+func setFakeSoftlayerClientCreateObjectTestFixturesWithVipNetwork(fakeSoftLayerClient *fakeslclient.FakeSoftLayerClient) {
+	fileNames := []string{
+		"SoftLayer_Virtual_Guest_Service_getCreateObjectOptions.json",
+
+		"SoftLayer_Virtual_Guest_Service_createObject.json",
+
+		"SoftLayer_Virtual_Guest_Service_getLastTransaction.json",
+
+		"SoftLayer_Account_Service_getGlobalIpRecords.json",
+		"SoftLayer_Network_Subnet_IpAddress_Global_Service_route_true.json",
+	}
+	testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, fileNames)
+}
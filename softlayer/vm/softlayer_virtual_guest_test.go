@@ -1,6 +1,7 @@
 package vm_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"time"
@@ -21,6 +22,7 @@ import (
 	fakedisk "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/disk/fakes"
 	fakestemcell "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell/fakes"
 	fakevm "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm/fakes"
+	util "github.com/cloudfoundry/bosh-softlayer-cpi/util"
 	fakesutil "github.com/cloudfoundry/bosh-softlayer-cpi/util/fakes"
 	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
 
@@ -69,7 +71,7 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 			},
 		}
 
-		vm = NewSoftLayerVirtualGuest(virtualGuest, fakeSoftLayerClient, sshClient, logger)
+		vm = NewSoftLayerVirtualGuest(virtualGuest, fakeSoftLayerClient, sshClient, DiskAttachModeSsh, bslcommon.UniformWaitPolicies(bslcommon.NewWaitPolicy(2*time.Second, 1*time.Second)), logger)
 		vm.SetAgentEnvService(agentEnvService)
 	})
 
@@ -77,6 +79,7 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 		Context("valid VM ID is used and averageDuration is normal", func() {
 			BeforeEach(func() {
 				fileNames := []string{
+					"SoftLayer_Account_Service_getGlobalIpRecords_None.json",
 					"SoftLayer_Virtual_Guest_Service_getActiveTransactions_None.json",
 					"SoftLayer_Virtual_Guest_Service_deleteObject_true.json",
 					"SoftLayer_Virtual_Guest_Service_getActiveTransactions.json",
@@ -88,17 +91,40 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 			})
 
 			It("deletes the VM successfully", func() {
-				bslcommon.TIMEOUT = 2 * time.Second
-				bslcommon.POLLING_INTERVAL = 1 * time.Second
+				err := vm.Delete(context.Background(), "fake-agentID")
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("valid VM ID is used and a VIP is routed to the VM", func() {
+			BeforeEach(func() {
+				fileNames := []string{
+					"SoftLayer_Account_Service_getGlobalIpRecords.json",
+					"SoftLayer_Network_Subnet_IpAddress_Global_Service_unroute_true.json",
+					"SoftLayer_Virtual_Guest_Service_getActiveTransactions_None.json",
+					"SoftLayer_Virtual_Guest_Service_deleteObject_true.json",
+					"SoftLayer_Virtual_Guest_Service_getActiveTransactions.json",
+					"SoftLayer_Virtual_Guest_Service_getObject.json",
+					"SoftLayer_Virtual_Guest_Service_getActiveTransaction.json",
+					"SoftLayer_Virtual_Guest_Service_getEmptyObject.json",
+				}
+				testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, fileNames)
+			})
 
-				err := vm.Delete("fake-agentID")
+			It("releases the VIP without reading the agent env and deletes the VM successfully", func() {
+				agentEnvService.FetchErr = errors.New("fake-fetch-error")
+
+				err := vm.Delete(context.Background(), "fake-agentID")
 				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeSoftLayerClient.FakeHttpClient.DoRawHttpRequestResponsesIndex).To(BeNumerically(">", 2))
+				Expect(agentEnvService.FetchCalled).To(BeFalse())
 			})
 		})
 
 		Context("valid VM ID is used and averageDuration is \"\"", func() {
 			BeforeEach(func() {
 				fileNames := []string{
+					"SoftLayer_Account_Service_getGlobalIpRecords_None.json",
 					"SoftLayer_Virtual_Guest_Service_getActiveTransactions_None.json",
 					"SoftLayer_Virtual_Guest_Service_deleteObject_true.json",
 					"SoftLayer_Virtual_Guest_Service_getActiveTransactions.json",
@@ -110,10 +136,7 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 			})
 
 			It("deletes the VM successfully", func() {
-				bslcommon.TIMEOUT = 2 * time.Second
-				bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-				err := vm.Delete("")
+				err := vm.Delete(context.Background(), "")
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
@@ -121,6 +144,7 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 		Context("valid VM ID is used and averageDuration is invalid", func() {
 			BeforeEach(func() {
 				fileNames := []string{
+					"SoftLayer_Account_Service_getGlobalIpRecords_None.json",
 					"SoftLayer_Virtual_Guest_Service_getActiveTransactions_None.json",
 					"SoftLayer_Virtual_Guest_Service_deleteObject_true.json",
 					"SoftLayer_Virtual_Guest_Service_getActiveTransactions.json",
@@ -132,10 +156,7 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 			})
 
 			It("deletes the VM successfully", func() {
-				bslcommon.TIMEOUT = 2 * time.Second
-				bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-				err := vm.Delete("fake-agent-id")
+				err := vm.Delete(context.Background(), "fake-agent-id")
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
@@ -143,6 +164,7 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 		Context("invalid VM ID is used", func() {
 			BeforeEach(func() {
 				fileNames := []string{
+					"SoftLayer_Account_Service_getGlobalIpRecords_None.json",
 					"SoftLayer_Virtual_Guest_Service_getActiveTransactions.json",
 					"SoftLayer_Virtual_Guest_Service_getActiveTransactions_None.json",
 					"SoftLayer_Virtual_Guest_Service_deleteObject_false.json",
@@ -152,12 +174,10 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 					"SoftLayer_Virtual_Guest_Service_getEmptyObject.json",
 				}
 				testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, fileNames)
-				bslcommon.TIMEOUT = 2 * time.Second
-				bslcommon.POLLING_INTERVAL = 1 * time.Second
 			})
 
 			It("fails deleting the VM", func() {
-				err := vm.Delete("fake-agent-id")
+				err := vm.Delete(context.Background(), "fake-agent-id")
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -207,7 +227,7 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 			})
 
 			It("os reload on the VM successfully", func() {
-				err := vm.ReloadOS(stemcell)
+				err := vm.ReloadOS(context.Background(), stemcell)
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
@@ -289,9 +309,40 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 		})
 
 		It("returns the expected network", func() {
-			err := vm.ConfigureNetworks(networks)
+			err := vm.ConfigureNetworks(context.Background(), networks)
 			Expect(err).ToNot(HaveOccurred())
 		})
+
+		It("routes VIPs and records them in the agent env", func() {
+			networks["fake-vip"] = Network{Type: "vip", IP: "169.50.20.5"}
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(fakeSoftLayerClient, []string{
+				"SoftLayer_Account_Service_getGlobalIpRecords.json",
+				"SoftLayer_Network_Subnet_IpAddress_Global_Service_route_true.json",
+			})
+
+			err := vm.ConfigureNetworks(context.Background(), networks)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeSoftLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Network_Subnet_IpAddress_Global/4444/route.json"))
+			Expect(agentEnvService.UpdateAgentEnv.Networks["fake-vip"].IP).To(Equal("169.50.20.5"))
+		})
+	})
+
+	Describe("GetAttachedDiskIds", func() {
+		It("returns the ids of the attached iSCSI volumes", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fakeSoftLayerClient, "SoftLayer_Virtual_Guest_Service_getAllowedNetworkStorage.json")
+
+			diskIds, err := vm.GetAttachedDiskIds()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskIds).To(Equal([]int{1234, 9012}))
+		})
+
+		It("reports error when SoftLayer returns an HTTP error code", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(fakeSoftLayerClient, "SoftLayer_Virtual_Guest_Service_getAllowedNetworkStorage.json")
+			fakeSoftLayerClient.FakeHttpClient.DoRawHttpRequestInt = 500
+
+			_, err := vm.GetAttachedDiskIds()
+			Expect(err).To(HaveOccurred())
+		})
 	})
 
 	Describe("#AttachDisk", func() {
@@ -360,13 +411,10 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 				"",
 				expectedDmSetupLs1,
 			}
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.AttachDisk(disk)
+			err := vm.AttachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -381,13 +429,10 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 				"",
 				expectedPartitions2,
 			}
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.AttachDisk(disk)
+			err := vm.AttachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -403,23 +448,17 @@ var _ = Describe("SoftLayerVirtualGuest", func() {
 				expectedDmSetupLs2,
 			}
 
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.AttachDisk(disk)
+			err := vm.AttachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("reports error when failed to attach the iSCSI volume", func() {
 
-			sshClient.ExecCommandReturns("fake-result", errors.New("fake-error"))
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.AttachDisk(disk)
+			sshClient.RunReturns(util.CommandResult{Stdout: "fake-result"}, errors.New("fake-error"))
+			err := vm.AttachDisk(context.Background(), disk)
 			Expect(err).To(HaveOccurred())
 		})
 	})
@@ -485,13 +524,10 @@ iscsiadm: No records found
 				"",
 				expectStartOpenIscsi,
 			}
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.DetachDisk(disk)
+			err := vm.DetachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -506,22 +542,16 @@ iscsiadm: No records found
 				expectStartOpenIscsi,
 				expectRestartMultipathd,
 			}
-			sshClient.ExecCommandStub = func(_, _, _, _ string) (string, error) {
-				return expectedCmdResults[sshClient.ExecCommandCallCount()-1], nil
+			sshClient.RunStub = func(_, _, _, _ string, _ time.Duration) (util.CommandResult, error) {
+				return util.CommandResult{Stdout: expectedCmdResults[sshClient.RunCallCount()-1]}, nil
 			}
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.DetachDisk(disk)
+			err := vm.DetachDisk(context.Background(), disk)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("reports error when failed to detach iSCSI volume", func() {
-			sshClient.ExecCommandReturns("fake-result", errors.New("fake-error"))
-			bslcommon.TIMEOUT = 2 * time.Second
-			bslcommon.POLLING_INTERVAL = 1 * time.Second
-
-			err := vm.DetachDisk(disk)
+			sshClient.RunReturns(util.CommandResult{Stdout: "fake-result"}, errors.New("fake-error"))
+			err := vm.DetachDisk(context.Background(), disk)
 			Expect(err).To(HaveOccurred())
 		})
 	})
@@ -0,0 +1,223 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	slcommon "github.com/maximilien/softlayer-go/common"
+	sl "github.com/maximilien/softlayer-go/softlayer"
+
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
+)
+
+type softLayerGlobalIp struct {
+	Id                   int               `json:"id"`
+	IpAddress            softLayerAddress  `json:"ipAddress"`
+	DestinationIpAddress *softLayerAddress `json:"destinationIpAddress,omitempty"`
+}
+
+type softLayerAddress struct {
+	IpAddress string `json:"ipAddress"`
+}
+
+// RouteVipNetworks routes the global IP of each vip network to the given
+// public IP of a VM
+func RouteVipNetworks(softLayerClient sl.Client, networks Networks, destinationIp string) error {
+	vips := vipAddresses(networks)
+	if len(vips) == 0 {
+		return nil
+	}
+
+	if len(destinationIp) == 0 {
+		return bosherr.Error("VIP networks require a VM with a public network component")
+	}
+
+	globalIps, err := getGlobalIps(softLayerClient)
+	if err != nil {
+		return err
+	}
+
+	for _, vip := range vips {
+		globalIp, found := globalIps[vip]
+		if !found {
+			return bosherr.Errorf("VIP '%s' is not a global IP of the SoftLayer account", vip)
+		}
+
+		if globalIp.DestinationIpAddress != nil && globalIp.DestinationIpAddress.IpAddress == destinationIp {
+			continue
+		}
+
+		err = routeGlobalIp(softLayerClient, globalIp, destinationIp)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReleaseVipNetworks unroutes the global IP of each vip network that is still
+// routed to the given public IP of a VM
+func ReleaseVipNetworks(softLayerClient sl.Client, networks Networks, destinationIp string) error {
+	vips := vipAddresses(networks)
+	if len(vips) == 0 || len(destinationIp) == 0 {
+		return nil
+	}
+
+	globalIps, err := getGlobalIps(softLayerClient)
+	if err != nil {
+		return err
+	}
+
+	for _, vip := range vips {
+		globalIp, found := globalIps[vip]
+		if !found || globalIp.DestinationIpAddress == nil || globalIp.DestinationIpAddress.IpAddress != destinationIp {
+			continue
+		}
+
+		err = unrouteGlobalIp(softLayerClient, globalIp)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReleaseGlobalIps unroutes every global IP of the account that is routed to
+// the given public IP of a VM. Unlike ReleaseVipNetworks it does not need the
+// VM's networks, so it also works once the VM cannot be reached anymore.
+func ReleaseGlobalIps(softLayerClient sl.Client, destinationIp string) error {
+	if len(destinationIp) == 0 {
+		return nil
+	}
+
+	globalIps, err := getGlobalIps(softLayerClient)
+	if err != nil {
+		return err
+	}
+
+	for _, globalIp := range globalIps {
+		if globalIp.DestinationIpAddress == nil || globalIp.DestinationIpAddress.IpAddress != destinationIp {
+			continue
+		}
+
+		err = unrouteGlobalIp(softLayerClient, globalIp)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReconfigureVipNetworks releases the VIPs only found in the old networks and
+// routes the ones of the new networks
+func ReconfigureVipNetworks(softLayerClient sl.Client, oldNetworks Networks, newNetworks Networks, destinationIp string) error {
+	kept := map[string]bool{}
+	for _, vip := range vipAddresses(newNetworks) {
+		kept[vip] = true
+	}
+
+	stale := Networks{}
+	for name, network := range oldNetworks {
+		if network.IsVip() && !kept[network.IP] {
+			stale[name] = network
+		}
+	}
+
+	err := ReleaseVipNetworks(softLayerClient, stale, destinationIp)
+	if err != nil {
+		return bosherr.WrapError(err, "Releasing VIPs")
+	}
+
+	err = RouteVipNetworks(softLayerClient, newNetworks, destinationIp)
+	if err != nil {
+		return bosherr.WrapError(err, "Routing VIPs")
+	}
+
+	return nil
+}
+
+func vipAddresses(networks Networks) []string {
+	vips := []string{}
+	for _, name := range networks.Names() {
+		if networks[name].IsVip() && len(networks[name].IP) > 0 {
+			vips = append(vips, networks[name].IP)
+		}
+	}
+
+	return vips
+}
+
+func getGlobalIps(softLayerClient sl.Client) (map[string]softLayerGlobalIp, error) {
+	objectMask := []string{"id", "ipAddress.ipAddress", "destinationIpAddress.ipAddress"}
+	response, errorCode, err := softLayerClient.GetHttpClient().DoRawHttpRequestWithObjectMask("SoftLayer_Account/getGlobalIpRecords.json", objectMask, "GET", new(bytes.Buffer))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting global IPs of SoftLayer account")
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return nil, bslcommon.NewSoftLayerHttpError(errorCode, "Getting global IPs of SoftLayer account")
+	}
+
+	records := []softLayerGlobalIp{}
+	err = json.Unmarshal(response, &records)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling global IPs of SoftLayer account")
+	}
+
+	globalIps := map[string]softLayerGlobalIp{}
+	for _, record := range records {
+		globalIps[record.IpAddress.IpAddress] = record
+	}
+
+	return globalIps, nil
+}
+
+func routeGlobalIp(softLayerClient sl.Client, globalIp softLayerGlobalIp, destinationIp string) error {
+	parameters := map[string]interface{}{
+		"parameters": []string{destinationIp},
+	}
+
+	requestBody, err := json.Marshal(parameters)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling route parameters")
+	}
+
+	path := fmt.Sprintf("SoftLayer_Network_Subnet_IpAddress_Global/%d/route.json", globalIp.Id)
+	response, errorCode, err := softLayerClient.GetHttpClient().DoRawHttpRequest(path, "POST", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Routing global IP '%s' to '%s'", globalIp.IpAddress.IpAddress, destinationIp)
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return bslcommon.NewSoftLayerHttpError(errorCode, "Routing global IP '%s' to '%s'", globalIp.IpAddress.IpAddress, destinationIp)
+	}
+
+	if string(response) != "true" {
+		return bosherr.Errorf("Routing global IP '%s' to '%s' was not accepted: %s", globalIp.IpAddress.IpAddress, destinationIp, string(response))
+	}
+
+	return nil
+}
+
+func unrouteGlobalIp(softLayerClient sl.Client, globalIp softLayerGlobalIp) error {
+	path := fmt.Sprintf("SoftLayer_Network_Subnet_IpAddress_Global/%d/unroute.json", globalIp.Id)
+	response, errorCode, err := softLayerClient.GetHttpClient().DoRawHttpRequest(path, "GET", new(bytes.Buffer))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Unrouting global IP '%s'", globalIp.IpAddress.IpAddress)
+	}
+
+	if slcommon.IsHttpErrorCode(errorCode) {
+		return bslcommon.NewSoftLayerHttpError(errorCode, "Unrouting global IP '%s'", globalIp.IpAddress.IpAddress)
+	}
+
+	if string(response) != "true" {
+		return bosherr.Errorf("Unrouting global IP '%s' was not accepted: %s", globalIp.IpAddress.IpAddress, string(response))
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package vm_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/vm"
+
+	testhelpers "github.com/cloudfoundry/bosh-softlayer-cpi/test_helpers"
+
+	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
+)
+
+var _ = Describe("VipNetworks", func() {
+	var (
+		softLayerClient *fakeslclient.FakeSoftLayerClient
+		networks        Networks
+	)
+
+	BeforeEach(func() {
+		softLayerClient = fakeslclient.NewFakeSoftLayerClient("fake-username", "fake-api-key")
+		networks = Networks{
+			"fake-network0": Network{Type: "dynamic"},
+			"fake-vip":      Network{Type: "vip", IP: "169.50.20.5"},
+		}
+	})
+
+	Describe("RouteVipNetworks", func() {
+		It("does nothing when there are no vip networks", func() {
+			err := RouteVipNetworks(softLayerClient, Networks{"fake-network0": Network{Type: "dynamic"}}, "fake-primary-ip")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(0))
+		})
+
+		It("routes the global IP to the destination", func() {
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+				"SoftLayer_Account_Service_getGlobalIpRecords.json",
+				"SoftLayer_Network_Subnet_IpAddress_Global_Service_route_true.json",
+			})
+
+			err := RouteVipNetworks(softLayerClient, networks, "fake-primary-ip")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Network_Subnet_IpAddress_Global/4444/route.json"))
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestRequestType).To(Equal("POST"))
+		})
+
+		It("skips global IPs already routed to the destination", func() {
+			networks["fake-vip"] = Network{Type: "vip", IP: "169.50.20.6"}
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(softLayerClient, "SoftLayer_Account_Service_getGlobalIpRecords.json")
+
+			err := RouteVipNetworks(softLayerClient, networks, "fake-primary-ip")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(1))
+		})
+
+		It("returns error when the VIP is not a global IP of the account", func() {
+			networks["fake-vip"] = Network{Type: "vip", IP: "169.50.30.1"}
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(softLayerClient, "SoftLayer_Account_Service_getGlobalIpRecords.json")
+
+			err := RouteVipNetworks(softLayerClient, networks, "fake-primary-ip")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("VIP '169.50.30.1' is not a global IP of the SoftLayer account"))
+		})
+
+		It("returns error when the VM has no public IP", func() {
+			err := RouteVipNetworks(softLayerClient, networks, "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("public network component"))
+		})
+	})
+
+	Describe("ReleaseVipNetworks", func() {
+		It("unroutes global IPs routed to the destination", func() {
+			networks["fake-vip"] = Network{Type: "vip", IP: "169.50.20.6"}
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+				"SoftLayer_Account_Service_getGlobalIpRecords.json",
+				"SoftLayer_Network_Subnet_IpAddress_Global_Service_unroute_true.json",
+			})
+
+			err := ReleaseVipNetworks(softLayerClient, networks, "fake-primary-ip")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Network_Subnet_IpAddress_Global/5555/unroute.json"))
+		})
+
+		It("leaves global IPs routed elsewhere alone", func() {
+			testhelpers.SetTestFixtureForFakeSoftLayerClient(softLayerClient, "SoftLayer_Account_Service_getGlobalIpRecords.json")
+
+			err := ReleaseVipNetworks(softLayerClient, networks, "fake-primary-ip")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(1))
+		})
+	})
+
+	Describe("ReleaseGlobalIps", func() {
+		It("unroutes every global IP routed to the destination", func() {
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+				"SoftLayer_Account_Service_getGlobalIpRecords.json",
+				"SoftLayer_Network_Subnet_IpAddress_Global_Service_unroute_true.json",
+			})
+
+			err := ReleaseGlobalIps(softLayerClient, "fake-primary-ip")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(2))
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Network_Subnet_IpAddress_Global/5555/unroute.json"))
+		})
+
+		It("leaves global IPs routed elsewhere alone", func() {
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{"SoftLayer_Account_Service_getGlobalIpRecords.json"})
+
+			err := ReleaseGlobalIps(softLayerClient, "fake-other-ip")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(1))
+		})
+
+		It("does nothing for a VM without public IP", func() {
+			err := ReleaseGlobalIps(softLayerClient, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(0))
+		})
+	})
+
+	Describe("ReconfigureVipNetworks", func() {
+		It("releases stale VIPs and routes new ones", func() {
+			oldNetworks := Networks{"fake-old-vip": Network{Type: "vip", IP: "169.50.20.6"}}
+			testhelpers.SetTestFixturesForFakeSoftLayerClient(softLayerClient, []string{
+				"SoftLayer_Account_Service_getGlobalIpRecords.json",
+				"SoftLayer_Network_Subnet_IpAddress_Global_Service_unroute_true.json",
+				"SoftLayer_Account_Service_getGlobalIpRecords.json",
+				"SoftLayer_Network_Subnet_IpAddress_Global_Service_route_true.json",
+			})
+
+			err := ReconfigureVipNetworks(softLayerClient, oldNetworks, networks, "fake-primary-ip")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestResponsesCount).To(Equal(4))
+			Expect(softLayerClient.FakeHttpClient.DoRawHttpRequestPath).To(Equal("SoftLayer_Network_Subnet_IpAddress_Global/4444/route.json"))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package vm
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net"
 	"net/url"
@@ -11,8 +12,10 @@ import (
 	"time"
 
 	sldatatypes "github.com/maximilien/softlayer-go/data_types"
+	sl "github.com/maximilien/softlayer-go/softlayer"
 
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
+	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
@@ -124,6 +127,82 @@ func UpdateEtcHostsOfBoshInit(record string) (err error) {
 	return nil
 }
 
+// rollbackFailedVM deletes a server that was provisioned but could not be
+// set up, since BOSH never learns its CID and would leave it running. Errors
+// are only logged so that the original failure is what gets reported.
+func rollbackFailedVM(vmFinder Finder, id int, cloudProps VMCloudProperties, logger boshlog.Logger) {
+	if cloudProps.KeepFailedVM {
+		logger.Warn(SOFTLAYER_VM_CREATOR_LOG_TAG, "Keeping VM `%d` after failed creation for debugging", id)
+		return
+	}
+
+	logger.Info(SOFTLAYER_VM_CREATOR_LOG_TAG, "Deleting VM `%d` after failed creation", id)
+
+	vm, found, err := vmFinder.Find(id)
+	if err != nil || !found {
+		logger.Error(SOFTLAYER_VM_CREATOR_LOG_TAG, "Cannot find VM `%d` to delete after failed creation, it must be deleted manually: %v", id, err)
+		return
+	}
+
+	// The request context may already be cancelled, which is often why
+	// creation failed; the server still has to be released
+	ctx := context.WithValue(context.Background(), rollbackContextKey{}, true)
+	err = vm.Delete(ctx, "")
+	if err != nil {
+		logger.Error(SOFTLAYER_VM_CREATOR_LOG_TAG, "Deleting VM `%d` after failed creation, it must be deleted manually: %s", id, err)
+	}
+}
+
+// rollbackFailedOrder deletes the virtual guest of an order that was placed
+// but never seen through, e.g. because waiting for it timed out or was
+// cancelled. A guest SoftLayer has not provisioned yet cannot be deleted and
+// is only reported.
+func rollbackFailedOrder(vmFinder Finder, softLayerClient sl.Client, orderId int, cloudProps VMCloudProperties, logger boshlog.Logger) {
+	if cloudProps.KeepFailedVM {
+		logger.Warn(SOFTLAYER_VM_CREATOR_LOG_TAG, "Keeping the virtual guest of order `%d` after failed creation for debugging", orderId)
+		return
+	}
+
+	virtualGuestId, found, err := FindOrderedVirtualGuest(softLayerClient, orderId)
+	if err != nil {
+		logger.Error(SOFTLAYER_VM_CREATOR_LOG_TAG, "Cannot find the virtual guest of order `%d` to delete after failed creation, it must be deleted manually: %s", orderId, err)
+		return
+	}
+
+	if !found {
+		logger.Error(SOFTLAYER_VM_CREATOR_LOG_TAG, "No virtual guest provisioned yet for order `%d`, it must be cancelled or deleted manually", orderId)
+		return
+	}
+
+	rollbackFailedVM(vmFinder, virtualGuestId, cloudProps, logger)
+}
+
+type rollbackContextKey struct{}
+
+// isRollback reports whether a VM is being deleted by rollbackFailedVM, in
+// which case steps that need the VM to be reachable are best-effort
+func isRollback(ctx context.Context) bool {
+	rollback, _ := ctx.Value(rollbackContextKey{}).(bool)
+	return rollback
+}
+
+// runRemoteCommand runs command as root on ip and returns its stdout. A
+// non-zero exit is returned as a util.CommandError carrying the remote stderr.
+func runRemoteCommand(sshClient util.SshClient, password string, ip string, command string, logger boshlog.Logger, logTag string) (string, error) {
+	result, err := sshClient.Run(ROOT_USER_NAME, password, ip, command, REMOTE_COMMAND_TIMEOUT)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Debug(logTag, "Command '%s' on '%s' exited with status %d after %s", util.RedactCommand(command), ip, result.ExitStatus, result.Duration)
+
+	if result.ExitStatus != 0 {
+		return result.Stdout, util.CommandError{Command: command, Result: result}
+	}
+
+	return result.Stdout, nil
+}
+
 const ETC_HOSTS_TEMPLATE = `127.0.0.1 localhost
 {{.}}
 `
@@ -12,6 +12,7 @@ import (
 
 	fakeslclient "github.com/maximilien/softlayer-go/client/fakes"
 
+	bslcommon "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/common"
 	bslcstem "github.com/cloudfoundry/bosh-softlayer-cpi/softlayer/stemcell"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -133,7 +134,7 @@ var _ = Describe("VM Utils", func() {
 
 		BeforeEach(func() {
 			agentID = "fake-agentID"
-			stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, logger)
+			stemcell = bslcstem.NewSoftLayerStemcell(1234, "fake-stemcell-uuid", softLayerClient, bslcommon.DefaultWaitPolicies(), logger)
 			cloudProps = VMCloudProperties{
 				StartCpus: 4,
 				MaxMemory: 2048,
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var quotedArgumentPattern = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+
+// RedactCommand hides the quoted arguments of command, which is where
+// passwords, password hashes and credentials are passed, so that the command
+// can be logged and reported in errors
+func RedactCommand(command string) string {
+	return quotedArgumentPattern.ReplaceAllString(command, "'<redacted>'")
+}
+
+// CommandResult describes a command that ran to completion on a remote server
+type CommandResult struct {
+	Stdout     string
+	Stderr     string
+	ExitStatus int
+	Duration   time.Duration
+}
+
+// CommandError reports a remote command that exited with a non-zero status
+type CommandError struct {
+	Command string
+	Result  CommandResult
+}
+
+func (e CommandError) Error() string {
+	stderr := strings.TrimSpace(e.Result.Stderr)
+	if stderr == "" {
+		return fmt.Sprintf("Command '%s' exited with status %d", RedactCommand(e.Command), e.Result.ExitStatus)
+	}
+
+	return fmt.Sprintf("Command '%s' exited with status %d: %s", RedactCommand(e.Command), e.Result.ExitStatus, stderr)
+}
+
+// CommandTimeoutError reports a remote command that was killed after running
+// longer than its timeout
+type CommandTimeoutError struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (e CommandTimeoutError) Error() string {
+	return fmt.Sprintf("Command '%s' timed out after %s", RedactCommand(e.Command), e.Timeout)
+}
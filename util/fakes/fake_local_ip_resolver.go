@@ -0,0 +1,10 @@
+package fakes
+
+type FakeLocalIPResolver struct {
+	LocalIPsIPs []string
+	LocalIPsErr error
+}
+
+func (r *FakeLocalIPResolver) LocalIPs() ([]string, error) {
+	return r.LocalIPsIPs, r.LocalIPsErr
+}
@@ -4,6 +4,7 @@ package fakes
 import (
 	"io"
 	"sync"
+	"time"
 
 	"github.com/cloudfoundry/bosh-softlayer-cpi/util"
 )
@@ -21,6 +22,19 @@ type FakeSshClient struct {
 		result1 string
 		result2 error
 	}
+	RunStub        func(username, password, ip, command string, timeout time.Duration) (util.CommandResult, error)
+	runMutex       sync.RWMutex
+	runArgsForCall []struct {
+		username string
+		password string
+		ip       string
+		command  string
+		timeout  time.Duration
+	}
+	runReturns struct {
+		result1 util.CommandResult
+		result2 error
+	}
 	DownloadStub        func(username, password, ip, srcFile string, destination io.Writer) error
 	downloadMutex       sync.RWMutex
 	downloadArgsForCall []struct {
@@ -69,6 +83,14 @@ type FakeSshClient struct {
 	uploadFileReturns struct {
 		result1 error
 	}
+	ForgetHostKeyStub        func(ip string) error
+	forgetHostKeyMutex       sync.RWMutex
+	forgetHostKeyArgsForCall []struct {
+		ip string
+	}
+	forgetHostKeyReturns struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -110,6 +132,44 @@ func (fake *FakeSshClient) ExecCommandReturns(result1 string, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeSshClient) Run(username string, password string, ip string, command string, timeout time.Duration) (util.CommandResult, error) {
+	fake.runMutex.Lock()
+	fake.runArgsForCall = append(fake.runArgsForCall, struct {
+		username string
+		password string
+		ip       string
+		command  string
+		timeout  time.Duration
+	}{username, password, ip, command, timeout})
+	fake.recordInvocation("Run", []interface{}{username, password, ip, command, timeout})
+	fake.runMutex.Unlock()
+	if fake.RunStub != nil {
+		return fake.RunStub(username, password, ip, command, timeout)
+	} else {
+		return fake.runReturns.result1, fake.runReturns.result2
+	}
+}
+
+func (fake *FakeSshClient) RunCallCount() int {
+	fake.runMutex.RLock()
+	defer fake.runMutex.RUnlock()
+	return len(fake.runArgsForCall)
+}
+
+func (fake *FakeSshClient) RunArgsForCall(i int) (string, string, string, string, time.Duration) {
+	fake.runMutex.RLock()
+	defer fake.runMutex.RUnlock()
+	return fake.runArgsForCall[i].username, fake.runArgsForCall[i].password, fake.runArgsForCall[i].ip, fake.runArgsForCall[i].command, fake.runArgsForCall[i].timeout
+}
+
+func (fake *FakeSshClient) RunReturns(result1 util.CommandResult, result2 error) {
+	fake.RunStub = nil
+	fake.runReturns = struct {
+		result1 util.CommandResult
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSshClient) Download(username string, password string, ip string, srcFile string, destination io.Writer) error {
 	fake.downloadMutex.Lock()
 	fake.downloadArgsForCall = append(fake.downloadArgsForCall, struct {
@@ -258,11 +318,46 @@ func (fake *FakeSshClient) UploadFileReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeSshClient) ForgetHostKey(ip string) error {
+	fake.forgetHostKeyMutex.Lock()
+	fake.forgetHostKeyArgsForCall = append(fake.forgetHostKeyArgsForCall, struct {
+		ip string
+	}{ip})
+	fake.recordInvocation("ForgetHostKey", []interface{}{ip})
+	fake.forgetHostKeyMutex.Unlock()
+	if fake.ForgetHostKeyStub != nil {
+		return fake.ForgetHostKeyStub(ip)
+	} else {
+		return fake.forgetHostKeyReturns.result1
+	}
+}
+
+func (fake *FakeSshClient) ForgetHostKeyCallCount() int {
+	fake.forgetHostKeyMutex.RLock()
+	defer fake.forgetHostKeyMutex.RUnlock()
+	return len(fake.forgetHostKeyArgsForCall)
+}
+
+func (fake *FakeSshClient) ForgetHostKeyArgsForCall(i int) string {
+	fake.forgetHostKeyMutex.RLock()
+	defer fake.forgetHostKeyMutex.RUnlock()
+	return fake.forgetHostKeyArgsForCall[i].ip
+}
+
+func (fake *FakeSshClient) ForgetHostKeyReturns(result1 error) {
+	fake.ForgetHostKeyStub = nil
+	fake.forgetHostKeyReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeSshClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.execCommandMutex.RLock()
 	defer fake.execCommandMutex.RUnlock()
+	fake.runMutex.RLock()
+	defer fake.runMutex.RUnlock()
 	fake.downloadMutex.RLock()
 	defer fake.downloadMutex.RUnlock()
 	fake.downloadFileMutex.RLock()
@@ -271,6 +366,8 @@ func (fake *FakeSshClient) Invocations() map[string][][]interface{} {
 	defer fake.uploadMutex.RUnlock()
 	fake.uploadFileMutex.RLock()
 	defer fake.uploadFileMutex.RUnlock()
+	fake.forgetHostKeyMutex.RLock()
+	defer fake.forgetHostKeyMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -0,0 +1,126 @@
+package util
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// HostKeyFingerprint returns the SHA256 fingerprint of key as printed by ssh-keygen -l
+func HostKeyFingerprint(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// knownHosts stores one "<ip> <fingerprint>" line per server
+type knownHosts struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func newKnownHosts(path string) *knownHosts {
+	return &knownHosts{path: path}
+}
+
+// Verify accepts fingerprint if it matches the one recorded for ip, or
+// records it if ip has not been seen before
+func (k *knownHosts) Verify(ip string, fingerprint string) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	entries, err := k.read()
+	if err != nil {
+		return err
+	}
+
+	known, found := entries[ip]
+	if found {
+		if known != fingerprint {
+			return bosherr.Errorf("Host key of '%s' has fingerprint '%s', but '%s' is recorded in '%s'", ip, fingerprint, known, k.path)
+		}
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(k.path), 0700)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating directory of known hosts file '%s'", k.path)
+	}
+
+	file, err := os.OpenFile(k.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening known hosts file '%s'", k.path)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s %s\n", ip, fingerprint)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Recording host key of '%s'", ip)
+	}
+
+	return nil
+}
+
+func (k *knownHosts) Forget(ip string) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	entries, err := k.read()
+	if err != nil {
+		return err
+	}
+
+	_, found := entries[ip]
+	if !found {
+		return nil
+	}
+	delete(entries, ip)
+
+	lines := ""
+	for host, fingerprint := range entries {
+		lines += fmt.Sprintf("%s %s\n", host, fingerprint)
+	}
+
+	err = ioutil.WriteFile(k.path, []byte(lines), 0600)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Forgetting host key of '%s'", ip)
+	}
+
+	return nil
+}
+
+func (k *knownHosts) read() (map[string]string, error) {
+	entries := map[string]string{}
+
+	file, err := os.Open(k.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, bosherr.WrapErrorf(err, "Opening known hosts file '%s'", k.path)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			entries[fields[0]] = fields[1]
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading known hosts file '%s'", k.path)
+	}
+
+	return entries, nil
+}
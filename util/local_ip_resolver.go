@@ -0,0 +1,29 @@
+package util
+
+import (
+	"net"
+)
+
+type LocalIPResolver interface {
+	LocalIPs() ([]string, error)
+}
+
+type RealLocalIPResolver struct{}
+
+func (r RealLocalIPResolver) LocalIPs() ([]string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return []string{}, err
+	}
+
+	ips := []string{}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+			continue
+		}
+		ips = append(ips, ipNet.IP.String())
+	}
+
+	return ips, nil
+}
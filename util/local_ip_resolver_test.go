@@ -0,0 +1,26 @@
+package util_test
+
+import (
+	"net"
+
+	bscutil "github.com/cloudfoundry/bosh-softlayer-cpi/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LocalIPResolver", func() {
+	Context("RealLocalIPResolver", func() {
+		It("#LocalIPs returns only non-loopback IPv4 addresses", func() {
+			ips, err := bscutil.RealLocalIPResolver{}.LocalIPs()
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, ip := range ips {
+				parsed := net.ParseIP(ip)
+				Expect(parsed).ToNot(BeNil())
+				Expect(parsed.To4()).ToNot(BeNil())
+				Expect(parsed.IsLoopback()).To(BeFalse())
+			}
+		})
+	})
+})
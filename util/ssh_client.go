@@ -1,10 +1,15 @@
 package util
 
 import (
+	"bytes"
 	"io"
+	"io/ioutil"
 	"net"
 	"os"
+	"time"
 
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
@@ -13,41 +18,175 @@ import (
 type SshClient interface {
 	ExecCommand(username string, password string, ip string, command string) (string, error)
 
+	// Run executes command and reports its output, exit status and duration.
+	// A non-zero exit is not an error; a command still running after timeout
+	// is killed and reported as a CommandTimeoutError. A zero timeout waits forever.
+	Run(username, password, ip, command string, timeout time.Duration) (CommandResult, error)
+
 	Download(username, password, ip, srcFile string, destination io.Writer) error
 	DownloadFile(username string, password string, ip string, srcFile string, destFile string) error
 
 	Upload(username, password, ip string, source io.Reader, destFile string) error
 	UploadFile(username string, password string, ip string, srcFile string, destFile string) error
+
+	// ForgetHostKey drops the host key learned for ip, so that the key of a
+	// newly provisioned or reloaded server is recorded on the next connection
+	ForgetHostKey(ip string) error
 }
 
-type sshClientImpl struct{}
+const (
+	sshClientLogTag = "SshClient"
 
-func (c *sshClientImpl) ExecCommand(username string, password string, ip string, command string) (string, error) {
-	config := &ssh.ClientConfig{
-		User: username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(password),
-		},
+	// Connecting to a server, including the SSH handshake, fails after this long
+	sshDialTimeout = 30 * time.Second
+
+	// DefaultKnownHostsPath keeps learned host keys in the CPI job's data dir
+	DefaultKnownHostsPath = "/var/vcap/data/cpi/known_hosts"
+)
+
+type SshClientOptions struct {
+	// Private key offered before the password, matching a key registered in
+	// SoftLayer and listed in the VM's ssh_keys cloud property
+	PrivateKeyPath string `json:"private_key_path,omitempty"`
+
+	// File of host key fingerprints learned on first connection to a server,
+	// DefaultKnownHostsPath unless set
+	KnownHostsPath string `json:"known_hosts_path,omitempty"`
+
+	// Fingerprints pinned per IP, in the "SHA256:<base64>" format of ssh-keygen -l
+	HostKeyFingerprints map[string]string `json:"host_key_fingerprints,omitempty"`
+
+	// Accept any host key without recording it. Only meant for environments
+	// where servers cannot be reached by anyone else.
+	InsecureIgnoreHostKey bool `json:"insecure_ignore_host_key,omitempty"`
+}
+
+// WithDefaults verifies host keys against DefaultKnownHostsPath unless
+// another file is set or verification is explicitly turned off
+func (o SshClientOptions) WithDefaults() SshClientOptions {
+	if o.KnownHostsPath == "" && !o.InsecureIgnoreHostKey {
+		o.KnownHostsPath = DefaultKnownHostsPath
 	}
 
-	client, err := ssh.Dial("tcp", address(ip), config)
-	if err != nil {
-		return "", err
+	return o
+}
+
+func (o SshClientOptions) Validate() error {
+	if o.PrivateKeyPath != "" {
+		_, err := o.signer()
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Loading private key '%s'", o.PrivateKeyPath)
+		}
 	}
-	defer client.Close()
 
-	session, err := client.NewSession()
+	if o.InsecureIgnoreHostKey && (o.KnownHostsPath != "" || len(o.HostKeyFingerprints) > 0) {
+		return bosherr.Error("Must not set InsecureIgnoreHostKey together with KnownHostsPath or HostKeyFingerprints")
+	}
+
+	return nil
+}
+
+func (o SshClientOptions) signer() (ssh.Signer, error) {
+	privateKey, err := ioutil.ReadFile(o.PrivateKeyPath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer session.Close()
 
-	output, err := session.Output(command)
+	return ssh.ParsePrivateKey(privateKey)
+}
+
+type sshClientImpl struct {
+	options    SshClientOptions
+	knownHosts *knownHosts
+	pool       *sshConnectionPool
+	logger     boshlog.Logger
+}
+
+// NewSshClient returns a client that keeps one connection per user and server
+// open across calls, pinging it with keepalives and closing it once idle.
+// Host keys are verified unless options turn that off explicitly.
+func NewSshClient(options SshClientOptions, logger boshlog.Logger) SshClient {
+	options = options.WithDefaults()
+
+	client := &sshClientImpl{options: options, logger: logger}
+	if options.KnownHostsPath != "" {
+		client.knownHosts = newKnownHosts(options.KnownHostsPath)
+	}
+	if options.InsecureIgnoreHostKey {
+		logger.Warn(sshClientLogTag, "Host keys of servers are not verified, SSH connections can be intercepted")
+	}
+	client.pool = newSshConnectionPool(client.dial, sshKeepAliveInterval, sshIdleTimeout)
+
+	return client
+}
+
+func (c *sshClientImpl) ExecCommand(username string, password string, ip string, command string) (string, error) {
+	result, err := c.Run(username, password, ip, command, 0)
 	if err != nil {
 		return "", err
 	}
 
-	return string(output), nil
+	if result.ExitStatus != 0 {
+		return "", CommandError{Command: command, Result: result}
+	}
+
+	return result.Stdout, nil
+}
+
+func (c *sshClientImpl) Run(username, password, ip, command string, timeout time.Duration) (CommandResult, error) {
+	var result CommandResult
+	start := time.Now()
+
+	err := c.withConnection(username, password, ip, func(client *ssh.Client) error {
+		session, err := client.NewSession()
+		if err != nil {
+			return sshConnectionError{err}
+		}
+		defer session.Close()
+
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		session.Stdout = stdout
+		session.Stderr = stderr
+
+		err = session.Start(command)
+		if err != nil {
+			return err
+		}
+
+		exited := make(chan error, 1)
+		go func() {
+			exited <- session.Wait()
+		}()
+
+		var timedOut <-chan time.Time
+		if timeout > 0 {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			timedOut = timer.C
+		}
+
+		select {
+		case err = <-exited:
+		case <-timedOut:
+			session.Signal(ssh.SIGKILL)
+			return CommandTimeoutError{Command: command, Timeout: timeout}
+		}
+
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			result.ExitStatus = exitErr.ExitStatus()
+			return nil
+		}
+
+		return err
+	})
+
+	result.Duration = time.Since(start)
+
+	return result, err
 }
 
 func (c *sshClientImpl) UploadFile(username string, password string, ip string, srcFile string, destFile string) error {
@@ -61,81 +200,170 @@ func (c *sshClientImpl) UploadFile(username string, password string, ip string,
 }
 
 func (c *sshClientImpl) Upload(username, password, ip string, source io.Reader, destFile string) error {
-	config := &ssh.ClientConfig{
-		User: username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(password),
-		},
-	}
+	return c.withConnection(username, password, ip, func(client *ssh.Client) error {
+		sftp, err := sftp.NewClient(client)
+		if err != nil {
+			return sshConnectionError{err}
+		}
+		defer sftp.Close()
 
-	client, err := ssh.Dial("tcp", address(ip), config)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+		f, err := sftp.Create(destFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
 
-	sftp, err := sftp.NewClient(client)
-	if err != nil {
+		_, err = f.ReadFrom(source)
 		return err
-	}
-	defer sftp.Close()
+	})
+}
 
-	f, err := sftp.Create(destFile)
+func (c *sshClientImpl) DownloadFile(username string, password string, ip string, srcFile string, destFile string) error {
+	writer, err := os.OpenFile(destFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	defer writer.Close()
 
-	_, err = f.ReadFrom(source)
-	if err != nil {
+	return c.Download(username, password, ip, srcFile, writer)
+}
+
+func (c *sshClientImpl) Download(username, password, ip, srcFile string, destination io.Writer) error {
+	return c.withConnection(username, password, ip, func(client *ssh.Client) error {
+		sftp, err := sftp.NewClient(client)
+		if err != nil {
+			return sshConnectionError{err}
+		}
+		defer sftp.Close()
+
+		f, err := sftp.Open(srcFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = f.WriteTo(destination)
 		return err
+	})
+}
+
+func (c *sshClientImpl) ForgetHostKey(ip string) error {
+	// A reprovisioned server will not accept the old connections anyway
+	c.pool.DiscardHost(ip)
+
+	if c.knownHosts == nil {
+		return nil
 	}
 
-	return nil
+	return c.knownHosts.Forget(ip)
 }
 
-func (c *sshClientImpl) DownloadFile(username string, password string, ip string, srcFile string, destFile string) error {
-	writer, err := os.OpenFile(destFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+// Close closes every pooled connection
+func (c *sshClientImpl) Close() error {
+	return c.pool.Close()
+}
+
+// sshConnectionError marks a failure to open a session or SFTP channel on a
+// pooled connection, which usually means the connection has gone stale
+type sshConnectionError struct {
+	err error
+}
+
+func (e sshConnectionError) Error() string { return e.err.Error() }
+
+// withConnection runs action on the pooled connection for username at ip and
+// retries it once on a fresh connection if the pooled one turns out to be broken
+func (c *sshClientImpl) withConnection(username, password, ip string, action func(*ssh.Client) error) error {
+	client, err := c.pool.Get(username, password, ip)
 	if err != nil {
 		return err
 	}
-	defer writer.Close()
 
-	return c.Download(username, password, ip, srcFile, writer)
+	err = action(client)
+	c.pool.Release(username, ip, client)
+	if _, broken := err.(sshConnectionError); broken {
+		c.pool.Discard(username, ip, client)
+
+		client, err = c.pool.Get(username, password, ip)
+		if err != nil {
+			return err
+		}
+
+		err = action(client)
+		c.pool.Release(username, ip, client)
+	}
+
+	if connErr, broken := err.(sshConnectionError); broken {
+		c.pool.Discard(username, ip, client)
+		return connErr.err
+	}
+
+	return err
 }
 
-func (c *sshClientImpl) Download(username, password, ip, srcFile string, destination io.Writer) error {
+func (c *sshClientImpl) dial(username, password, ip string) (*ssh.Client, error) {
+	auth := []ssh.AuthMethod{}
+	if c.options.PrivateKeyPath != "" {
+		signer, err := c.options.signer()
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Loading private key '%s'", c.options.PrivateKeyPath)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+
 	config := &ssh.ClientConfig{
 		User: username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(password),
+		Auth: auth,
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			return c.verifyHostKey(ip, key)
 		},
 	}
+	if c.options.InsecureIgnoreHostKey {
+		c.logger.Warn(sshClientLogTag, "Accepting the host key of '%s' without verifying it", ip)
+		config.HostKeyCallback = func(_ string, _ net.Addr, _ ssh.PublicKey) error {
+			return nil
+		}
+	}
 
-	client, err := ssh.Dial("tcp", address(ip), config)
+	// The vendored ssh.ClientConfig has no Timeout, so bound the TCP connect
+	// and the handshake here
+	conn, err := net.DialTimeout("tcp", address(ip), sshDialTimeout)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer client.Close()
 
-	sftp, err := sftp.NewClient(client)
+	conn.SetDeadline(time.Now().Add(sshDialTimeout))
+	clientConn, channels, requests, err := ssh.NewClientConn(conn, address(ip), config)
 	if err != nil {
-		return err
+		conn.Close()
+		return nil, err
 	}
-	defer sftp.Close()
+	conn.SetDeadline(time.Time{})
 
-	f, err := sftp.Open(srcFile)
-	if err != nil {
-		return err
+	return ssh.NewClient(clientConn, channels, requests), nil
+}
+
+// verifyHostKey checks key against the fingerprint pinned for ip, falling
+// back to the known hosts file, which trusts the first key it sees
+func (c *sshClientImpl) verifyHostKey(ip string, key ssh.PublicKey) error {
+	fingerprint := HostKeyFingerprint(key)
+
+	pinned, found := c.options.HostKeyFingerprints[ip]
+	if found {
+		if pinned != fingerprint {
+			return bosherr.Errorf("Host key of '%s' has fingerprint '%s', expected '%s'", ip, fingerprint, pinned)
+		}
+		return nil
 	}
-	defer f.Close()
 
-	_, err = f.WriteTo(destination)
-	if err != nil {
-		return err
+	if c.knownHosts != nil {
+		return c.knownHosts.Verify(ip, fingerprint)
 	}
 
-	return nil
+	return bosherr.Errorf("Host key of '%s' cannot be verified: no known hosts file and no pinned fingerprint", ip)
 }
 
 func address(a string) string {
@@ -147,5 +375,5 @@ func address(a string) string {
 }
 
 func GetSshClient() SshClient {
-	return &sshClientImpl{}
+	return NewSshClient(SshClientOptions{}, boshlog.NewLogger(boshlog.LevelNone))
 }
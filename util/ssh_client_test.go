@@ -11,10 +11,12 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 
 	bscutil "github.com/cloudfoundry/bosh-softlayer-cpi/util"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/pkg/sftp"
 
 	. "github.com/onsi/ginkgo"
@@ -23,16 +25,23 @@ import (
 
 var _ = Describe("SshClient", func() {
 	var (
-		sshClient     bscutil.SshClient
-		listener      net.Listener
-		serverAddress string
+		sshClient      bscutil.SshClient
+		logger         boshlog.Logger
+		knownHostsPath string
+		listener       net.Listener
+		serverAddress  string
 
 		serverConfig *ssh.ServerConfig
 		sshServer    *server
 	)
 
 	BeforeEach(func() {
-		sshClient = bscutil.GetSshClient()
+		knownHostsDir, err := ioutil.TempDir("", "known-hosts")
+		Expect(err).NotTo(HaveOccurred())
+		knownHostsPath = filepath.Join(knownHostsDir, "known_hosts")
+
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		sshClient = bscutil.NewSshClient(bscutil.SshClientOptions{KnownHostsPath: knownHostsPath}, logger)
 
 		serverHostKey, err := ssh.ParsePrivateKey([]byte(hostKey))
 		Expect(err).NotTo(HaveOccurred())
@@ -60,53 +69,308 @@ var _ = Describe("SshClient", func() {
 	})
 
 	AfterEach(func() {
+		if closer, ok := sshClient.(io.Closer); ok {
+			closer.Close()
+		}
 		sshServer.Shutdown()
+		os.RemoveAll(filepath.Dir(knownHostsPath))
 	})
 
 	Describe("ExecCommand", func() {
 		BeforeEach(func() {
-			sshServer.sessionChannelHandler = func(newChannel ssh.NewChannel) {
+			sshServer.sessionChannelHandler = echoCommandHandler
+		})
+
+		It("executes the command over ssh", func() {
+			result, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "this is my command string")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("this is my command string"))
+		})
+
+		Context("when authentication fails", func() {
+			It("returns an error", func() {
+				_, err := sshClient.ExecCommand("testuser", "broken", serverAddress, "this is my command string")
+				Expect(err).To(MatchError(ContainSubstring("handshake failed")))
+			})
+		})
+	})
+
+	Describe("Run", func() {
+		Context("when the command succeeds", func() {
+			BeforeEach(func() {
+				sshServer.sessionChannelHandler = scriptedCommandHandler("fake-stdout", "fake-stderr", 0, false)
+			})
+
+			It("returns the output and exit status of the command", func() {
+				result, err := sshClient.Run("testuser", "testpass", serverAddress, "command", time.Minute)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Stdout).To(Equal("fake-stdout"))
+				Expect(result.Stderr).To(Equal("fake-stderr"))
+				Expect(result.ExitStatus).To(Equal(0))
+				Expect(result.Duration).To(BeNumerically(">", 0))
+			})
+		})
+
+		Context("when the command exits with a non-zero status", func() {
+			BeforeEach(func() {
+				sshServer.sessionChannelHandler = scriptedCommandHandler("fake-stdout", "fake-stderr", 3, false)
+			})
+
+			It("returns the exit status and stderr without an error", func() {
+				result, err := sshClient.Run("testuser", "testpass", serverAddress, "command", time.Minute)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.ExitStatus).To(Equal(3))
+				Expect(result.Stderr).To(Equal("fake-stderr"))
+			})
+
+			It("makes ExecCommand return a CommandError including stderr", func() {
+				_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+				Expect(err).To(BeAssignableToTypeOf(bscutil.CommandError{}))
+				Expect(err).To(MatchError("Command 'command' exited with status 3: fake-stderr"))
+			})
+
+			It("does not report the quoted arguments of the command", func() {
+				_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "usermod -p 'fake-password-hash' vcap")
+				Expect(err).To(MatchError("Command 'usermod -p '<redacted>' vcap' exited with status 3: fake-stderr"))
+			})
+		})
+
+		Context("when the command runs longer than the timeout", func() {
+			BeforeEach(func() {
+				sshServer.sessionChannelHandler = scriptedCommandHandler("", "", 0, true)
+			})
+
+			It("returns a CommandTimeoutError", func() {
+				_, err := sshClient.Run("testuser", "testpass", serverAddress, "sleep", 100*time.Millisecond)
+				Expect(err).To(BeAssignableToTypeOf(bscutil.CommandTimeoutError{}))
+				Expect(err).To(MatchError("Command 'sleep' timed out after 100ms"))
+			})
+		})
+	})
+
+	Describe("connection reuse", func() {
+		BeforeEach(func() {
+			sshServer.sessionChannelHandler = echoCommandHandler
+		})
+
+		It("runs consecutive commands over a single connection", func() {
+			for i := 0; i < 3; i++ {
+				result, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "this is my command string")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal("this is my command string"))
+			}
+
+			Expect(sshServer.ConnectionCount()).To(Equal(1))
+		})
+
+		It("connects to other servers while one does not answer the handshake", func() {
+			hangingListener, hangingAddress := newListener()
+			defer hangingListener.Close()
+
+			accepted := make(chan net.Conn, 1)
+			go func() {
+				conn, err := hangingListener.Accept()
+				if err == nil {
+					accepted <- conn
+				}
+			}()
+
+			hangingDone := make(chan error, 1)
+			go func() {
+				_, err := sshClient.ExecCommand("testuser", "testpass", hangingAddress, "command")
+				hangingDone <- err
+			}()
+
+			var hangingConn net.Conn
+			Eventually(accepted).Should(Receive(&hangingConn))
+
+			done := make(chan string, 1)
+			go func() {
 				defer GinkgoRecover()
+				result, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+				Expect(err).NotTo(HaveOccurred())
+				done <- result
+			}()
+			Eventually(done, 5*time.Second).Should(Receive(Equal("command")))
 
-				channel, requests, err := newChannel.Accept()
+			hangingConn.Close()
+			Eventually(hangingDone, 5*time.Second).Should(Receive(HaveOccurred()))
+		})
+
+		It("reconnects when the server drops the pooled connection", func() {
+			_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+			Expect(err).NotTo(HaveOccurred())
+
+			sshServer.DropConnections()
+
+			result, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("command"))
+			Expect(sshServer.ConnectionCount()).To(Equal(2))
+		})
+
+		It("reconnects after the host key of the server is forgotten", func() {
+			_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = sshClient.ForgetHostKey(serverAddress)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sshServer.ConnectionCount()).To(Equal(2))
+		})
+
+		Context("with several users", func() {
+			BeforeEach(func() {
+				serverConfig.PasswordCallback = func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+					return &ssh.Permissions{}, nil
+				}
+			})
+
+			It("keeps separate connections per user", func() {
+				_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = sshClient.ExecCommand("otheruser", "otherpass", serverAddress, "command")
 				Expect(err).NotTo(HaveOccurred())
-				defer channel.Close()
 
-				for req := range requests {
-					switch req.Type {
-					case "exec":
-						var execMsg struct{ Command string }
-						err := ssh.Unmarshal(req.Payload, &execMsg)
-						Expect(err).NotTo(HaveOccurred())
+				Expect(sshServer.ConnectionCount()).To(Equal(2))
+			})
+		})
+	})
 
-						if req.WantReply {
-							req.Reply(true, nil)
-						}
+	Describe("private key authentication", func() {
+		var keyPath string
 
-						var exitStatusMsg struct{ Status uint32 }
-						io.Copy(channel, strings.NewReader(execMsg.Command))
-						channel.SendRequest("exit-status", false, ssh.Marshal(exitStatusMsg))
-						return
-					default:
-						if req.WantReply {
-							req.Reply(false, nil)
-						}
-					}
+		BeforeEach(func() {
+			clientKey, err := ssh.ParsePrivateKey([]byte(hostKey))
+			Expect(err).NotTo(HaveOccurred())
+
+			serverConfig.PublicKeyCallback = func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+				if conn.User() == "testuser" && bytes.Equal(key.Marshal(), clientKey.PublicKey().Marshal()) {
+					return &ssh.Permissions{}, nil
 				}
+				return nil, errors.New("authentication failed")
 			}
+			sshServer.sessionChannelHandler = echoCommandHandler
+
+			keyFile, err := ioutil.TempFile("", "ssh-key")
+			Expect(err).NotTo(HaveOccurred())
+			defer keyFile.Close()
+
+			_, err = keyFile.WriteString(hostKey)
+			Expect(err).NotTo(HaveOccurred())
+			keyPath = keyFile.Name()
 		})
 
-		It("executes the command over ssh", func() {
-			result, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "this is my command string")
+		AfterEach(func() {
+			os.Remove(keyPath)
+		})
+
+		It("authenticates with the private key when no password is given", func() {
+			sshClient = bscutil.NewSshClient(bscutil.SshClientOptions{PrivateKeyPath: keyPath, KnownHostsPath: knownHostsPath}, logger)
+
+			result, err := sshClient.ExecCommand("testuser", "", serverAddress, "this is my command string")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(result).To(Equal("this is my command string"))
 		})
 
-		Context("when authentication fails", func() {
-			It("returns an error", func() {
-				_, err := sshClient.ExecCommand("testuser", "broken", serverAddress, "this is my command string")
-				Expect(err).To(MatchError(ContainSubstring("handshake failed")))
+		It("rejects options with an unreadable private key", func() {
+			err := bscutil.SshClientOptions{PrivateKeyPath: "/non-existent/key"}.Validate()
+			Expect(err).To(MatchError(ContainSubstring("Loading private key '/non-existent/key'")))
+		})
+	})
+
+	Describe("host key verification", func() {
+		var (
+			host        string
+			fingerprint string
+		)
+
+		BeforeEach(func() {
+			sshServer.sessionChannelHandler = echoCommandHandler
+
+			serverHostKey, err := ssh.ParsePrivateKey([]byte(hostKey))
+			Expect(err).NotTo(HaveOccurred())
+			fingerprint = bscutil.HostKeyFingerprint(serverHostKey.PublicKey())
+
+			host = serverAddress
+		})
+
+		It("accepts a server whose fingerprint is pinned", func() {
+			sshClient = bscutil.NewSshClient(bscutil.SshClientOptions{
+				HostKeyFingerprints: map[string]string{host: fingerprint},
+			}, logger)
+
+			_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rejects a server whose fingerprint does not match the pinned one", func() {
+			sshClient = bscutil.NewSshClient(bscutil.SshClientOptions{
+				HostKeyFingerprints: map[string]string{host: "SHA256:fake-fingerprint"},
+				KnownHostsPath:      knownHostsPath,
+			}, logger)
+
+			_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+			Expect(err).To(MatchError(ContainSubstring("expected 'SHA256:fake-fingerprint'")))
+		})
+
+		Context("with a known hosts file", func() {
+			It("records the fingerprint on first connection", func() {
+				_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(knownHostsPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(host + " " + fingerprint + "\n"))
+			})
+
+			It("rejects a server whose key changed until its host key is forgotten", func() {
+				err := ioutil.WriteFile(knownHostsPath, []byte(host+" SHA256:fake-old-fingerprint\n"), 0600)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+				Expect(err).To(MatchError(ContainSubstring("'SHA256:fake-old-fingerprint' is recorded")))
+
+				err = sshClient.ForgetHostKey(host)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+				Expect(err).NotTo(HaveOccurred())
 			})
+
+			It("creates the directory of the known hosts file", func() {
+				knownHostsPath = filepath.Join(filepath.Dir(knownHostsPath), "cpi", "known_hosts")
+				sshClient = bscutil.NewSshClient(bscutil.SshClientOptions{KnownHostsPath: knownHostsPath}, logger)
+
+				_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(knownHostsPath).To(BeARegularFile())
+			})
+		})
+
+		It("verifies host keys against the default known hosts file unless told otherwise", func() {
+			options := bscutil.SshClientOptions{}.WithDefaults()
+			Expect(options.KnownHostsPath).To(Equal(bscutil.DefaultKnownHostsPath))
+
+			options = bscutil.SshClientOptions{InsecureIgnoreHostKey: true}.WithDefaults()
+			Expect(options.KnownHostsPath).To(BeEmpty())
+		})
+
+		It("accepts any host key only when explicitly asked to and warns about it", func() {
+			logBuffer := &bytes.Buffer{}
+			logger = boshlog.NewWriterLogger(boshlog.LevelWarn, logBuffer, logBuffer)
+			sshClient = bscutil.NewSshClient(bscutil.SshClientOptions{InsecureIgnoreHostKey: true}, logger)
+
+			_, err := sshClient.ExecCommand("testuser", "testpass", serverAddress, "command")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logBuffer.String()).To(ContainSubstring("Accepting the host key of '" + host + "' without verifying it"))
+		})
+
+		It("rejects options that turn off verification and configure it at the same time", func() {
+			err := bscutil.SshClientOptions{InsecureIgnoreHostKey: true, KnownHostsPath: knownHostsPath}.Validate()
+			Expect(err).To(MatchError(ContainSubstring("Must not set InsecureIgnoreHostKey")))
 		})
 	})
 
@@ -362,6 +626,64 @@ var _ = Describe("SshClient", func() {
 	})
 })
 
+func echoCommandHandler(newChannel ssh.NewChannel) {
+	defer GinkgoRecover()
+
+	channel, requests, err := newChannel.Accept()
+	Expect(err).NotTo(HaveOccurred())
+	defer channel.Close()
+
+	for req := range requests {
+		switch req.Type {
+		case "exec":
+			var execMsg struct{ Command string }
+			err := ssh.Unmarshal(req.Payload, &execMsg)
+			Expect(err).NotTo(HaveOccurred())
+
+			if req.WantReply {
+				req.Reply(true, nil)
+			}
+
+			var exitStatusMsg struct{ Status uint32 }
+			io.Copy(channel, strings.NewReader(execMsg.Command))
+			channel.SendRequest("exit-status", false, ssh.Marshal(exitStatusMsg))
+			return
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// scriptedCommandHandler answers exec requests with the given output and exit
+// status, or never exits when hang is set
+func scriptedCommandHandler(stdout, stderr string, status uint32, hang bool) func(ssh.NewChannel) {
+	return func(newChannel ssh.NewChannel) {
+		defer GinkgoRecover()
+
+		channel, requests, err := newChannel.Accept()
+		Expect(err).NotTo(HaveOccurred())
+		defer channel.Close()
+
+		for req := range requests {
+			if req.WantReply {
+				req.Reply(req.Type == "exec", nil)
+			}
+
+			if req.Type != "exec" || hang {
+				continue
+			}
+
+			exitStatusMsg := struct{ Status uint32 }{status}
+			io.Copy(channel, strings.NewReader(stdout))
+			io.Copy(channel.Stderr(), strings.NewReader(stderr))
+			channel.SendRequest("exit-status", false, ssh.Marshal(exitStatusMsg))
+			return
+		}
+	}
+}
+
 func newListener() (net.Listener, string) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	Expect(err).NotTo(HaveOccurred())
@@ -376,7 +698,9 @@ type server struct {
 
 	sessionChannelHandler func(ssh.NewChannel)
 
-	stopping bool
+	stopping        bool
+	connectionCount int
+	serverConns     []*ssh.ServerConn
 }
 
 func (s *server) Serve() {
@@ -407,6 +731,11 @@ func (s *server) handleConnection(conn net.Conn) {
 	}
 	defer serverConn.Close()
 
+	s.mutex.Lock()
+	s.connectionCount++
+	s.serverConns = append(s.serverConns, serverConn)
+	s.mutex.Unlock()
+
 	go ssh.DiscardRequests(serverRequests)
 	go s.handleNewChannels(serverChannels)
 
@@ -432,6 +761,22 @@ func (s *server) Shutdown() {
 	s.listener.Close()
 }
 
+func (s *server) ConnectionCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.connectionCount
+}
+
+func (s *server) DropConnections() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, serverConn := range s.serverConns {
+		serverConn.Close()
+	}
+	s.serverConns = nil
+}
+
 func (s *server) isStopping() bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -0,0 +1,213 @@
+package util
+
+import (
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshKeepAliveInterval = 15 * time.Second
+
+	// Connections left unused this long are closed, so that a CPI serving
+	// many calls does not hold on to servers it is done with
+	sshIdleTimeout = 2 * time.Minute
+)
+
+type sshConnectionKey struct {
+	username string
+	ip       string
+}
+
+type pooledSshConnection struct {
+	client   *ssh.Client
+	dialErr  error
+	users    int
+	lastUsed time.Time
+
+	// dialed is closed once client or dialErr is set
+	dialed chan struct{}
+	done   chan struct{}
+}
+
+// sshConnectionPool keeps one SSH connection per (user, ip) open so that the
+// sessions and SFTP transfers of a CPI call share a single handshake
+type sshConnectionPool struct {
+	dial func(username, password, ip string) (*ssh.Client, error)
+
+	keepAliveInterval time.Duration
+	idleTimeout       time.Duration
+
+	mutex       sync.Mutex
+	connections map[sshConnectionKey]*pooledSshConnection
+}
+
+func newSshConnectionPool(dial func(username, password, ip string) (*ssh.Client, error), keepAliveInterval, idleTimeout time.Duration) *sshConnectionPool {
+	return &sshConnectionPool{
+		dial:              dial,
+		keepAliveInterval: keepAliveInterval,
+		idleTimeout:       idleTimeout,
+		connections:       map[sshConnectionKey]*pooledSshConnection{},
+	}
+}
+
+// Get returns the pooled connection for username at ip, dialing a new one if
+// there is none yet. Every successful Get must be paired with a Release.
+func (p *sshConnectionPool) Get(username, password, ip string) (*ssh.Client, error) {
+	key := sshConnectionKey{username: username, ip: ip}
+
+	p.mutex.Lock()
+	connection, found := p.connections[key]
+	if !found {
+		connection = &pooledSshConnection{
+			dialed: make(chan struct{}),
+			done:   make(chan struct{}),
+		}
+		p.connections[key] = connection
+	}
+	p.mutex.Unlock()
+
+	// Dial without holding the mutex, so that an unreachable server only
+	// holds up the calls that need it
+	if !found {
+		p.connect(key, connection, username, password, ip)
+	}
+
+	<-connection.dialed
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if connection.dialErr != nil {
+		return nil, connection.dialErr
+	}
+
+	connection.users++
+	connection.lastUsed = time.Now()
+
+	return connection.client, nil
+}
+
+// Release marks the end of a use of client obtained from Get
+func (p *sshConnectionPool) Release(username, ip string, client *ssh.Client) {
+	key := sshConnectionKey{username: username, ip: ip}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	connection, found := p.connections[key]
+	if found && connection.client == client {
+		connection.users--
+		connection.lastUsed = time.Now()
+	}
+}
+
+func (p *sshConnectionPool) connect(key sshConnectionKey, connection *pooledSshConnection, username, password, ip string) {
+	client, err := p.dial(username, password, ip)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	defer close(connection.dialed)
+
+	current, found := p.connections[key]
+	if err != nil {
+		connection.dialErr = err
+		if found && current == connection {
+			delete(p.connections, key)
+		}
+		return
+	}
+
+	if !found || current != connection {
+		client.Close()
+		connection.dialErr = bosherr.Errorf("SSH connection to '%s' was discarded while connecting", ip)
+		return
+	}
+
+	connection.client = client
+	connection.lastUsed = time.Now()
+
+	go p.keepAlive(key, connection)
+}
+
+// Discard closes client and removes it from the pool, so that the next Get
+// for username at ip reconnects
+func (p *sshConnectionPool) Discard(username, ip string, client *ssh.Client) {
+	key := sshConnectionKey{username: username, ip: ip}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	connection, found := p.connections[key]
+	if found && connection.client == client {
+		p.remove(key, connection)
+	}
+}
+
+// DiscardHost closes the connections of every user at ip
+func (p *sshConnectionPool) DiscardHost(ip string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for key, connection := range p.connections {
+		if key.ip == ip {
+			p.remove(key, connection)
+		}
+	}
+}
+
+func (p *sshConnectionPool) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for key, connection := range p.connections {
+		p.remove(key, connection)
+	}
+
+	return nil
+}
+
+// remove must be called with the mutex held
+func (p *sshConnectionPool) remove(key sshConnectionKey, connection *pooledSshConnection) {
+	delete(p.connections, key)
+	close(connection.done)
+	if connection.client != nil {
+		connection.client.Close()
+	}
+}
+
+// keepAlive pings the server until the connection is discarded, fails a
+// keepalive or sits unused for longer than the idle timeout
+func (p *sshConnectionPool) keepAlive(key sshConnectionKey, connection *pooledSshConnection) {
+	ticker := time.NewTicker(p.keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-connection.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.mutex.Lock()
+		idle := connection.users == 0 && time.Since(connection.lastUsed) > p.idleTimeout
+		p.mutex.Unlock()
+
+		if !idle {
+			_, _, err := connection.client.SendRequest("keepalive@openssh.com", true, nil)
+			if err == nil {
+				continue
+			}
+		}
+
+		p.mutex.Lock()
+		current, found := p.connections[key]
+		if found && current == connection {
+			p.remove(key, connection)
+		}
+		p.mutex.Unlock()
+		return
+	}
+}